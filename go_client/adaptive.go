@@ -0,0 +1,292 @@
+// Package fdfs adaptive concurrency control.
+// This file adds AIMD-based self-tuning concurrency for batch workloads run
+// through Pool (see pool.go), instead of requiring callers to hard-code a
+// worker count or MaxConns the way examples/concurrent does. Every
+// ProbeEveryOps completed operations, the controller compares throughput
+// against the previous probe window and nudges the worker count by one
+// (additive increase) when it improved, or halves it (multiplicative
+// decrease) when latency has inflated past LatencyInflationLimit or errors
+// have spiked — the same AIMD shape TCP congestion control uses, applied to
+// a Little's-Law-style "how many workers keep the pipe full without
+// overloading it" problem instead of a send window.
+package fdfs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// adaptiveEWMAAlpha is the smoothing factor for the controller's latency
+// EWMA, matching progressEWMAAlpha's reasoning (see progress.go): recent
+// enough to react within a few probe windows, smooth enough that one slow
+// op doesn't trigger a needless backoff.
+const adaptiveEWMAAlpha = 0.2
+
+// AdaptiveConcurrencyConfig enables Pool.Adaptive (see pool.go). Set it on
+// ClientConfig to let a Pool discover the concurrency sweet spot for the
+// current tracker/storage cluster instead of using a fixed worker count.
+type AdaptiveConcurrencyConfig struct {
+	// MinConcurrency is the floor the controller never backs off below.
+	// Defaults to 1 when <= 0.
+	MinConcurrency int
+
+	// MaxConcurrency is the ceiling the controller never grows past, and
+	// also how many worker goroutines an Adaptive Pool starts (only up to
+	// the controller's current concurrency run at once; the rest block on
+	// an internal semaphore). Defaults to 64 when <= 0.
+	MaxConcurrency int
+
+	// ProbeEveryOps is how many completed operations make up one probe
+	// window. Defaults to 50 when <= 0.
+	ProbeEveryOps int
+
+	// ImprovementThreshold is the fractional throughput gain (e.g. 0.05 for
+	// 5%) a probe window must show over the previous one to keep an
+	// additive increase. Defaults to 0.05 when <= 0.
+	ImprovementThreshold float64
+
+	// LatencyInflationLimit is how many times the EWMA latency may exceed
+	// its last-adjustment baseline before the controller backs off.
+	// Defaults to 2.0 when <= 0.
+	LatencyInflationLimit float64
+}
+
+func (cfg AdaptiveConcurrencyConfig) withDefaults() AdaptiveConcurrencyConfig {
+	if cfg.MinConcurrency <= 0 {
+		cfg.MinConcurrency = 1
+	}
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = 64
+	}
+	if cfg.MaxConcurrency < cfg.MinConcurrency {
+		cfg.MaxConcurrency = cfg.MinConcurrency
+	}
+	if cfg.ProbeEveryOps <= 0 {
+		cfg.ProbeEveryOps = 50
+	}
+	if cfg.ImprovementThreshold <= 0 {
+		cfg.ImprovementThreshold = 0.05
+	}
+	if cfg.LatencyInflationLimit <= 0 {
+		cfg.LatencyInflationLimit = 2.0
+	}
+	return cfg
+}
+
+// adaptiveConcurrencyController runs the AIMD probe loop described in this
+// file's package comment. One controller is shared by every Pool an
+// Adaptive-enabled Client's callers create, since the sweet spot it
+// discovers is a property of the cluster the Client talks to, not of any
+// one batch call.
+type adaptiveConcurrencyController struct {
+	cfg AdaptiveConcurrencyConfig
+
+	mu              sync.Mutex
+	current         int
+	direction       int // +1 after a probe increase, -1 after a decrease, 0 at rest
+	baselineLatency time.Duration
+	ewmaLatency     time.Duration
+	opsSinceProbe   int
+	errsSinceProbe  int
+	probeStart      time.Time
+	lastThroughput  float64
+
+	groupMu sync.Mutex
+	groups  map[string]*groupLatency
+}
+
+// groupLatency is one storage group's latency EWMA and error count, kept
+// purely for observability (see adaptiveConcurrencyController.GroupLatencies):
+// the controller's concurrency dial is still cluster-wide, since a Pool
+// doesn't know which storage group an UploadJob will land on until the
+// tracker assigns one, but callers can still see which group is slow.
+type groupLatency struct {
+	ewma time.Duration
+	ops  int64
+	errs int64
+}
+
+func newAdaptiveConcurrencyController(cfg AdaptiveConcurrencyConfig) *adaptiveConcurrencyController {
+	cfg = cfg.withDefaults()
+	return &adaptiveConcurrencyController{
+		cfg:        cfg,
+		current:    cfg.MinConcurrency,
+		probeStart: time.Now(),
+		groups:     make(map[string]*groupLatency),
+	}
+}
+
+// concurrency returns the worker count the controller currently allows.
+func (a *adaptiveConcurrencyController) concurrency() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.current
+}
+
+// recordGroupOp folds one completed operation's latency and outcome into
+// group's latency EWMA, for GroupLatencies to report independently of the
+// cluster-wide EWMA recordOp maintains.
+func (a *adaptiveConcurrencyController) recordGroupOp(group string, latency time.Duration, err error) {
+	if group == "" {
+		return
+	}
+	a.groupMu.Lock()
+	defer a.groupMu.Unlock()
+
+	g, ok := a.groups[group]
+	if !ok {
+		g = &groupLatency{}
+		a.groups[group] = g
+	}
+	g.ops++
+	if err != nil {
+		g.errs++
+	}
+	if g.ewma == 0 {
+		g.ewma = latency
+	} else {
+		g.ewma = time.Duration(adaptiveEWMAAlpha*float64(latency) + (1-adaptiveEWMAAlpha)*float64(g.ewma))
+	}
+}
+
+// GroupLatencies returns a snapshot of each storage group's current latency
+// EWMA, keyed by group name, as last updated by recordGroupOp.
+func (a *adaptiveConcurrencyController) GroupLatencies() map[string]time.Duration {
+	a.groupMu.Lock()
+	defer a.groupMu.Unlock()
+
+	out := make(map[string]time.Duration, len(a.groups))
+	for name, g := range a.groups {
+		out[name] = g.ewma
+	}
+	return out
+}
+
+// recordOp feeds one completed operation's latency and outcome into the
+// controller, probing (and possibly adjusting current) every
+// AdaptiveConcurrencyConfig.ProbeEveryOps calls.
+func (a *adaptiveConcurrencyController) recordOp(latency time.Duration, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err != nil {
+		a.errsSinceProbe++
+	}
+	if a.ewmaLatency == 0 {
+		a.ewmaLatency = latency
+	} else {
+		a.ewmaLatency = time.Duration(adaptiveEWMAAlpha*float64(latency) + (1-adaptiveEWMAAlpha)*float64(a.ewmaLatency))
+	}
+	if a.baselineLatency == 0 {
+		a.baselineLatency = a.ewmaLatency
+	}
+	a.opsSinceProbe++
+
+	if a.opsSinceProbe >= a.cfg.ProbeEveryOps {
+		a.probeLocked()
+	}
+}
+
+// probeLocked evaluates the just-finished probe window and adjusts
+// current. Caller must hold a.mu.
+func (a *adaptiveConcurrencyController) probeLocked() {
+	elapsed := time.Since(a.probeStart).Seconds()
+	var throughput float64
+	if elapsed > 0 {
+		throughput = float64(a.opsSinceProbe) / elapsed
+	}
+
+	latencyInflated := a.baselineLatency > 0 &&
+		float64(a.ewmaLatency) > float64(a.baselineLatency)*a.cfg.LatencyInflationLimit
+	errorSpike := a.errsSinceProbe*10 > a.opsSinceProbe // > ~10% of this window errored
+
+	switch {
+	case latencyInflated || errorSpike:
+		// Multiplicative decrease: halve, but never below the floor.
+		if half := a.current / 2; half >= a.cfg.MinConcurrency {
+			a.current = half
+		} else {
+			a.current = a.cfg.MinConcurrency
+		}
+		a.direction = -1
+		a.baselineLatency = a.ewmaLatency
+
+	case throughput > a.lastThroughput*(1+a.cfg.ImprovementThreshold) && a.current < a.cfg.MaxConcurrency:
+		// Additive increase: throughput is still improving, try one more worker.
+		a.current++
+		a.direction = 1
+		a.baselineLatency = a.ewmaLatency
+
+	case a.direction > 0 && a.current > a.cfg.MinConcurrency:
+		// The last increase didn't pay off; revert it.
+		a.current--
+		a.direction = 0
+
+	default:
+		a.direction = 0
+	}
+
+	a.lastThroughput = throughput
+	a.opsSinceProbe = 0
+	a.errsSinceProbe = 0
+	a.probeStart = time.Now()
+}
+
+// elasticSemaphore gates concurrent operations at a limit that can change at
+// runtime, unlike a fixed-capacity buffered-channel semaphore.
+type elasticSemaphore struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active int
+	limit  int
+}
+
+func newElasticSemaphore(limit int) *elasticSemaphore {
+	s := &elasticSemaphore{limit: limit}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// setLimit changes how many acquires may be outstanding at once, waking any
+// waiters so they can re-check against the new limit.
+func (s *elasticSemaphore) setLimit(limit int) {
+	s.mu.Lock()
+	s.limit = limit
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// acquire blocks until a slot is available or ctx is done.
+func (s *elasticSemaphore) acquire(ctx context.Context) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.cond.Broadcast()
+			s.mu.Unlock()
+		case <-stop:
+		}
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.active >= s.limit && ctx.Err() == nil {
+		s.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	s.active++
+	return nil
+}
+
+// release frees a slot acquired via acquire.
+func (s *elasticSemaphore) release() {
+	s.mu.Lock()
+	s.active--
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
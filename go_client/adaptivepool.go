@@ -0,0 +1,205 @@
+// Package fdfs adaptive connection pool sizing.
+// This file lets ConnectionPool.MaxConns grow or shrink online instead of
+// running at a fixed size, replacing the manual MaxConns sweep a caller
+// would otherwise run by hand to find a good value. It's a distinct
+// concern from AdaptiveConcurrencyConfig (see adaptive.go): that one sizes
+// a Pool's worker goroutine count for a specific batch job, while this one
+// sizes the long-lived storage connection pool shared by every call a
+// Client makes. Only the storage pool is wired up (via recordMetrics,
+// which only instruments storage RPCs today); tracker RPCs aren't on the
+// same hook, so AdaptivePoolConfig has no effect on the tracker pool yet.
+package fdfs
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AdaptivePoolConfig lets ClientConfig.MaxConns be a starting point rather
+// than a fixed value: the storage connection pool is resized every
+// AdjustInterval based on its recent observed P95 latency and arrival
+// rate, using a Little's-Law estimate (required connections ≈ arrival
+// rate × P95 latency) clamped to [MinConns, MaxConns] and backed off
+// AIMD-style whenever the P95 SLO is breached.
+type AdaptivePoolConfig struct {
+	// TargetP95 is the SLO: once the pool's observed P95 latency exceeds
+	// this, the next adjustment halves the distance to MinConns regardless
+	// of what the Little's Law estimate suggests. Zero disables the SLO
+	// backoff, leaving pure Little's-Law sizing.
+	TargetP95 time.Duration
+
+	// MinConns and MaxConns bound the size an adaptive pool will settle
+	// on. MinConns defaults to 1 when <= 0; MaxConns defaults to
+	// ClientConfig.MaxConns (the pool's size at construction) when <= 0.
+	MinConns int
+	MaxConns int
+
+	// SampleWindow is how far back latency samples are kept for the P95
+	// and arrival-rate estimate. Defaults to 10s when <= 0.
+	SampleWindow time.Duration
+
+	// AdjustInterval is the minimum time between resize decisions.
+	// Defaults to 5s when <= 0.
+	AdjustInterval time.Duration
+
+	// OnResize, if set, is called every time the pool's size actually
+	// changes (never on a tick that leaves it unchanged).
+	OnResize func(PoolResizeEvent)
+}
+
+// PoolResizeEvent describes one adaptive resize decision.
+type PoolResizeEvent struct {
+	// Pool is which pool was resized, currently always "storage".
+	Pool             string
+	OldSize, NewSize int
+	P95              time.Duration
+	// Reason is one of "saturated" (grew to meet Little's-Law demand),
+	// "slo_breach" (P95 exceeded TargetP95), or "idle" (demand dropped).
+	Reason string
+}
+
+const (
+	defaultAdaptivePoolSampleWindow   = 10 * time.Second
+	defaultAdaptivePoolAdjustInterval = 5 * time.Second
+)
+
+// poolSample is one observed RPC latency, timestamped so stale samples can
+// be dropped once they fall outside the controller's SampleWindow.
+type poolSample struct {
+	at time.Time
+	d  time.Duration
+}
+
+// adaptivePoolController accumulates recent latency samples for one
+// ConnectionPool and periodically resizes it per AdaptivePoolConfig.
+type adaptivePoolController struct {
+	cfg   *AdaptivePoolConfig
+	pool  *ConnectionPool
+	label string
+
+	// initialMaxConns is pool's size at construction, the default ceiling
+	// when AdaptivePoolConfig.MaxConns is unset.
+	initialMaxConns int
+
+	mu         sync.Mutex
+	samples    []poolSample
+	lastAdjust time.Time
+}
+
+func newAdaptivePoolController(cfg *AdaptivePoolConfig, pool *ConnectionPool, label string) *adaptivePoolController {
+	return &adaptivePoolController{cfg: cfg, pool: pool, label: label, initialMaxConns: pool.MaxConns(), lastAdjust: time.Now()}
+}
+
+func (a *adaptivePoolController) sampleWindow() time.Duration {
+	if a.cfg.SampleWindow > 0 {
+		return a.cfg.SampleWindow
+	}
+	return defaultAdaptivePoolSampleWindow
+}
+
+func (a *adaptivePoolController) adjustInterval() time.Duration {
+	if a.cfg.AdjustInterval > 0 {
+		return a.cfg.AdjustInterval
+	}
+	return defaultAdaptivePoolAdjustInterval
+}
+
+// recordLatency records one RPC's latency and, once AdjustInterval has
+// elapsed since the last resize decision, recomputes and applies the
+// pool's target size. Called from Client.recordMetrics so it piggybacks
+// on the existing per-RPC instrumentation point instead of adding a
+// second one on the same call path.
+func (a *adaptivePoolController) recordLatency(d time.Duration) {
+	now := time.Now()
+
+	a.mu.Lock()
+	a.samples = append(a.samples, poolSample{at: now, d: d})
+	a.samples = trimPoolSamples(a.samples, now, a.sampleWindow())
+
+	due := now.Sub(a.lastAdjust) >= a.adjustInterval()
+	var samples []poolSample
+	if due {
+		a.lastAdjust = now
+		samples = append(samples, a.samples...)
+	}
+	a.mu.Unlock()
+
+	if due {
+		a.adjust(samples)
+	}
+}
+
+// trimPoolSamples drops samples older than window, relying on samples
+// being in append (i.e. non-decreasing timestamp) order.
+func trimPoolSamples(samples []poolSample, now time.Time, window time.Duration) []poolSample {
+	cutoff := now.Add(-window)
+	i := 0
+	for i < len(samples) && samples[i].at.Before(cutoff) {
+		i++
+	}
+	return samples[i:]
+}
+
+// adjust computes the new target size from samples and applies it via
+// a.pool.SetMaxConns, firing AdaptivePoolConfig.OnResize on a real change.
+// samples is consumed destructively (sorted in place by poolP95).
+func (a *adaptivePoolController) adjust(samples []poolSample) {
+	if len(samples) == 0 {
+		return
+	}
+
+	p95 := poolP95(samples)
+	arrivalRate := float64(len(samples)) / a.sampleWindow().Seconds()
+
+	oldSize := a.pool.MaxConns()
+	minConns := a.cfg.MinConns
+	if minConns <= 0 {
+		minConns = 1
+	}
+	maxConns := a.cfg.MaxConns
+	if maxConns <= 0 {
+		maxConns = a.initialMaxConns
+	}
+
+	// Little's Law: required concurrent connections ≈ arrival rate × P95
+	// latency.
+	target := int(math.Ceil(arrivalRate * p95.Seconds()))
+	reason := "saturated"
+	if target <= oldSize {
+		reason = "idle"
+	}
+	if a.cfg.TargetP95 > 0 && p95 > a.cfg.TargetP95 {
+		// SLO breached: back off toward the floor regardless of what
+		// Little's Law suggests (AIMD-style multiplicative decrease).
+		target = oldSize - (oldSize-minConns+1)/2
+		reason = "slo_breach"
+	}
+
+	if target < minConns {
+		target = minConns
+	}
+	if target > maxConns {
+		target = maxConns
+	}
+	if target == oldSize {
+		return
+	}
+
+	a.pool.SetMaxConns(target)
+	if a.cfg.OnResize != nil {
+		a.cfg.OnResize(PoolResizeEvent{Pool: a.label, OldSize: oldSize, NewSize: target, P95: p95, Reason: reason})
+	}
+}
+
+// poolP95 returns the 95th-percentile latency among samples, sorting
+// samples in place.
+func poolP95(samples []poolSample) time.Duration {
+	sort.Slice(samples, func(i, j int) bool { return samples[i].d < samples[j].d })
+	idx := int(float64(len(samples)) * 0.95)
+	if idx >= len(samples) {
+		idx = len(samples) - 1
+	}
+	return samples[idx].d
+}
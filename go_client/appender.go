@@ -10,22 +10,27 @@ import (
 // uploadSlaveFileWithRetry uploads a slave file with retry logic
 func (c *Client) uploadSlaveFileWithRetry(ctx context.Context, masterFileID, prefixName, fileExtName string, data []byte, metadata map[string]string) (string, error) {
 	var lastErr error
-	for i := 0; i < c.config.RetryCount; i++ {
+	backoff := c.newRetryBackoff(ctx)
+	retryCount := c.retryCount(ctx)
+	for i := 0; i < retryCount; i++ {
 		fileID, err := c.uploadSlaveFileInternal(ctx, masterFileID, prefixName, fileExtName, data, metadata)
 		if err == nil {
 			return fileID, nil
 		}
 		lastErr = err
 
-		if err == ErrInvalidFileID || err == ErrFileNotFound {
+		if !c.retryableFunc(ctx)(err) {
 			return "", err
 		}
 
-		if i < c.config.RetryCount-1 {
+		if i < retryCount-1 {
+			delay := backoff.Next()
+			spanFromContext(ctx).SetTag("fdfs.retry.attempt", i+1)
+			c.observerFor(ctx).OnRetry(ctx, "upload_slave_file", i+1, err, delay)
 			select {
 			case <-ctx.Done():
 				return "", ctx.Err()
-			case <-time.After(time.Second * time.Duration(i+1)):
+			case <-time.After(delay):
 			}
 		}
 	}
@@ -33,7 +38,7 @@ func (c *Client) uploadSlaveFileWithRetry(ctx context.Context, masterFileID, pre
 }
 
 // uploadSlaveFileInternal performs the actual slave file upload
-func (c *Client) uploadSlaveFileInternal(ctx context.Context, masterFileID, prefixName, fileExtName string, data []byte, metadata map[string]string) (string, error) {
+func (c *Client) uploadSlaveFileInternal(ctx context.Context, masterFileID, prefixName, fileExtName string, data []byte, metadata map[string]string) (fileID string, err error) {
 	groupName, masterFilename, err := splitFileID(masterFileID)
 	if err != nil {
 		return "", err
@@ -49,39 +54,51 @@ func (c *Client) uploadSlaveFileInternal(ctx context.Context, masterFileID, pref
 	if err != nil {
 		return "", err
 	}
+	storageAddr := storageServer.IPAddr + ":" + fmt.Sprintf("%d", storageServer.Port)
+
+	start := time.Now()
+	defer func() { c.recordMetrics("upload_slave_file", storageAddr, masterFileID, start, len(data), err) }()
+
+	reserved, err := c.byteSem.take(ctx, int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	defer c.byteSem.give(reserved)
 
 	// Get connection
-	conn, err := c.storagePool.Get(ctx, storageServer.IPAddr+":"+fmt.Sprintf("%d", storageServer.Port))
+	conn, err := c.storagePool.Get(ctx, storageAddr)
 	if err != nil {
 		return "", err
 	}
 	defer c.storagePool.Put(conn)
 
+	sendData, compressionMeta := c.maybeCompress(data)
+
 	// Build request
 	extNameBytes := padString(fileExtName, FdfsFileExtNameMaxLen)
 	prefixNameBytes := padString(prefixName, FdfsFilePrefixMaxLen)
 
-	bodyLen := int64(len(masterFilename) + FdfsFilePrefixMaxLen + FdfsFileExtNameMaxLen + 8 + len(data))
+	bodyLen := int64(len(masterFilename) + FdfsFilePrefixMaxLen + FdfsFileExtNameMaxLen + 8 + len(sendData))
 	header := encodeHeader(bodyLen, StorageProtoCmdUploadSlaveFile, 0)
 
 	var buf bytes.Buffer
 	buf.Write(encodeInt64(int64(len(masterFilename))))
-	buf.Write(encodeInt64(int64(len(data))))
+	buf.Write(encodeInt64(int64(len(sendData))))
 	buf.Write(prefixNameBytes)
 	buf.Write(extNameBytes)
 	buf.Write([]byte(masterFilename))
-	buf.Write(data)
+	buf.Write(sendData)
 
 	// Send request
-	if err := conn.Send(header, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(header, c.networkTimeout(ctx)); err != nil {
 		return "", err
 	}
-	if err := conn.Send(buf.Bytes(), c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(buf.Bytes(), c.networkTimeout(ctx)); err != nil {
 		return "", err
 	}
 
 	// Receive response
-	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.config.NetworkTimeout)
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.networkTimeout(ctx))
 	if err != nil {
 		return "", err
 	}
@@ -99,7 +116,7 @@ func (c *Client) uploadSlaveFileInternal(ctx context.Context, masterFileID, pref
 		return "", ErrInvalidResponse
 	}
 
-	respBody, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.config.NetworkTimeout)
+	respBody, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.networkTimeout(ctx))
 	if err != nil {
 		return "", err
 	}
@@ -112,11 +129,22 @@ func (c *Client) uploadSlaveFileInternal(ctx context.Context, masterFileID, pref
 	respGroupName := unpadString(respBody[:FdfsGroupNameMaxLen])
 	remoteFilename := string(respBody[FdfsGroupNameMaxLen:])
 
-	fileID := joinFileID(respGroupName, remoteFilename)
+	fileID = joinFileID(respGroupName, remoteFilename)
 
-	// Set metadata if provided
-	if len(metadata) > 0 {
-		c.setMetadataInternal(ctx, fileID, metadata, MetadataOverwrite)
+	// Set metadata if provided, merging in how to reverse compression (if
+	// any) so DownloadFile can recognize and decode it later.
+	allMeta := metadata
+	if len(compressionMeta) > 0 {
+		allMeta = make(map[string]string, len(metadata)+len(compressionMeta))
+		for k, v := range metadata {
+			allMeta[k] = v
+		}
+		for k, v := range compressionMeta {
+			allMeta[k] = v
+		}
+	}
+	if len(allMeta) > 0 {
+		c.setMetadataInternal(ctx, fileID, allMeta, MetadataOverwrite)
 	}
 
 	return fileID, nil
@@ -125,22 +153,27 @@ func (c *Client) uploadSlaveFileInternal(ctx context.Context, masterFileID, pref
 // appendFileWithRetry appends data to a file with retry logic
 func (c *Client) appendFileWithRetry(ctx context.Context, fileID string, data []byte) error {
 	var lastErr error
-	for i := 0; i < c.config.RetryCount; i++ {
+	backoff := c.newRetryBackoff(ctx)
+	retryCount := c.retryCount(ctx)
+	for i := 0; i < retryCount; i++ {
 		err := c.appendFileInternal(ctx, fileID, data)
 		if err == nil {
 			return nil
 		}
 		lastErr = err
 
-		if err == ErrFileNotFound || err == ErrInvalidFileID {
+		if !c.retryableFunc(ctx)(err) {
 			return err
 		}
 
-		if i < c.config.RetryCount-1 {
+		if i < retryCount-1 {
+			delay := backoff.Next()
+			spanFromContext(ctx).SetTag("fdfs.retry.attempt", i+1)
+			c.observerFor(ctx).OnRetry(ctx, "append_file", i+1, err, delay)
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case <-time.After(time.Second * time.Duration(i+1)):
+			case <-time.After(delay):
 			}
 		}
 	}
@@ -148,7 +181,7 @@ func (c *Client) appendFileWithRetry(ctx context.Context, fileID string, data []
 }
 
 // appendFileInternal performs the actual file append
-func (c *Client) appendFileInternal(ctx context.Context, fileID string, data []byte) error {
+func (c *Client) appendFileInternal(ctx context.Context, fileID string, data []byte) (err error) {
 	groupName, remoteFilename, err := splitFileID(fileID)
 	if err != nil {
 		return err
@@ -159,9 +192,19 @@ func (c *Client) appendFileInternal(ctx context.Context, fileID string, data []b
 	if err != nil {
 		return err
 	}
+	storageAddr := storageServer.IPAddr + ":" + fmt.Sprintf("%d", storageServer.Port)
+
+	start := time.Now()
+	defer func() { c.recordMetrics("append_file", storageAddr, fileID, start, len(data), err) }()
+
+	reserved, err := c.byteSem.take(ctx, int64(len(data)))
+	if err != nil {
+		return err
+	}
+	defer c.byteSem.give(reserved)
 
 	// Get connection
-	conn, err := c.storagePool.Get(ctx, storageServer.IPAddr+":"+fmt.Sprintf("%d", storageServer.Port))
+	conn, err := c.storagePool.Get(ctx, storageAddr)
 	if err != nil {
 		return err
 	}
@@ -177,15 +220,15 @@ func (c *Client) appendFileInternal(ctx context.Context, fileID string, data []b
 	buf.Write(data)
 
 	// Send request
-	if err := conn.Send(header, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(header, c.networkTimeout(ctx)); err != nil {
 		return err
 	}
-	if err := conn.Send(buf.Bytes(), c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(buf.Bytes(), c.networkTimeout(ctx)); err != nil {
 		return err
 	}
 
 	// Receive response
-	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.config.NetworkTimeout)
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.networkTimeout(ctx))
 	if err != nil {
 		return err
 	}
@@ -205,22 +248,27 @@ func (c *Client) appendFileInternal(ctx context.Context, fileID string, data []b
 // modifyFileWithRetry modifies a file with retry logic
 func (c *Client) modifyFileWithRetry(ctx context.Context, fileID string, offset int64, data []byte) error {
 	var lastErr error
-	for i := 0; i < c.config.RetryCount; i++ {
+	backoff := c.newRetryBackoff(ctx)
+	retryCount := c.retryCount(ctx)
+	for i := 0; i < retryCount; i++ {
 		err := c.modifyFileInternal(ctx, fileID, offset, data)
 		if err == nil {
 			return nil
 		}
 		lastErr = err
 
-		if err == ErrFileNotFound || err == ErrInvalidFileID {
+		if !c.retryableFunc(ctx)(err) {
 			return err
 		}
 
-		if i < c.config.RetryCount-1 {
+		if i < retryCount-1 {
+			delay := backoff.Next()
+			spanFromContext(ctx).SetTag("fdfs.retry.attempt", i+1)
+			c.observerFor(ctx).OnRetry(ctx, "modify_file", i+1, err, delay)
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case <-time.After(time.Second * time.Duration(i+1)):
+			case <-time.After(delay):
 			}
 		}
 	}
@@ -228,7 +276,7 @@ func (c *Client) modifyFileWithRetry(ctx context.Context, fileID string, offset
 }
 
 // modifyFileInternal performs the actual file modification
-func (c *Client) modifyFileInternal(ctx context.Context, fileID string, offset int64, data []byte) error {
+func (c *Client) modifyFileInternal(ctx context.Context, fileID string, offset int64, data []byte) (err error) {
 	groupName, remoteFilename, err := splitFileID(fileID)
 	if err != nil {
 		return err
@@ -239,9 +287,19 @@ func (c *Client) modifyFileInternal(ctx context.Context, fileID string, offset i
 	if err != nil {
 		return err
 	}
+	storageAddr := storageServer.IPAddr + ":" + fmt.Sprintf("%d", storageServer.Port)
+
+	start := time.Now()
+	defer func() { c.recordMetrics("modify_file", storageAddr, fileID, start, len(data), err) }()
+
+	reserved, err := c.byteSem.take(ctx, int64(len(data)))
+	if err != nil {
+		return err
+	}
+	defer c.byteSem.give(reserved)
 
 	// Get connection
-	conn, err := c.storagePool.Get(ctx, storageServer.IPAddr+":"+fmt.Sprintf("%d", storageServer.Port))
+	conn, err := c.storagePool.Get(ctx, storageAddr)
 	if err != nil {
 		return err
 	}
@@ -259,15 +317,15 @@ func (c *Client) modifyFileInternal(ctx context.Context, fileID string, offset i
 	buf.Write(data)
 
 	// Send request
-	if err := conn.Send(header, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(header, c.networkTimeout(ctx)); err != nil {
 		return err
 	}
-	if err := conn.Send(buf.Bytes(), c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(buf.Bytes(), c.networkTimeout(ctx)); err != nil {
 		return err
 	}
 
 	// Receive response
-	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.config.NetworkTimeout)
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.networkTimeout(ctx))
 	if err != nil {
 		return err
 	}
@@ -287,22 +345,27 @@ func (c *Client) modifyFileInternal(ctx context.Context, fileID string, offset i
 // truncateFileWithRetry truncates a file with retry logic
 func (c *Client) truncateFileWithRetry(ctx context.Context, fileID string, size int64) error {
 	var lastErr error
-	for i := 0; i < c.config.RetryCount; i++ {
+	backoff := c.newRetryBackoff(ctx)
+	retryCount := c.retryCount(ctx)
+	for i := 0; i < retryCount; i++ {
 		err := c.truncateFileInternal(ctx, fileID, size)
 		if err == nil {
 			return nil
 		}
 		lastErr = err
 
-		if err == ErrFileNotFound || err == ErrInvalidFileID {
+		if !c.retryableFunc(ctx)(err) {
 			return err
 		}
 
-		if i < c.config.RetryCount-1 {
+		if i < retryCount-1 {
+			delay := backoff.Next()
+			spanFromContext(ctx).SetTag("fdfs.retry.attempt", i+1)
+			c.observerFor(ctx).OnRetry(ctx, "truncate_file", i+1, err, delay)
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case <-time.After(time.Second * time.Duration(i+1)):
+			case <-time.After(delay):
 			}
 		}
 	}
@@ -310,7 +373,7 @@ func (c *Client) truncateFileWithRetry(ctx context.Context, fileID string, size
 }
 
 // truncateFileInternal performs the actual file truncation
-func (c *Client) truncateFileInternal(ctx context.Context, fileID string, size int64) error {
+func (c *Client) truncateFileInternal(ctx context.Context, fileID string, size int64) (err error) {
 	groupName, remoteFilename, err := splitFileID(fileID)
 	if err != nil {
 		return err
@@ -321,9 +384,13 @@ func (c *Client) truncateFileInternal(ctx context.Context, fileID string, size i
 	if err != nil {
 		return err
 	}
+	storageAddr := storageServer.IPAddr + ":" + fmt.Sprintf("%d", storageServer.Port)
+
+	start := time.Now()
+	defer func() { c.recordMetrics("truncate_file", storageAddr, fileID, start, 0, err) }()
 
 	// Get connection
-	conn, err := c.storagePool.Get(ctx, storageServer.IPAddr+":"+fmt.Sprintf("%d", storageServer.Port))
+	conn, err := c.storagePool.Get(ctx, storageAddr)
 	if err != nil {
 		return err
 	}
@@ -340,15 +407,15 @@ func (c *Client) truncateFileInternal(ctx context.Context, fileID string, size i
 	buf.Write([]byte(remoteFilename))
 
 	// Send request
-	if err := conn.Send(header, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(header, c.networkTimeout(ctx)); err != nil {
 		return err
 	}
-	if err := conn.Send(buf.Bytes(), c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(buf.Bytes(), c.networkTimeout(ctx)); err != nil {
 		return err
 	}
 
 	// Receive response
-	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.config.NetworkTimeout)
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.networkTimeout(ctx))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,275 @@
+// Package sftp provides an SFTP-backed storage backend that exposes the same
+// upload/download/delete/stat surface as the native fdfs storage client, so a
+// plain SSH server can be used as a FastDFS-style DR or cold-tier target.
+//
+// # Copyright (C) 2025 FastDFS Go Client Contributors
+//
+// FastDFS may be copied only under the terms of the GNU General
+// Public License V3, which may be found in the FastDFS source kit.
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	fdfs "github.com/happyfish100/fastdfs/go_client"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// AuthMethod selects how the backend authenticates to the SFTP server.
+type AuthMethod int
+
+const (
+	// AuthPassword authenticates with Config.Password.
+	AuthPassword AuthMethod = iota
+	// AuthPrivateKey authenticates with Config.PrivateKey (PEM-encoded).
+	AuthPrivateKey
+)
+
+// Config holds the connection settings for the SFTP backend.
+type Config struct {
+	// Addr is the SFTP server address in "host:port" format.
+	Addr string
+
+	// User is the SSH username used to authenticate.
+	User string
+
+	// Auth selects which of Password/PrivateKey is used to authenticate.
+	Auth AuthMethod
+
+	// Password is used when Auth is AuthPassword.
+	Password string
+
+	// PrivateKey is a PEM-encoded private key used when Auth is AuthPrivateKey.
+	PrivateKey []byte
+
+	// HostKeyCallback verifies the server's host key. It is required;
+	// use ssh.FixedHostKey or golang.org/x/crypto/ssh/knownhosts in production.
+	HostKeyCallback ssh.HostKeyCallback
+
+	// RootDir is the remote directory that file IDs are resolved relative to.
+	RootDir string
+
+	// ConnectTimeout bounds the SSH dial.
+	ConnectTimeout time.Duration
+}
+
+// Backend implements upload/download/delete/stat operations against an SFTP
+// server, mapping FastDFS file IDs ("group/M00/xx/yy/filename") to
+// deterministic remote paths under Config.RootDir.
+type Backend struct {
+	sshClient  *ssh.Client
+	sftpClient *sftp.Client
+	rootDir    string
+}
+
+// NewBackend dials the SFTP server described by cfg and returns a ready-to-use
+// Backend. The caller must call Close when done.
+func NewBackend(cfg *Config) (*Backend, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("sftp: config is nil")
+	}
+	if cfg.Addr == "" {
+		return nil, fmt.Errorf("sftp: addr is required")
+	}
+	if cfg.HostKeyCallback == nil {
+		return nil, fmt.Errorf("sftp: HostKeyCallback is required")
+	}
+
+	var authMethods []ssh.AuthMethod
+	switch cfg.Auth {
+	case AuthPassword:
+		authMethods = append(authMethods, ssh.Password(cfg.Password))
+	case AuthPrivateKey:
+		signer, err := ssh.ParsePrivateKey(cfg.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("sftp: parse private key: %w", err)
+		}
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	default:
+		return nil, fmt.Errorf("sftp: unknown auth method %d", cfg.Auth)
+	}
+
+	timeout := cfg.ConnectTimeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	sshConfig := &ssh.ClientConfig{
+		User:            cfg.User,
+		Auth:            authMethods,
+		HostKeyCallback: cfg.HostKeyCallback,
+		Timeout:         timeout,
+	}
+
+	sshClient, err := ssh.Dial("tcp", cfg.Addr, sshConfig)
+	if err != nil {
+		return nil, fmt.Errorf("sftp: dial %s: %w", cfg.Addr, err)
+	}
+
+	sftpClient, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, fmt.Errorf("sftp: new client: %w", err)
+	}
+
+	rootDir := cfg.RootDir
+	if rootDir == "" {
+		rootDir = "/"
+	}
+
+	return &Backend{
+		sshClient:  sshClient,
+		sftpClient: sftpClient,
+		rootDir:    rootDir,
+	}, nil
+}
+
+// Close releases the underlying SFTP and SSH connections.
+func (b *Backend) Close() error {
+	var errs []error
+	if err := b.sftpClient.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := b.sshClient.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("sftp: close errors: %v", errs)
+	}
+	return nil
+}
+
+// Upload stores data under groupName and returns a FastDFS-style file ID
+// ("group/M00/xx/yy/filename.ext") that deterministically maps to the
+// remote path the data was written to.
+func (b *Backend) Upload(ctx context.Context, groupName string, data []byte, extName string) (string, error) {
+	remoteFilename, err := newRemoteFilename(extName)
+	if err != nil {
+		return "", err
+	}
+	fileID := joinFileID(groupName, remoteFilename)
+
+	remotePath := b.resolvePath(fileID)
+	if err := b.sftpClient.MkdirAll(path.Dir(remotePath)); err != nil {
+		return "", mapStatusToError(err)
+	}
+
+	f, err := b.sftpClient.Create(remotePath)
+	if err != nil {
+		return "", mapStatusToError(err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return "", mapStatusToError(err)
+	}
+
+	return fileID, nil
+}
+
+// Download reads the full contents of the file identified by fileID.
+func (b *Backend) Download(ctx context.Context, fileID string) ([]byte, error) {
+	remotePath := b.resolvePath(fileID)
+
+	f, err := b.sftpClient.Open(remotePath)
+	if err != nil {
+		return nil, mapStatusToError(err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, f); err != nil {
+		return nil, mapStatusToError(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Delete removes the file identified by fileID.
+func (b *Backend) Delete(ctx context.Context, fileID string) error {
+	remotePath := b.resolvePath(fileID)
+	if err := b.sftpClient.Remove(remotePath); err != nil {
+		return mapStatusToError(err)
+	}
+	return nil
+}
+
+// Stat returns file metadata for fileID, translated into the fdfs FileInfo shape.
+func (b *Backend) Stat(ctx context.Context, fileID string) (*fdfs.FileInfo, error) {
+	remotePath := b.resolvePath(fileID)
+
+	info, err := b.sftpClient.Stat(remotePath)
+	if err != nil {
+		return nil, mapStatusToError(err)
+	}
+
+	return &fdfs.FileInfo{
+		FileSize:   info.Size(),
+		CreateTime: info.ModTime(),
+	}, nil
+}
+
+// resolvePath maps a FastDFS file ID to its deterministic remote path under rootDir.
+func (b *Backend) resolvePath(fileID string) string {
+	return path.Join(b.rootDir, fileID)
+}
+
+// joinFileID builds a FastDFS-style file ID from a group name and remote filename.
+func joinFileID(groupName, remoteFilename string) string {
+	return groupName + "/" + remoteFilename
+}
+
+// newRemoteFilename generates a deterministic-layout remote filename following
+// the FastDFS "M00/xx/yy/hash.ext" convention, using random bytes as the hash
+// since the SFTP backend has no storage-daemon-assigned ID to reuse.
+func newRemoteFilename(extName string) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("sftp: generate file name: %w", err)
+	}
+	hash := hex.EncodeToString(raw)
+
+	name := fmt.Sprintf("M00/%s/%s/%s", hash[0:2], hash[2:4], hash)
+	if extName != "" {
+		name += "." + extName
+	}
+	return name, nil
+}
+
+// mapStatusToError maps SFTP protocol status codes to the sentinel errors
+// exposed by the fdfs package, so callers can keep using errors.Is regardless
+// of which backend they are talking to.
+func mapStatusToError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	statusErr, ok := err.(*sftp.StatusError)
+	if !ok {
+		return err
+	}
+
+	switch statusErr.Code {
+	case uint32(sftp.ErrSSHFxNoSuchFile):
+		return fdfs.ErrFileNotFound
+	case uint32(sftp.ErrSSHFxPermissionDenied):
+		return fdfs.ErrInvalidArgument
+	case uint32(sftp.ErrSSHFxFailure):
+		return fmt.Errorf("sftp: operation failed: %w", err)
+	case uint32(sftp.ErrSSHFxBadMessage):
+		return fdfs.ErrInvalidResponse
+	case uint32(sftp.ErrSSHFxNoConnection), uint32(sftp.ErrSSHFxConnectionLost):
+		return fdfs.ErrStorageServerOffline
+	case uint32(sftp.ErrSSHFxOpUnsupported):
+		return fdfs.ErrOperationNotSupported
+	default:
+		return err
+	}
+}
@@ -0,0 +1,538 @@
+// Package bench implements a first-class benchmarking workload runner for
+// the FastDFS Go client, extracted from the ad-hoc metrics helpers in
+// examples/performance into a reusable library behind the fastdfs-bench CLI
+// (cmd/fastdfs-bench), modeled after SeaweedFS's "weed benchmark".
+package bench
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	fdfs "github.com/happyfish100/fastdfs/go_client"
+)
+
+// Workload labels which operation a Report covers.
+type Workload int
+
+const (
+	WorkloadWrite Workload = iota
+	WorkloadRead
+	WorkloadDelete
+	WorkloadParallelRead
+)
+
+// String implements fmt.Stringer.
+func (w Workload) String() string {
+	switch w {
+	case WorkloadWrite:
+		return "write"
+	case WorkloadRead:
+		return "read"
+	case WorkloadDelete:
+		return "delete"
+	case WorkloadParallelRead:
+		return "parallelread"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalJSON implements json.Marshaler, encoding a Workload as its String()
+// form rather than the underlying int, so a Report written to a result file
+// (see cmd/fastdfs-bench's -output and compare) stays readable and stable
+// across reordering the Workload const block.
+func (w Workload) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + w.String() + `"`), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, the inverse of MarshalJSON.
+func (w *Workload) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case `"write"`:
+		*w = WorkloadWrite
+	case `"read"`:
+		*w = WorkloadRead
+	case `"delete"`:
+		*w = WorkloadDelete
+	case `"parallelread"`:
+		*w = WorkloadParallelRead
+	default:
+		return fmt.Errorf("bench: unknown workload %s", data)
+	}
+	return nil
+}
+
+// Config configures a Runner. Write, Read, and Delete select which phases
+// Run executes, in that order; any combination may be set (a mixed
+// read-while-writing workload is not modeled here, but writing then
+// reading then deleting the same generated IDs is the common case this
+// mirrors from weed benchmark).
+type Config struct {
+	// Client is the FastDFS client the benchmark runs against.
+	Client *fdfs.Client
+
+	// Concurrency is the number of worker goroutines pulling from the job
+	// channel for each phase (-c).
+	Concurrency int
+
+	// NumOps is the number of operations to run in the write phase (-n).
+	// The read and delete phases operate on however many file IDs the
+	// write phase (or IDListPath) produced.
+	NumOps int
+
+	// Size is the payload size in bytes written during the write phase
+	// (-size).
+	Size int
+
+	Write  bool
+	Read   bool
+	Delete bool
+
+	// SequentialRead reads file IDs in the order they were written rather
+	// than shuffling them first.
+	SequentialRead bool
+
+	// DeletePercentage, 0-100, is the chance a freshly-written file is
+	// deleted again in-flight during the write phase, instead of being
+	// kept for the read/delete phases.
+	DeletePercentage int
+
+	// IDListPath, if set, records every file ID kept by the write phase
+	// (one per line) and is read back to drive the read and delete
+	// phases, so a benchmark run can be split across separate write and
+	// read invocations.
+	IDListPath string
+
+	// SizePadding, when true, adds 0-63 random extra bytes to Size for each
+	// write, matching weed benchmark's behavior of not writing perfectly
+	// uniform-size payloads.
+	SizePadding bool
+
+	// ParallelRead, when true, runs a second read phase over the same IDs
+	// as Read using Client.DownloadRangesParallel (parallelupload.go)
+	// instead of Client.DownloadFile, reported separately as
+	// WorkloadParallelRead so the two phases' Reports can be compared
+	// directly for the same files in one run: the whole point of this
+	// phase existing alongside Read is measuring the throughput difference
+	// a multi-range parallel download makes, which is most visible on
+	// large files (point -size at something well over 100 MiB to see it).
+	ParallelRead bool
+
+	// ParallelOptions configures the ParallelRead phase's
+	// DownloadRangesParallel calls. Concurrency and ChunkSize default the
+	// same way DownloadRangesParallel itself defaults them when left zero.
+	ParallelOptions fdfs.ParallelOptions
+}
+
+// maxLatencyReservoir bounds how many latency samples Stat.record keeps,
+// via reservoir sampling, regardless of how many operations a phase runs.
+// Appending every sample to an unbounded slice OOMs for large -n runs;
+// 100000 samples is enough for stable percentile estimates while capping
+// memory at a few megabytes.
+const maxLatencyReservoir = 100000
+
+// Stat accumulates latency and throughput samples for one phase of a run,
+// recorded concurrently by every worker goroutine and finalized once the
+// phase completes. Latency samples beyond maxLatencyReservoir are kept via
+// reservoir sampling rather than all retained.
+type Stat struct {
+	mu        sync.Mutex
+	count     int64
+	errors    int64
+	bytes     int64
+	seen      int64 // successful ops seen, for the reservoir's replace probability
+	latencies []time.Duration
+	started   time.Time
+	elapsed   time.Duration
+}
+
+func newStat() *Stat {
+	return &Stat{started: time.Now()}
+}
+
+func (s *Stat) record(d time.Duration, bytes int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	if err != nil {
+		s.errors++
+		return
+	}
+	s.bytes += bytes
+	s.seen++
+
+	if int64(len(s.latencies)) < maxLatencyReservoir {
+		s.latencies = append(s.latencies, d)
+	} else if j := rand.Int63n(s.seen); j < maxLatencyReservoir {
+		s.latencies[j] = d
+	}
+}
+
+func (s *Stat) finish() {
+	s.elapsed = time.Since(s.started)
+}
+
+// percentile returns the latency at percentile p (0-100) across recorded
+// samples, or 0 if there are none. Must be called after finish.
+func (s *Stat) percentile(p float64) time.Duration {
+	if len(s.latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), s.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(float64(len(sorted)-1) * p / 100)
+	return sorted[idx]
+}
+
+// Report summarizes one phase of a Runner.Run.
+type Report struct {
+	Workload  Workload
+	Count     int64
+	Errors    int64
+	Bytes     int64
+	Elapsed   time.Duration
+	Min       time.Duration
+	Avg       time.Duration
+	Max       time.Duration
+	P50       time.Duration
+	P75       time.Duration
+	P90       time.Duration
+	P95       time.Duration
+	P99       time.Duration
+	P999      time.Duration
+	OpsPerSec float64
+	MBPerSec  float64
+}
+
+func (s *Stat) report(w Workload) Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := Report{
+		Workload: w,
+		Count:    s.count,
+		Errors:   s.errors,
+		Bytes:    s.bytes,
+		Elapsed:  s.elapsed,
+		P50:      s.percentile(50),
+		P75:      s.percentile(75),
+		P90:      s.percentile(90),
+		P95:      s.percentile(95),
+		P99:      s.percentile(99),
+		P999:     s.percentile(99.9),
+	}
+	if len(s.latencies) > 0 {
+		r.Min = s.percentile(0)
+		r.Max = s.percentile(100)
+		var sum time.Duration
+		for _, d := range s.latencies {
+			sum += d
+		}
+		r.Avg = sum / time.Duration(len(s.latencies))
+	}
+	if s.elapsed > 0 {
+		r.OpsPerSec = float64(s.count) / s.elapsed.Seconds()
+		r.MBPerSec = float64(s.bytes) / 1024 / 1024 / s.elapsed.Seconds()
+	}
+	return r
+}
+
+// String formats r for terminal output.
+func (r Report) String() string {
+	return fmt.Sprintf("%-6s %8d ops (%d errors) in %-12v %10.2f ops/sec %10.2f MB/sec  min=%-10v avg=%-10v max=%-10v p50=%-10v p75=%-10v p90=%-10v p95=%-10v p99=%-10v p999=%v",
+		r.Workload, r.Count, r.Errors, r.Elapsed.Round(time.Millisecond), r.OpsPerSec, r.MBPerSec,
+		r.Min, r.Avg, r.Max, r.P50, r.P75, r.P90, r.P95, r.P99, r.P999)
+}
+
+// Runner drives a benchmark workload against Config.Client.
+type Runner struct {
+	cfg Config
+}
+
+// NewRunner returns a Runner for cfg.
+func NewRunner(cfg Config) *Runner {
+	return &Runner{cfg: cfg}
+}
+
+// Run executes the configured phases (write, then read, then delete) and
+// returns one Report per phase that ran.
+func (r *Runner) Run(ctx context.Context) ([]Report, error) {
+	var reports []Report
+	var ids []string
+
+	if r.cfg.Write {
+		stat, written, err := r.runWrite(ctx)
+		if err != nil {
+			return reports, err
+		}
+		reports = append(reports, stat.report(WorkloadWrite))
+		ids = written
+
+		if r.cfg.IDListPath != "" {
+			if err := writeIDList(r.cfg.IDListPath, written); err != nil {
+				return reports, fmt.Errorf("write id list: %w", err)
+			}
+		}
+	}
+
+	if r.cfg.Read {
+		readIDs, err := r.idsForPhase(ids)
+		if err != nil {
+			return reports, err
+		}
+		if !r.cfg.SequentialRead {
+			rand.Shuffle(len(readIDs), func(i, j int) { readIDs[i], readIDs[j] = readIDs[j], readIDs[i] })
+		}
+
+		stat, err := r.runRead(ctx, readIDs)
+		if err != nil {
+			return reports, err
+		}
+		reports = append(reports, stat.report(WorkloadRead))
+	}
+
+	if r.cfg.ParallelRead {
+		readIDs, err := r.idsForPhase(ids)
+		if err != nil {
+			return reports, err
+		}
+		if !r.cfg.SequentialRead {
+			rand.Shuffle(len(readIDs), func(i, j int) { readIDs[i], readIDs[j] = readIDs[j], readIDs[i] })
+		}
+
+		stat, err := r.runParallelRead(ctx, readIDs)
+		if err != nil {
+			return reports, err
+		}
+		reports = append(reports, stat.report(WorkloadParallelRead))
+	}
+
+	if r.cfg.Delete {
+		deleteIDs, err := r.idsForPhase(ids)
+		if err != nil {
+			return reports, err
+		}
+
+		stat, err := r.runDelete(ctx, deleteIDs)
+		if err != nil {
+			return reports, err
+		}
+		reports = append(reports, stat.report(WorkloadDelete))
+	}
+
+	return reports, nil
+}
+
+// idsForPhase returns written (the write phase's in-memory result) when
+// non-empty, otherwise loads Config.IDListPath so the read/delete phases
+// can run as a separate invocation from the write phase.
+func (r *Runner) idsForPhase(written []string) ([]string, error) {
+	if len(written) > 0 {
+		return append([]string(nil), written...), nil
+	}
+	if r.cfg.IDListPath == "" {
+		return nil, fmt.Errorf("bench: no file IDs to operate on; run a write phase or set IDListPath")
+	}
+	ids, err := readIDList(r.cfg.IDListPath)
+	if err != nil {
+		return nil, fmt.Errorf("read id list: %w", err)
+	}
+	return ids, nil
+}
+
+// runWrite uploads Config.NumOps buffers of Config.Size bytes across
+// Config.Concurrency workers, optionally deleting a DeletePercentage chance
+// of them again in-flight, and returns the IDs of the files kept.
+func (r *Runner) runWrite(ctx context.Context) (*Stat, []string, error) {
+	stat := newStat()
+
+	jobs := make(chan struct{}, r.cfg.Concurrency)
+	var (
+		mu      sync.Mutex
+		written []string
+		wg      sync.WaitGroup
+	)
+
+	for i := 0; i < r.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data := make([]byte, r.cfg.Size+63)
+			for range jobs {
+				size := r.cfg.Size
+				if r.cfg.SizePadding {
+					size += rand.Intn(64)
+				}
+				buf := data[:size]
+				// Randomize the payload per op so identical-size writes
+				// don't collide on a storage backend that dedupes by
+				// content hash, matching weed benchmark's write workload.
+				rand.Read(buf)
+
+				start := time.Now()
+				fileID, err := r.cfg.Client.UploadBuffer(ctx, buf, "bin", nil)
+				stat.record(time.Since(start), int64(len(buf)), err)
+				if err != nil {
+					continue
+				}
+
+				if r.cfg.DeletePercentage > 0 && rand.Intn(100) < r.cfg.DeletePercentage {
+					r.cfg.Client.DeleteFile(ctx, fileID)
+					continue
+				}
+
+				mu.Lock()
+				written = append(written, fileID)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := 0; i < r.cfg.NumOps; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+	stat.finish()
+
+	return stat, written, nil
+}
+
+// runRead downloads every ID in ids across Config.Concurrency workers.
+func (r *Runner) runRead(ctx context.Context, ids []string) (*Stat, error) {
+	stat := newStat()
+
+	jobs := make(chan string, r.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < r.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for fileID := range jobs {
+				start := time.Now()
+				data, err := r.cfg.Client.DownloadFile(ctx, fileID)
+				stat.record(time.Since(start), int64(len(data)), err)
+			}
+		}()
+	}
+
+	for _, id := range ids {
+		jobs <- id
+	}
+	close(jobs)
+	wg.Wait()
+	stat.finish()
+
+	return stat, nil
+}
+
+// discardWriterAt is an io.WriterAt that drops every write, for measuring
+// DownloadRangesParallel's fetch throughput without also paying for local
+// disk I/O, the same way runRead discards DownloadFile's returned bytes
+// rather than writing them anywhere.
+type discardWriterAt struct{}
+
+func (discardWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	return len(p), nil
+}
+
+// runParallelRead is runRead's counterpart for Config.ParallelRead: each
+// worker fetches a whole file via Client.DownloadRangesParallel instead of
+// Client.DownloadFile, so the two Reports can be compared side by side for
+// the same IDs.
+func (r *Runner) runParallelRead(ctx context.Context, ids []string) (*Stat, error) {
+	stat := newStat()
+	opts := r.cfg.ParallelOptions
+
+	jobs := make(chan string, r.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < r.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for fileID := range jobs {
+				start := time.Now()
+				n, err := r.cfg.Client.DownloadRangesParallel(ctx, fileID, discardWriterAt{}, &opts)
+				stat.record(time.Since(start), n, err)
+			}
+		}()
+	}
+
+	for _, id := range ids {
+		jobs <- id
+	}
+	close(jobs)
+	wg.Wait()
+	stat.finish()
+
+	return stat, nil
+}
+
+// runDelete deletes every ID in ids across Config.Concurrency workers.
+func (r *Runner) runDelete(ctx context.Context, ids []string) (*Stat, error) {
+	stat := newStat()
+
+	jobs := make(chan string, r.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < r.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for fileID := range jobs {
+				start := time.Now()
+				err := r.cfg.Client.DeleteFile(ctx, fileID)
+				stat.record(time.Since(start), 0, err)
+			}
+		}()
+	}
+
+	for _, id := range ids {
+		jobs <- id
+	}
+	close(jobs)
+	wg.Wait()
+	stat.finish()
+
+	return stat, nil
+}
+
+// writeIDList writes ids to path, one per line, for a later read/delete
+// phase invocation to consume via readIDList.
+func writeIDList(path string, ids []string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, id := range ids {
+		if _, err := w.WriteString(id + "\n"); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// readIDList reads back a file ID list written by writeIDList.
+func readIDList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			ids = append(ids, line)
+		}
+	}
+	return ids, scanner.Err()
+}
@@ -0,0 +1,163 @@
+// Package fdfs fixed-size block streaming upload/download.
+// This file adds UploadBlockStream/DownloadBlockStream, a pair of streaming
+// transfer paths for files too large to buffer as a single []byte (as
+// UploadBuffer/DownloadFile require) that don't need UploadChunked's
+// (chunkedupload.go) per-block manifest and post-upload verification pass
+// — just a rolling checksum and progress reporting. UploadStream/
+// DownloadStream (stream.go) already stream a whole file through the plain
+// upload/download protocol commands in fixed-size pieces; this file instead
+// splits the upload into independent appender-file blocks (block 0 via
+// UploadAppenderBuffer, the rest via AppendFile), which is what lets
+// DownloadBlockStream read it back one block at a time as a request range
+// rather than one continuous download stream.
+package fdfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+)
+
+// Block size bounds for UploadBlockStream/BlockStreamOptions.BlockSize.
+const (
+	minBlockStreamSize     = 128 << 10
+	maxBlockStreamSize     = 16 << 20
+	defaultBlockStreamSize = 1 << 20
+)
+
+// BlockStreamOptions configures UploadBlockStream.
+type BlockStreamOptions struct {
+	// BlockSize is the size of each appender block sent. Clamped to
+	// [minBlockStreamSize, maxBlockStreamSize]; defaults to
+	// defaultBlockStreamSize when <= 0.
+	BlockSize int64
+
+	// Metadata is attached to the file on its initial upload (block 0),
+	// the same as UploadAppenderBuffer's metadata parameter.
+	Metadata map[string]string
+
+	// Size is the caller's best knowledge of r's total length, passed
+	// through to Progress as bytesTotal. Leave zero when unknown; Progress
+	// is still called with bytesDone on every block.
+	Size int64
+
+	// HashOut, if non-nil, receives the SHA-256 of the full stream once
+	// UploadBlockStream returns successfully.
+	HashOut *[32]byte
+
+	// Progress, if set, is called after every block is committed with the
+	// cumulative bytes sent and opts.Size (0 if the caller didn't set it).
+	Progress func(bytesDone, bytesTotal int64)
+}
+
+// UploadBlockStream uploads r as a sequence of independent appender blocks
+// (block 0 via UploadAppenderBuffer, the rest via AppendFile), without
+// requiring the caller to know r's total length or buffer it in full. See
+// DownloadBlockStream for the corresponding read path.
+func (c *Client) UploadBlockStream(ctx context.Context, r io.Reader, extName string, opts *BlockStreamOptions) (string, error) {
+	if err := c.checkClosed(); err != nil {
+		return "", err
+	}
+	if opts == nil {
+		opts = &BlockStreamOptions{}
+	}
+
+	blockSize := opts.BlockSize
+	switch {
+	case blockSize <= 0:
+		blockSize = defaultBlockStreamSize
+	case blockSize < minBlockStreamSize:
+		blockSize = minBlockStreamSize
+	case blockSize > maxBlockStreamSize:
+		blockSize = maxBlockStreamSize
+	}
+
+	hasher := sha256.New()
+	buf := make([]byte, blockSize)
+	var fileID string
+	var sent int64
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return "", fmt.Errorf("failed to read upload data at offset %d: %w", sent, err)
+		}
+
+		if n > 0 {
+			hasher.Write(buf[:n])
+			if fileID == "" {
+				id, uerr := c.UploadAppenderBuffer(ctx, buf[:n], extName, opts.Metadata)
+				if uerr != nil {
+					return "", uerr
+				}
+				fileID = id
+			} else if aerr := c.AppendFile(ctx, fileID, buf[:n]); aerr != nil {
+				return "", aerr
+			}
+
+			sent += int64(n)
+			if opts.Progress != nil {
+				opts.Progress(sent, opts.Size)
+			}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+	}
+
+	if fileID == "" {
+		id, uerr := c.UploadAppenderBuffer(ctx, nil, extName, opts.Metadata)
+		if uerr != nil {
+			return "", uerr
+		}
+		fileID = id
+	}
+
+	if opts.HashOut != nil {
+		copy(opts.HashOut[:], hasher.Sum(nil))
+	}
+	return fileID, nil
+}
+
+// DownloadBlockStream returns an io.ReadCloser that streams fileID's
+// content without materializing it as a single []byte, fetching it
+// defaultBlockStreamSize bytes at a time via DownloadFileRange in a
+// background goroutine. The io.Pipe it's built on provides backpressure:
+// the background fetch blocks on the next range request until the caller
+// has read the previous one, so at most one block is held in memory ahead
+// of the reader.
+func (c *Client) DownloadBlockStream(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	if err := c.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	info, err := c.GetFileInfo(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		var offset int64
+		for offset < info.FileSize {
+			n := int64(defaultBlockStreamSize)
+			if remaining := info.FileSize - offset; remaining < n {
+				n = remaining
+			}
+			data, err := c.DownloadFileRange(ctx, fileID, offset, n)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := pw.Write(data); err != nil {
+				return
+			}
+			offset += n
+		}
+		pw.Close()
+	}()
+
+	return pr, nil
+}
@@ -0,0 +1,35 @@
+// Package fdfs buffer pooling hook.
+// This file defines the BufferPool interface UploadStream/DownloadStream
+// use for their chunk buffers (see stream.go), distinct from the
+// connection pools in connection.go: those pool *Connections, this pools
+// the []byte chunks copied across them. ClientConfig.BufferPool is
+// optional; a nil BufferPool falls back to plain allocation, identical to
+// this package's behavior before the field existed. See go_client/bufpool
+// for a size-bucketed sync.Pool implementation.
+package fdfs
+
+// BufferPool supplies and reclaims the []byte chunk buffers used while
+// streaming upload/download bodies. Get returns a buffer with length size
+// (capacity may be larger); Put returns a buffer obtained from Get for
+// reuse once the caller is done with it. Implementations must be safe for
+// concurrent use.
+type BufferPool interface {
+	Get(size int) []byte
+	Put(buf []byte)
+}
+
+// defaultBufferPool allocates a fresh buffer on every Get and discards it
+// on Put, i.e. no pooling at all. Used when ClientConfig.BufferPool is
+// unset so call sites never need a nil check.
+type defaultBufferPool struct{}
+
+func (defaultBufferPool) Get(size int) []byte { return make([]byte, size) }
+func (defaultBufferPool) Put(buf []byte)      {}
+
+// bufferPool returns ClientConfig.BufferPool, or defaultBufferPool when unset.
+func (c *Client) bufferPool() BufferPool {
+	if c.config.BufferPool != nil {
+		return c.config.BufferPool
+	}
+	return defaultBufferPool{}
+}
@@ -0,0 +1,84 @@
+// Package bufpool implements fdfs.BufferPool with size-bucketed
+// sync.Pools, so UploadStream/DownloadStream chunk buffers (see the core
+// package's stream.go) are recycled instead of allocated fresh on every
+// call. Plug one in via ClientConfig.BufferPool:
+//
+//	client, err := fdfs.NewClient(&fdfs.ClientConfig{
+//		TrackerAddrs: []string{"192.168.1.100:22122"},
+//		BufferPool:   bufpool.New(),
+//	})
+package bufpool
+
+import "sync"
+
+// minBucketSize and maxBucketSize bound the power-of-two buckets Pool
+// keeps: requests below minBucketSize round up to it, and requests above
+// maxBucketSize (e.g. a caller-overridden ChunkSize far larger than any
+// realistic chunk) bypass pooling entirely rather than growing the pool
+// for a size that's unlikely to repeat.
+const (
+	minBucketSize = 4 * 1024
+	maxBucketSize = 16 * 1024 * 1024
+)
+
+// Pool is an fdfs.BufferPool backed by one sync.Pool per power-of-two size
+// class from minBucketSize to maxBucketSize. Get rounds a request up to
+// its size class's bucket so buffers are reused across calls asking for
+// slightly different sizes (e.g. the last, short chunk of a transfer).
+type Pool struct {
+	buckets []*sync.Pool // indexed by size class; buckets[i] holds slices of len 1<<(log2(minBucketSize)+i)
+}
+
+// New returns a ready-to-use Pool.
+func New() *Pool {
+	p := &Pool{}
+	for size := minBucketSize; size <= maxBucketSize; size *= 2 {
+		size := size
+		p.buckets = append(p.buckets, &sync.Pool{
+			New: func() interface{} {
+				buf := make([]byte, size)
+				return &buf
+			},
+		})
+	}
+	return p
+}
+
+// bucketFor returns the index into p.buckets whose size class is the
+// smallest power of two >= size, or -1 if size exceeds maxBucketSize.
+func bucketFor(size int) int {
+	if size > maxBucketSize {
+		return -1
+	}
+	class := minBucketSize
+	for i := 0; class <= maxBucketSize; i++ {
+		if class >= size {
+			return i
+		}
+		class *= 2
+	}
+	return -1
+}
+
+// Get implements fdfs.BufferPool.
+func (p *Pool) Get(size int) []byte {
+	i := bucketFor(size)
+	if i < 0 || i >= len(p.buckets) {
+		return make([]byte, size)
+	}
+	bufPtr := p.buckets[i].Get().(*[]byte)
+	return (*bufPtr)[:size]
+}
+
+// Put implements fdfs.BufferPool. buf is only retained if its capacity
+// exactly matches one of Pool's size classes (i.e. it came from Get);
+// anything else is silently dropped rather than pooled, since re-slicing
+// it back to that capacity could expose stale data to the next Get.
+func (p *Pool) Put(buf []byte) {
+	i := bucketFor(cap(buf))
+	if i < 0 || i >= len(p.buckets) || (minBucketSize<<uint(i)) != cap(buf) {
+		return
+	}
+	full := buf[:cap(buf)]
+	p.buckets[i].Put(&full)
+}
@@ -0,0 +1,82 @@
+// Package fdfs in-flight byte budget.
+// This file bounds the total memory held by pending upload/append/modify
+// payload buffers across concurrent callers, so client RSS stays bounded
+// regardless of caller concurrency. See ClientConfig.MaxInFlightBytes.
+package fdfs
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultMaxInFlightBytes is used when ClientConfig.MaxInFlightBytes is unset.
+const defaultMaxInFlightBytes = 128 << 20 // 128 MiB
+
+// byteSemaphore is a counting semaphore over bytes rather than request
+// count, used by uploadSlaveFileInternal, appendFileInternal, and
+// modifyFileInternal to bound the total size of in-flight payload buffers.
+type byteSemaphore struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	max  int64
+	held int64
+}
+
+// newByteSemaphore returns a byteSemaphore capped at max bytes, or
+// defaultMaxInFlightBytes when max <= 0.
+func newByteSemaphore(max int64) *byteSemaphore {
+	if max <= 0 {
+		max = defaultMaxInFlightBytes
+	}
+	s := &byteSemaphore{max: max}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// take reserves n bytes, blocking until enough are available, and returns
+// the amount actually reserved. n is capped at the semaphore's max so a
+// single request larger than the whole budget can't wait forever for room
+// that will never exist; it proceeds as soon as every other holder has
+// released. Callers must give back exactly the returned amount, not n,
+// since the two can differ when n was capped. take honors ctx.Done() via a
+// goroutine parked on the cond that wakes waiters on cancellation.
+func (s *byteSemaphore) take(ctx context.Context, n int64) (int64, error) {
+	if n > s.max {
+		n = s.max
+	}
+	if n < 0 {
+		n = 0
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.cond.Broadcast()
+			s.mu.Unlock()
+		case <-stop:
+		}
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.held+n > s.max {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		s.cond.Wait()
+	}
+	s.held += n
+	return n, nil
+}
+
+// give releases n bytes previously reserved by take, waking any blocked
+// waiters so they can recheck whether enough room is now free.
+func (s *byteSemaphore) give(n int64) {
+	s.mu.Lock()
+	s.held -= n
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
@@ -0,0 +1,91 @@
+package fdfs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestByteSemaphoreTakeGive(t *testing.T) {
+	s := newByteSemaphore(100)
+
+	reserved, err := s.take(context.Background(), 40)
+	require.NoError(t, err)
+	assert.EqualValues(t, 40, reserved)
+	assert.EqualValues(t, 40, s.held)
+
+	s.give(reserved)
+	assert.EqualValues(t, 0, s.held)
+}
+
+func TestByteSemaphoreCapsOversizedRequest(t *testing.T) {
+	// A single request larger than the whole budget must be capped to max,
+	// and give must release exactly the capped amount take returned, not
+	// the caller's original, uncapped size, or held would go negative and
+	// the budget would stop being enforced for the rest of the Client's
+	// life.
+	s := newByteSemaphore(100)
+
+	reserved, err := s.take(context.Background(), 500)
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, reserved)
+	assert.EqualValues(t, 100, s.held)
+
+	s.give(reserved)
+	assert.EqualValues(t, 0, s.held)
+}
+
+func TestByteSemaphoreBlocksUntilRoomFrees(t *testing.T) {
+	s := newByteSemaphore(10)
+
+	reserved, err := s.take(context.Background(), 10)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r, err := s.take(context.Background(), 5)
+		assert.NoError(t, err)
+		s.give(r)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("take should block while the semaphore is fully held")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	s.give(reserved)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("take should unblock once room is freed")
+	}
+}
+
+func TestByteSemaphoreTakeRespectsContextCancellation(t *testing.T) {
+	s := newByteSemaphore(10)
+
+	_, err := s.take(context.Background(), 10)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := s.take(ctx, 1)
+		errCh <- err
+	}()
+
+	cancel()
+
+	select {
+	case err := <-errCh:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("take should return once ctx is canceled")
+	}
+}
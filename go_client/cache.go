@@ -0,0 +1,477 @@
+// Package fdfs client-side read cache.
+// This file adds an optional two-level block cache for downloaded byte
+// ranges, plus a TTL cache for FileInfo/metadata results, so repeated reads
+// of hot files avoid round-trips to the storage server. See
+// ClientConfig.CacheTotalBytes. The block half is pluggable via the
+// BlockCache interface and ClientConfig.BlockCache; readCache is just the
+// built-in default implementation.
+package fdfs
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultCacheBlockSize is used when ClientConfig.CacheBlockSize is unset.
+const defaultCacheBlockSize = 1 << 20 // 1 MiB
+
+// BlockCache is a pluggable cache for downloaded byte-range blocks, keyed
+// by (fileID, blockIndex). ClientConfig.BlockCache is optional; when unset,
+// Client uses its built-in in-memory LRU (readCache) sized by
+// CacheBytesPerFile/CacheTotalBytes/CacheBlockSize. A custom
+// implementation lets a caller back block storage with something else
+// (e.g. an external LRU library, or a shared cache across processes)
+// while still getting DownloadFile/DownloadFileRange's transparent
+// block-aligned splitting and miss coalescing.
+type BlockCache interface {
+	// BlockSize is the fixed size DownloadFileRange aligns requested
+	// ranges to when consulting this cache.
+	BlockSize() int
+
+	// GetBlock returns the cached block at index for fileID, if present.
+	GetBlock(fileID string, index int64) ([]byte, bool)
+
+	// PutBlock inserts or replaces the block at index for fileID.
+	PutBlock(fileID string, index int64, data []byte)
+
+	// InvalidateFile drops every cached block for fileID.
+	InvalidateFile(fileID string)
+}
+
+// readCache is a two-level block cache: each file has a fixed-size LRU of
+// blockSize-aligned byte blocks capped by perFileBytes, and a single global
+// LRU spans every file's blocks and evicts the coldest one anywhere once
+// totalBytes is exceeded. FileInfo and metadata results are cached
+// separately, keyed by fileID, with a TTL.
+type readCache struct {
+	blockSize    int
+	perFileBytes int64
+	totalBytes   int64
+	ttl          time.Duration
+
+	mu        sync.Mutex
+	files     map[string]*fileBlockCache
+	global    *list.List // of *blockEntry, front = most recently used
+	totalUsed int64
+
+	infoMu sync.Mutex
+	info   map[string]cachedEntry
+
+	metaMu sync.Mutex
+	meta   map[string]cachedEntry
+
+	// hits/misses/evictions/bytes back Stats; updated without rc.mu since
+	// exact consistency between them isn't needed for reporting purposes.
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+// cachedEntry is a TTL-bounded cache entry holding either a *FileInfo or a
+// map[string]string.
+type cachedEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// blockEntry is one cached block, linked into both its file's local LRU and
+// the cache-wide global LRU so either can evict it.
+type blockEntry struct {
+	fileID     string
+	index      int64
+	data       []byte
+	fileElem   *list.Element
+	globalElem *list.Element
+}
+
+// fileBlockCache is the per-file LRU of cached blocks.
+type fileBlockCache struct {
+	usedBytes int64
+	lru       *list.List // of *blockEntry, front = most recently used
+	blocks    map[int64]*blockEntry
+}
+
+// newReadCache returns a readCache configured from config, or nil when the
+// read cache is disabled (CacheBytesPerFile or CacheTotalBytes unset).
+func newReadCache(config *ClientConfig) *readCache {
+	if config.CacheBytesPerFile <= 0 || config.CacheTotalBytes <= 0 {
+		return nil
+	}
+	blockSize := config.CacheBlockSize
+	if blockSize <= 0 {
+		blockSize = defaultCacheBlockSize
+	}
+	return &readCache{
+		blockSize:    blockSize,
+		perFileBytes: config.CacheBytesPerFile,
+		totalBytes:   config.CacheTotalBytes,
+		ttl:          config.CacheTTL,
+		files:        make(map[string]*fileBlockCache),
+		global:       list.New(),
+		info:         make(map[string]cachedEntry),
+		meta:         make(map[string]cachedEntry),
+	}
+}
+
+// getBlock returns the cached block at index for fileID, if present.
+func (rc *readCache) getBlock(fileID string, index int64) ([]byte, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	fc, ok := rc.files[fileID]
+	if !ok {
+		atomic.AddInt64(&rc.misses, 1)
+		return nil, false
+	}
+	be, ok := fc.blocks[index]
+	if !ok {
+		atomic.AddInt64(&rc.misses, 1)
+		return nil, false
+	}
+	fc.lru.MoveToFront(be.fileElem)
+	rc.global.MoveToFront(be.globalElem)
+	atomic.AddInt64(&rc.hits, 1)
+	return be.data, true
+}
+
+// putBlock inserts or replaces the cached block at index for fileID,
+// evicting the coldest block in the file (and then cache-wide) as needed to
+// stay within perFileBytes and totalBytes.
+func (rc *readCache) putBlock(fileID string, index int64, data []byte) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	fc, ok := rc.files[fileID]
+	if !ok {
+		fc = &fileBlockCache{lru: list.New(), blocks: make(map[int64]*blockEntry)}
+		rc.files[fileID] = fc
+	}
+
+	if existing, ok := fc.blocks[index]; ok {
+		rc.totalUsed += int64(len(data)) - int64(len(existing.data))
+		fc.usedBytes += int64(len(data)) - int64(len(existing.data))
+		existing.data = data
+		fc.lru.MoveToFront(existing.fileElem)
+		rc.global.MoveToFront(existing.globalElem)
+		return
+	}
+
+	be := &blockEntry{fileID: fileID, index: index, data: data}
+	be.fileElem = fc.lru.PushFront(be)
+	be.globalElem = rc.global.PushFront(be)
+	fc.usedBytes += int64(len(data))
+	fc.blocks[index] = be
+	rc.totalUsed += int64(len(data))
+
+	for fc.usedBytes > rc.perFileBytes && fc.lru.Len() > 1 {
+		rc.removeBlock(fc.lru.Back().Value.(*blockEntry))
+	}
+	for rc.totalUsed > rc.totalBytes && rc.global.Len() > 1 {
+		rc.removeBlock(rc.global.Back().Value.(*blockEntry))
+	}
+}
+
+// removeBlock unlinks be from its file's LRU and the global LRU. Callers
+// must hold rc.mu.
+func (rc *readCache) removeBlock(be *blockEntry) {
+	fc, ok := rc.files[be.fileID]
+	if !ok {
+		return
+	}
+	fc.lru.Remove(be.fileElem)
+	delete(fc.blocks, be.index)
+	fc.usedBytes -= int64(len(be.data))
+	rc.global.Remove(be.globalElem)
+	rc.totalUsed -= int64(len(be.data))
+	atomic.AddInt64(&rc.evictions, 1)
+	if fc.lru.Len() == 0 {
+		delete(rc.files, be.fileID)
+	}
+}
+
+// Stats reports cumulative block-cache hits, misses, and evictions, plus
+// the cache's current byte usage, implementing cacheStatsProvider.
+func (rc *readCache) Stats() (hits, misses, evictions, bytes int64) {
+	rc.mu.Lock()
+	bytes = rc.totalUsed
+	rc.mu.Unlock()
+	return atomic.LoadInt64(&rc.hits), atomic.LoadInt64(&rc.misses), atomic.LoadInt64(&rc.evictions), bytes
+}
+
+// BlockSize returns the fixed block size this cache aligns downloads to,
+// implementing BlockCache.
+func (rc *readCache) BlockSize() int {
+	return rc.blockSize
+}
+
+// GetBlock implements BlockCache.
+func (rc *readCache) GetBlock(fileID string, index int64) ([]byte, bool) {
+	return rc.getBlock(fileID, index)
+}
+
+// PutBlock implements BlockCache.
+func (rc *readCache) PutBlock(fileID string, index int64, data []byte) {
+	rc.putBlock(fileID, index, data)
+}
+
+// InvalidateFile implements BlockCache: it drops every cached block for
+// fileID. FileInfo/metadata entries are invalidated separately by
+// invalidateMeta, since those aren't part of the pluggable BlockCache
+// surface.
+func (rc *readCache) InvalidateFile(fileID string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if fc, ok := rc.files[fileID]; ok {
+		for _, be := range fc.blocks {
+			rc.removeBlock(be)
+		}
+	}
+}
+
+// getInfo returns the cached FileInfo for fileID, if present and unexpired.
+func (rc *readCache) getInfo(fileID string) (*FileInfo, bool) {
+	rc.infoMu.Lock()
+	defer rc.infoMu.Unlock()
+
+	entry, ok := rc.info[fileID]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.value.(*FileInfo), true
+}
+
+// putInfo caches info for fileID until CacheTTL elapses.
+func (rc *readCache) putInfo(fileID string, info *FileInfo) {
+	if rc.ttl <= 0 {
+		return
+	}
+	rc.infoMu.Lock()
+	defer rc.infoMu.Unlock()
+	rc.info[fileID] = cachedEntry{value: info, expires: time.Now().Add(rc.ttl)}
+}
+
+// getMeta returns the cached metadata for fileID, if present and unexpired.
+func (rc *readCache) getMeta(fileID string) (map[string]string, bool) {
+	rc.metaMu.Lock()
+	defer rc.metaMu.Unlock()
+
+	entry, ok := rc.meta[fileID]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.value.(map[string]string), true
+}
+
+// putMeta caches metadata for fileID until CacheTTL elapses.
+func (rc *readCache) putMeta(fileID string, metadata map[string]string) {
+	if rc.ttl <= 0 {
+		return
+	}
+	rc.metaMu.Lock()
+	defer rc.metaMu.Unlock()
+	rc.meta[fileID] = cachedEntry{value: metadata, expires: time.Now().Add(rc.ttl)}
+}
+
+// invalidateMeta drops the cached FileInfo and metadata entries for
+// fileID. Block invalidation is handled separately by the BlockCache
+// (readCache.InvalidateFile for the built-in cache), since a caller can
+// plug in a different BlockCache implementation independent of FileInfo/
+// metadata caching.
+func (rc *readCache) invalidateMeta(fileID string) {
+	rc.infoMu.Lock()
+	delete(rc.info, fileID)
+	rc.infoMu.Unlock()
+
+	rc.metaMu.Lock()
+	delete(rc.meta, fileID)
+	rc.metaMu.Unlock()
+}
+
+// InvalidateCache removes fileID from the read cache (cached blocks,
+// FileInfo, and metadata). It is a no-op when no cache is configured or
+// fileID was never cached.
+func (c *Client) InvalidateCache(fileID string) {
+	if c.blockCache != nil {
+		c.blockCache.InvalidateFile(fileID)
+	}
+	if c.cache != nil {
+		c.cache.invalidateMeta(fileID)
+	}
+}
+
+// cacheStatsProvider is implemented by readCache, the built-in BlockCache.
+// A custom ClientConfig.BlockCache isn't required to implement it; when it
+// doesn't, CacheStats reports all zeros.
+type cacheStatsProvider interface {
+	Stats() (hits, misses, evictions, bytes int64)
+}
+
+// CacheStats reports the block cache's cumulative hit/miss/eviction counts
+// and current byte usage. It reports all zeros when no BlockCache is
+// configured, or when a custom one doesn't implement cacheStatsProvider.
+func (c *Client) CacheStats() (hits, misses, evictions, bytes int64) {
+	if sp, ok := c.blockCache.(cacheStatsProvider); ok {
+		return sp.Stats()
+	}
+	return 0, 0, 0, 0
+}
+
+// blockFillLock returns a mutex shared by every caller filling the same
+// (fileID, index) block, so a cache miss only triggers one RPC regardless
+// of which BlockCache implementation is in use.
+func (c *Client) blockFillLock(fileID string, index int64) *sync.Mutex {
+	key := fmt.Sprintf("%s#%d", fileID, index)
+	actual, _ := c.blockFillMu.LoadOrStore(key, &sync.Mutex{})
+	return actual.(*sync.Mutex)
+}
+
+// downloadRangeCached serves a ranged download from the block cache,
+// filling any missing covering blocks via downloadFileWithRetry. length <= 0
+// means "to end of file", which requires a (possibly cached) FileInfo
+// lookup to learn the file size. Falls back to an uncached download when no
+// cache is configured.
+func (c *Client) downloadRangeCached(ctx context.Context, fileID string, offset, length int64) ([]byte, error) {
+	if c.blockCache == nil {
+		return c.downloadFileWithRetry(ctx, fileID, offset, length)
+	}
+
+	if length <= 0 {
+		info, err := c.getFileInfoCached(ctx, fileID)
+		if err != nil {
+			return nil, err
+		}
+		length = info.FileSize - offset
+		if length <= 0 {
+			return []byte{}, nil
+		}
+	}
+
+	blockSize := int64(c.blockCache.BlockSize())
+	startBlock := offset / blockSize
+	endBlock := (offset + length - 1) / blockSize
+
+	if err := c.fillBlockRange(ctx, fileID, startBlock, endBlock); err != nil {
+		return nil, err
+	}
+
+	result := make([]byte, 0, length)
+	for block := startBlock; block <= endBlock; block++ {
+		data, ok := c.blockCache.GetBlock(fileID, block)
+		if !ok {
+			// fillBlockRange guarantees every block in [startBlock, endBlock]
+			// is cached by the time it returns without error.
+			return nil, ErrInvalidResponse
+		}
+		blockStart := block * blockSize
+		lo := int64(0)
+		if offset > blockStart {
+			lo = offset - blockStart
+		}
+		hi := int64(len(data))
+		if blockEnd := blockStart + int64(len(data)); offset+length < blockEnd {
+			hi = offset + length - blockStart
+		}
+		if lo < hi {
+			result = append(result, data[lo:hi]...)
+		}
+	}
+	return result, nil
+}
+
+// fillBlockRange ensures every block in [startBlock, endBlock] is present in
+// the block cache, issuing one coalesced downloadFileWithRetry per
+// contiguous run of missing blocks rather than one request per block.
+func (c *Client) fillBlockRange(ctx context.Context, fileID string, startBlock, endBlock int64) error {
+	blockSize := int64(c.blockCache.BlockSize())
+
+	for block := startBlock; block <= endBlock; block++ {
+		if _, ok := c.blockCache.GetBlock(fileID, block); ok {
+			continue
+		}
+
+		runEnd := block
+		for runEnd < endBlock {
+			if _, ok := c.blockCache.GetBlock(fileID, runEnd+1); ok {
+				break
+			}
+			runEnd++
+		}
+
+		if err := c.fillBlockRun(ctx, fileID, block, runEnd, blockSize); err != nil {
+			return err
+		}
+		block = runEnd
+	}
+	return nil
+}
+
+// fillBlockRun downloads and caches every block in [first, last] (inclusive)
+// with a single range request. It serializes on the per-block lock from
+// blockFillLock, keyed by the run's first block, so concurrent
+// callers racing to fill an overlapping run only issue one RPC between
+// them; a caller that loses the race simply finds first already cached
+// after acquiring the lock and returns without fetching.
+func (c *Client) fillBlockRun(ctx context.Context, fileID string, first, last, blockSize int64) error {
+	lock := c.blockFillLock(fileID, first)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, ok := c.blockCache.GetBlock(fileID, first); ok {
+		return nil
+	}
+
+	length := (last - first + 1) * blockSize
+	data, err := c.downloadFileWithRetry(ctx, fileID, first*blockSize, length)
+	if err != nil {
+		return err
+	}
+
+	for block, off := first, int64(0); block <= last && off < int64(len(data)); block, off = block+1, off+blockSize {
+		end := off + blockSize
+		if end > int64(len(data)) {
+			end = int64(len(data))
+		}
+		c.blockCache.PutBlock(fileID, block, data[off:end])
+	}
+	return nil
+}
+
+// getFileInfoCached returns FileInfo for fileID, serving it from the TTL
+// cache when present. Falls back to an uncached lookup when no cache is
+// configured.
+func (c *Client) getFileInfoCached(ctx context.Context, fileID string) (*FileInfo, error) {
+	if c.cache == nil {
+		return c.getFileInfoWithRetry(ctx, fileID)
+	}
+	if info, ok := c.cache.getInfo(fileID); ok {
+		return info, nil
+	}
+	info, err := c.getFileInfoWithRetry(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.putInfo(fileID, info)
+	return info, nil
+}
+
+// getMetadataCached returns metadata for fileID, serving it from the TTL
+// cache when present. Falls back to an uncached lookup when no cache is
+// configured.
+func (c *Client) getMetadataCached(ctx context.Context, fileID string) (map[string]string, error) {
+	if c.cache == nil {
+		return c.getMetadataWithRetry(ctx, fileID)
+	}
+	if meta, ok := c.cache.getMeta(fileID); ok {
+		return meta, nil
+	}
+	meta, err := c.getMetadataWithRetry(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.putMeta(fileID, meta)
+	return meta, nil
+}
@@ -0,0 +1,240 @@
+// Package fdfs content-addressed chunked upload.
+// This file adds UploadChunked, a large-file upload path built on the
+// appender/modify commands (see appender.go) that records a per-block
+// SHA-256 ChunkedManifest as it goes: unlike UploadResumable (resumable.go),
+// which only tracks a byte offset for crash recovery, a ChunkedManifest lets
+// a caller independently verify every block's content later, or hand it to
+// ResumeUpload to repair exactly the blocks that didn't make it rather than
+// re-uploading the whole file.
+//
+// UploadChunked takes data as an io.ReaderAt rather than a plain io.Reader:
+// the post-upload verification pass (and ResumeUpload's repair pass) need
+// to re-read any block's original bytes on demand to fix a mismatch, which
+// a single-pass io.Reader can't support without buffering the whole file.
+// io.ReaderAt still lets the upload pass itself stream one block at a time
+// instead of holding the full file in memory.
+package fdfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// chunkedBlockSizeLadder is the fixed set of block sizes UploadChunked
+// chooses from, per the request's target of keeping a file's block count
+// near chunkedTargetBlockCount regardless of its size.
+var chunkedBlockSizeLadder = []int64{
+	128 << 10,
+	256 << 10,
+	512 << 10,
+	1 << 20,
+	2 << 20,
+	4 << 20,
+	8 << 20,
+	16 << 20,
+}
+
+// chunkedTargetBlockCount is the block count UploadChunked aims to stay
+// under when picking a block size from chunkedBlockSizeLadder.
+const chunkedTargetBlockCount = 2000
+
+// chunkedBlockSizeFor picks the smallest size in chunkedBlockSizeLadder that
+// keeps size's block count at or under chunkedTargetBlockCount, falling
+// back to the ladder's largest size for files too big for even that.
+func chunkedBlockSizeFor(size int64) int64 {
+	for _, blockSize := range chunkedBlockSizeLadder {
+		if (size+blockSize-1)/blockSize <= chunkedTargetBlockCount {
+			return blockSize
+		}
+	}
+	return chunkedBlockSizeLadder[len(chunkedBlockSizeLadder)-1]
+}
+
+// ChunkedManifest records how UploadChunked laid a file out in blocks, so a
+// caller can persist it and later re-verify the file's integrity or pass it
+// to ResumeUpload to repair a partial upload.
+type ChunkedManifest struct {
+	FileID      string
+	BlockSize   int64
+	BlockHashes [][32]byte
+	TotalSize   int64
+}
+
+// ChunkedUploadOptions configures UploadChunked.
+type ChunkedUploadOptions struct {
+	// Metadata is attached to the file on its initial upload (block 0), the
+	// same as UploadAppenderBuffer's metadata parameter.
+	Metadata map[string]string
+
+	// VerifyConcurrency is the number of worker goroutines used for the
+	// post-upload verification pass. Defaults to 8 when <= 0.
+	VerifyConcurrency int
+}
+
+// UploadChunked uploads size bytes read from data as an appender file, one
+// block at a time (block 0 via UploadAppenderBuffer, the rest via
+// AppendFile), recording each block's SHA-256 as it goes. Once every block
+// has been sent, it re-downloads and re-hashes each block to verify it
+// landed intact, repairing any mismatch in place via ModifyFile using a
+// bounded worker pool, and returns the resulting ChunkedManifest.
+func (c *Client) UploadChunked(ctx context.Context, data io.ReaderAt, size int64, extName string, opts ChunkedUploadOptions) (*ChunkedManifest, error) {
+	if err := c.checkClosed(); err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("fdfs: UploadChunked requires size > 0")
+	}
+
+	blockSize := chunkedBlockSizeFor(size)
+	blockCount := (size + blockSize - 1) / blockSize
+	hashes := make([][32]byte, blockCount)
+
+	var fileID string
+	for block := int64(0); block < blockCount; block++ {
+		offset := block * blockSize
+		n := blockSize
+		if remaining := size - offset; remaining < n {
+			n = remaining
+		}
+
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(io.NewSectionReader(data, offset, n), buf); err != nil {
+			return nil, fmt.Errorf("failed to read block %d at offset %d: %w", block, offset, err)
+		}
+		hashes[block] = sha256.Sum256(buf)
+
+		if block == 0 {
+			id, err := c.UploadAppenderBuffer(ctx, buf, extName, opts.Metadata)
+			if err != nil {
+				return nil, fmt.Errorf("failed to upload block 0: %w", err)
+			}
+			fileID = id
+		} else if err := c.AppendFile(ctx, fileID, buf); err != nil {
+			return nil, fmt.Errorf("failed to append block %d: %w", block, err)
+		}
+	}
+
+	manifest := &ChunkedManifest{
+		FileID:      fileID,
+		BlockSize:   blockSize,
+		BlockHashes: hashes,
+		TotalSize:   size,
+	}
+
+	if err := c.verifyAndRepairBlocks(ctx, manifest, data, opts.VerifyConcurrency); err != nil {
+		return manifest, err
+	}
+	return manifest, nil
+}
+
+// ResumeUpload repairs a ChunkedManifest's file against data, re-uploading
+// only the blocks whose server-side content doesn't already match the
+// manifest's recorded hash. Blocks entirely beyond the file's current
+// length on the server are appended in order via AppendFile; blocks within
+// the current length are re-verified and, on mismatch, rewritten via
+// ModifyFile. data must be the same content UploadChunked was originally
+// given, so re-read blocks hash identically to what's in the manifest.
+func (c *Client) ResumeUpload(ctx context.Context, manifest *ChunkedManifest, data io.ReaderAt) (*ChunkedManifest, error) {
+	if err := c.checkClosed(); err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("fdfs: ResumeUpload requires a non-nil manifest")
+	}
+
+	info, err := c.GetFileInfo(ctx, manifest.FileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", manifest.FileID, err)
+	}
+	committed := info.FileSize
+
+	for block, want := range manifest.BlockHashes {
+		offset := int64(block) * manifest.BlockSize
+		n := manifest.BlockSize
+		if remaining := manifest.TotalSize - offset; remaining < n {
+			n = remaining
+		}
+
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(io.NewSectionReader(data, offset, n), buf); err != nil {
+			return manifest, fmt.Errorf("failed to read block %d at offset %d: %w", block, offset, err)
+		}
+
+		if offset+n <= committed {
+			remote, err := c.DownloadFileRange(ctx, manifest.FileID, offset, n)
+			if err == nil && sha256.Sum256(remote) == want {
+				continue
+			}
+			if err := c.ModifyFile(ctx, manifest.FileID, offset, buf); err != nil {
+				return manifest, fmt.Errorf("failed to repair block %d: %w", block, err)
+			}
+		} else {
+			if err := c.AppendFile(ctx, manifest.FileID, buf); err != nil {
+				return manifest, fmt.Errorf("failed to append block %d: %w", block, err)
+			}
+			committed = offset + n
+		}
+	}
+
+	return manifest, nil
+}
+
+// verifyAndRepairBlocks re-downloads and re-hashes every block in manifest
+// across a bounded worker pool, repairing any that don't match via
+// ModifyFile. data supplies the original bytes for repair.
+func (c *Client) verifyAndRepairBlocks(ctx context.Context, manifest *ChunkedManifest, data io.ReaderAt, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = 8
+	}
+
+	indices := make(chan int, concurrency)
+	errs := make([]error, len(manifest.BlockHashes))
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for block := range indices {
+				errs[block] = c.verifyAndRepairBlock(ctx, manifest, data, block)
+			}
+		}()
+	}
+
+	for block := range manifest.BlockHashes {
+		indices <- block
+	}
+	close(indices)
+	wg.Wait()
+
+	for block, err := range errs {
+		if err != nil {
+			return fmt.Errorf("block %d: %w", block, err)
+		}
+	}
+	return nil
+}
+
+// verifyAndRepairBlock checks one block against manifest.BlockHashes,
+// rewriting it via ModifyFile when its server-side content doesn't match.
+func (c *Client) verifyAndRepairBlock(ctx context.Context, manifest *ChunkedManifest, data io.ReaderAt, block int) error {
+	offset := int64(block) * manifest.BlockSize
+	n := manifest.BlockSize
+	if remaining := manifest.TotalSize - offset; remaining < n {
+		n = remaining
+	}
+
+	remote, err := c.DownloadFileRange(ctx, manifest.FileID, offset, n)
+	if err == nil && sha256.Sum256(remote) == manifest.BlockHashes[block] {
+		return nil
+	}
+
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(io.NewSectionReader(data, offset, n), buf); err != nil {
+		return fmt.Errorf("failed to re-read original block for repair: %w", err)
+	}
+	return c.ModifyFile(ctx, manifest.FileID, offset, buf)
+}
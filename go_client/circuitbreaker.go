@@ -0,0 +1,251 @@
+// Package fdfs circuit breaker subsystem.
+// This file implements a per-endpoint (tracker or storage, keyed by
+// "ip:port") circuit breaker so a failing node is temporarily skipped
+// instead of being retried on every call.
+package fdfs
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a single endpoint's circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed means the endpoint is healthy and calls pass through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen means the endpoint recently failed past the configured
+	// threshold; calls are rejected with ErrCircuitOpen until OpenTimeout elapses.
+	CircuitOpen
+	// CircuitHalfOpen means OpenTimeout has elapsed and a limited number of
+	// trial calls are being admitted to decide whether to close again.
+	CircuitHalfOpen
+)
+
+// String implements fmt.Stringer for use in logs.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig configures the per-endpoint circuit breaker. A nil
+// *CircuitBreakerConfig on ClientConfig disables the breaker entirely.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the minimum number of consecutive failures
+	// required to trip the circuit, regardless of FailureRatio.
+	FailureThreshold int
+
+	// FailureRatio trips the circuit when failures/total >= FailureRatio
+	// once at least MinRequests calls have been observed. Both counts
+	// accumulate from the moment the circuit last closed; there is no
+	// rolling time window.
+	FailureRatio float64
+
+	// MinRequests is the minimum number of calls observed since the circuit
+	// last closed before FailureRatio is evaluated.
+	MinRequests int
+
+	// OpenTimeout is how long the circuit stays Open before moving to HalfOpen.
+	OpenTimeout time.Duration
+
+	// HalfOpenMaxProbes is how many trial calls are admitted while HalfOpen.
+	HalfOpenMaxProbes int
+}
+
+// EndpointStats is a snapshot of a single endpoint's circuit breaker counters.
+type EndpointStats struct {
+	Addr       string
+	State      CircuitState
+	Successes  int
+	Failures   int
+	Total      int
+	OpenedAt   time.Time
+	LastTripAt time.Time
+}
+
+// circuitRegistry tracks one endpointCircuit per "ip:port" address.
+type circuitRegistry struct {
+	cfg CircuitBreakerConfig
+
+	mu        sync.Mutex
+	endpoints map[string]*endpointCircuit
+}
+
+type endpointCircuit struct {
+	mu             sync.Mutex
+	state          CircuitState
+	successes      int
+	failures       int
+	consecutive    int // failures since the last success, for FailureThreshold
+	openedAt       time.Time
+	lastTripAt     time.Time
+	halfOpenProbes int
+}
+
+func newCircuitRegistry(cfg CircuitBreakerConfig) *circuitRegistry {
+	return &circuitRegistry{cfg: cfg, endpoints: make(map[string]*endpointCircuit)}
+}
+
+func (r *circuitRegistry) get(addr string) *endpointCircuit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ec, ok := r.endpoints[addr]
+	if !ok {
+		ec = &endpointCircuit{}
+		r.endpoints[addr] = ec
+	}
+	return ec
+}
+
+// allow reports whether a call to addr should proceed, transitioning
+// Open -> HalfOpen once OpenTimeout has elapsed.
+func (r *circuitRegistry) allow(addr string) bool {
+	ec := r.get(addr)
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	switch ec.state {
+	case CircuitClosed:
+		return true
+	case CircuitOpen:
+		if time.Since(ec.openedAt) >= r.cfg.OpenTimeout {
+			ec.state = CircuitHalfOpen
+			ec.halfOpenProbes = 1
+			return true
+		}
+		return false
+	case CircuitHalfOpen:
+		maxProbes := r.cfg.HalfOpenMaxProbes
+		if maxProbes <= 0 {
+			maxProbes = 1
+		}
+		if ec.halfOpenProbes < maxProbes {
+			ec.halfOpenProbes++
+			return true
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// recordSuccess counts a success and ends any run of consecutive failures;
+// a success while HalfOpen also closes the circuit and resets its counters.
+// It returns the state before and after the call so the caller
+// can report a transition to Observer.OnCircuitStateChange.
+func (r *circuitRegistry) recordSuccess(addr string) (from, to CircuitState) {
+	ec := r.get(addr)
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	from = ec.state
+	ec.successes++
+	ec.consecutive = 0
+	if ec.state == CircuitHalfOpen {
+		ec.state = CircuitClosed
+		ec.failures = 0
+		ec.successes = 0
+	}
+	return from, ec.state
+}
+
+// recordFailure increments failure counters and trips the circuit to Open
+// when FailureThreshold or FailureRatio (once MinRequests is reached) is
+// crossed. It returns the state before and after the call so the caller
+// can report a transition to Observer.OnCircuitStateChange.
+func (r *circuitRegistry) recordFailure(addr string) (from, to CircuitState) {
+	ec := r.get(addr)
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	from = ec.state
+	ec.failures++
+	ec.consecutive++
+
+	if ec.state == CircuitHalfOpen {
+		ec.state = CircuitOpen
+		ec.openedAt = time.Now()
+		ec.lastTripAt = ec.openedAt
+		return from, ec.state
+	}
+
+	total := ec.successes + ec.failures
+	ratioTripped := r.cfg.MinRequests > 0 && total >= r.cfg.MinRequests &&
+		r.cfg.FailureRatio > 0 && float64(ec.failures)/float64(total) >= r.cfg.FailureRatio
+
+	thresholdTripped := r.cfg.FailureThreshold > 0 && ec.consecutive >= r.cfg.FailureThreshold
+
+	if thresholdTripped || ratioTripped {
+		ec.state = CircuitOpen
+		ec.openedAt = time.Now()
+		ec.lastTripAt = ec.openedAt
+	}
+	return from, ec.state
+}
+
+func (r *circuitRegistry) stats() map[string]EndpointStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make(map[string]EndpointStats, len(r.endpoints))
+	for addr, ec := range r.endpoints {
+		ec.mu.Lock()
+		out[addr] = EndpointStats{
+			Addr:       addr,
+			State:      ec.state,
+			Successes:  ec.successes,
+			Failures:   ec.failures,
+			Total:      ec.successes + ec.failures,
+			OpenedAt:   ec.openedAt,
+			LastTripAt: ec.lastTripAt,
+		}
+		ec.mu.Unlock()
+	}
+	return out
+}
+
+// circuitAllow reports whether addr may currently be called. When no
+// CircuitBreakerConfig is configured, every address is always allowed.
+func (c *Client) circuitAllow(addr string) bool {
+	if c.circuits == nil {
+		return true
+	}
+	return c.circuits.allow(addr)
+}
+
+// circuitRecord reports the outcome of a call to addr to the circuit
+// breaker. It is a no-op when no CircuitBreakerConfig is configured.
+func (c *Client) circuitRecord(addr string, err error) {
+	if c.circuits == nil {
+		return
+	}
+	var from, to CircuitState
+	if err == nil {
+		from, to = c.circuits.recordSuccess(addr)
+	} else {
+		from, to = c.circuits.recordFailure(addr)
+	}
+	if from != to {
+		c.observer().OnCircuitStateChange(addr, from, to)
+	}
+}
+
+// EndpointStats returns per-address circuit breaker counters for every
+// tracker/storage endpoint observed so far, for wiring into Prometheus or
+// similar operator tooling. Returns an empty map when no CircuitBreakerConfig
+// is configured.
+func (c *Client) EndpointStats() map[string]EndpointStats {
+	if c.circuits == nil {
+		return map[string]EndpointStats{}
+	}
+	return c.circuits.stats()
+}
@@ -0,0 +1,85 @@
+package fdfs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitRegistryFailureThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       CircuitBreakerConfig
+		failures  int
+		wantState CircuitState
+	}{
+		{
+			name:      "below threshold stays closed",
+			cfg:       CircuitBreakerConfig{FailureThreshold: 3},
+			failures:  2,
+			wantState: CircuitClosed,
+		},
+		{
+			name:      "at threshold trips open",
+			cfg:       CircuitBreakerConfig{FailureThreshold: 3},
+			failures:  3,
+			wantState: CircuitOpen,
+		},
+		{
+			name:      "zero threshold never trips on its own",
+			cfg:       CircuitBreakerConfig{FailureThreshold: 0, FailureRatio: 0.5, MinRequests: 10},
+			failures:  1,
+			wantState: CircuitClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newCircuitRegistry(tt.cfg)
+			var state CircuitState
+			for i := 0; i < tt.failures; i++ {
+				_, state = r.recordFailure("addr")
+			}
+			assert.Equal(t, tt.wantState, state)
+		})
+	}
+}
+
+func TestCircuitRegistryFailureRatio(t *testing.T) {
+	r := newCircuitRegistry(CircuitBreakerConfig{FailureThreshold: 1000, FailureRatio: 0.5, MinRequests: 4})
+
+	r.recordSuccess("addr")
+	r.recordSuccess("addr")
+	_, state := r.recordFailure("addr")
+	assert.Equal(t, CircuitClosed, state, "below MinRequests, ratio shouldn't be evaluated yet")
+
+	_, state = r.recordFailure("addr")
+	assert.Equal(t, CircuitOpen, state, "4 calls, 2 failures meets the 0.5 ratio once MinRequests is reached")
+}
+
+func TestCircuitRegistryHalfOpenTransitions(t *testing.T) {
+	r := newCircuitRegistry(CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Millisecond, HalfOpenMaxProbes: 1})
+
+	_, state := r.recordFailure("addr")
+	assert.Equal(t, CircuitOpen, state)
+	assert.False(t, r.allow("addr"), "still within OpenTimeout")
+
+	time.Sleep(2 * time.Millisecond)
+	assert.True(t, r.allow("addr"), "OpenTimeout elapsed, should admit a HalfOpen probe")
+	assert.False(t, r.allow("addr"), "HalfOpenMaxProbes exhausted")
+
+	_, state = r.recordSuccess("addr")
+	assert.Equal(t, CircuitClosed, state, "a successful HalfOpen probe closes the circuit")
+}
+
+func TestCircuitRegistryHalfOpenFailureReopens(t *testing.T) {
+	r := newCircuitRegistry(CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Millisecond, HalfOpenMaxProbes: 1})
+
+	r.recordFailure("addr")
+	time.Sleep(2 * time.Millisecond)
+	r.allow("addr")
+
+	_, state := r.recordFailure("addr")
+	assert.Equal(t, CircuitOpen, state, "a failed HalfOpen probe reopens the circuit")
+}
@@ -19,8 +19,39 @@ type Client struct {
 	config      *ClientConfig
 	trackerPool *ConnectionPool
 	storagePool *ConnectionPool
-	mu          sync.RWMutex
-	closed      bool
+	circuits    *circuitRegistry
+	healthProbe *trackerHealthProbe
+	cache       *readCache
+	blockCache  BlockCache
+	blockFillMu sync.Map // "fileID#index" -> *sync.Mutex, serializes concurrent block cache fills
+	adaptive    *adaptiveConcurrencyController
+
+	// storageAdaptivePool resizes storagePool at runtime when
+	// ClientConfig.AdaptivePool is set. See adaptivepool.go.
+	storageAdaptivePool *adaptivePoolController
+
+	// byteSem bounds the total bytes held in pending upload/append/modify
+	// payload buffers across concurrent calls. See bytesemaphore.go and
+	// ClientConfig.MaxInFlightBytes.
+	byteSem *byteSemaphore
+
+	// uploadRate/downloadRate/uploadBytesRate/downloadBytesRate throttle
+	// UploadFile/UploadBuffer/DownloadFile/DownloadFileRange per
+	// ClientConfig.UploadQPS/DownloadQPS/UploadBytesPerSec/
+	// DownloadBytesPerSec. nil when the corresponding config field is
+	// unset. See ratelimit.go.
+	uploadRate        *RateLimiter
+	downloadRate      *RateLimiter
+	uploadBytesRate   *RateLimiter
+	downloadBytesRate *RateLimiter
+
+	// inFlight bounds the number of upload/download calls rate-limited by
+	// this Client running at once, per ClientConfig.MaxInFlight. nil when
+	// unset. See ratelimit.go.
+	inFlight chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
 }
 
 // ClientConfig holds the configuration for FastDFS client.
@@ -45,6 +76,220 @@ type ClientConfig struct {
 
 	// RetryCount is the number of retries for failed operations
 	RetryCount int
+
+	// RetentionLister enumerates candidate files for ApplyRetention/PlanRetention.
+	// It is optional and only required when retention cleanup is used.
+	RetentionLister RetentionLister
+
+	// Tracer, if set, wraps every tracker/storage RPC in a Span. See WithTracer.
+	Tracer Tracer
+
+	// RetryPolicy controls the backoff used between retry attempts on every
+	// RPC method. Defaults to the client's historical linear (1s, 2s, 3s, ...)
+	// backoff when nil. Use WithRetry to override it for a single call.
+	RetryPolicy RetryPolicy
+
+	// RetryableFunc classifies whether a failed attempt should be retried,
+	// replacing the client's historical per-operation "don't retry on X"
+	// checks (invalid input, a definitively missing file, caller
+	// cancellation). Defaults to defaultRetryableFunc when nil. Use
+	// WithRetryableFunc to override it for a single call, e.g. to stop
+	// retrying on ErrNoStorageServer instead of letting the next attempt
+	// re-query the tracker.
+	RetryableFunc RetryableFunc
+
+	// CircuitBreaker, if set, trips per-endpoint (tracker or storage) circuits
+	// after repeated failures so dead nodes are skipped instead of retried.
+	CircuitBreaker *CircuitBreakerConfig
+
+	// TrackerSelector chooses which configured tracker address to prefer for
+	// each new call and is fed the outcome of every tracker RPC. Defaults to
+	// always trying TrackerAddrs in order when nil. See
+	// RoundRobinTrackerSelector, RandomTrackerSelector, and
+	// WeightedTrackerSelector for ready-made implementations.
+	TrackerSelector TrackerSelector
+
+	// HealthCheckInterval, if positive, starts a background goroutine that
+	// issues a FdfsProtoCmdActiveTest probe to every tracker in TrackerAddrs
+	// on this interval and fires OnTrackerUp/OnTrackerDown on state changes.
+	// Leave zero to disable background probing.
+	HealthCheckInterval time.Duration
+
+	// OnTrackerUp, if set, is called from the background health probe when a
+	// tracker that was previously unreachable answers a probe successfully.
+	OnTrackerUp func(addr string)
+
+	// OnTrackerDown, if set, is called from the background health probe when
+	// a tracker that was previously reachable fails a probe.
+	OnTrackerDown func(addr string)
+
+	// Observer, if set, receives fine-grained lifecycle events (request
+	// start/end, retries, circuit breaker transitions, connection pool
+	// activity) for every RPC. See LogObserver for a ready-made
+	// implementation backed by log/slog.
+	Observer Observer
+
+	// CacheBytesPerFile is the maximum number of bytes of download blocks
+	// kept in memory per file. Zero (with CacheTotalBytes) disables the
+	// read cache entirely.
+	CacheBytesPerFile int64
+
+	// CacheTotalBytes is the maximum number of bytes of download blocks
+	// kept in memory across all files combined. Zero disables the read
+	// cache entirely.
+	CacheTotalBytes int64
+
+	// CacheBlockSize is the size of each cached download block. Defaults
+	// to 1 MiB when zero.
+	CacheBlockSize int
+
+	// CacheTTL is how long a cached FileInfo or metadata result remains
+	// valid before the next GetFileInfo/GetMetadata re-fetches it. Zero
+	// disables FileInfo/metadata caching even when the block cache is
+	// enabled.
+	CacheTTL time.Duration
+
+	// Metrics, if set, receives per-command counters, latency/byte
+	// histograms, and per-endpoint error counts for every RPC. Takes
+	// precedence over StatsdAddr. See metrics/prometheus for a ready-made
+	// implementation registrable with prometheus.Register.
+	Metrics Metrics
+
+	// StatsdAddr, if set and Metrics is nil, is a "host:port" UDP address
+	// NewClient dials to build a built-in StatsD-backed Metrics sink.
+	StatsdAddr string
+
+	// DNSCacheTTL is how long a successful DNS lookup of a tracker or
+	// storage hostname is cached before being refreshed. Defaults to 30s
+	// when zero. Refreshing happens asynchronously once an entry expires,
+	// so an in-flight lookup never blocks a connection attempt on the
+	// resolver. Has no effect on addresses already given as IP literals.
+	DNSCacheTTL time.Duration
+
+	// DisableDNSCache turns off DNS caching entirely, so every new
+	// connection resolves the hostname fresh. Useful in environments that
+	// rely on very short-TTL DNS for failover (e.g. an external load
+	// balancer record) where a cached stale address would be wrong.
+	DisableDNSCache bool
+
+	// AdaptiveConcurrency, if set, lets a Pool created from this Client
+	// (with Pool.Adaptive set) discover its worker concurrency at runtime
+	// via AIMD probing instead of using a fixed count. See
+	// AdaptiveConcurrencyConfig and Client.Concurrency.
+	AdaptiveConcurrency *AdaptiveConcurrencyConfig
+
+	// AdaptivePool, if set, lets the storage connection pool resize itself
+	// at runtime between MinConns and MaxConns based on observed P95
+	// latency, instead of running at a fixed MaxConns chosen by hand. See
+	// AdaptivePoolConfig.
+	AdaptivePool *AdaptivePoolConfig
+
+	// DigestIndex, if set, lets UploadIfMissing/FindMissingBlobs/
+	// Pool.BatchUploadIfMissing skip re-uploading content whose SHA-256
+	// digest is already recorded. See NewMemoryDigestIndex and
+	// NewFileDigestIndex for ready-made implementations. Unset disables
+	// dedup entirely.
+	DigestIndex DigestIndex
+
+	// BufferPool, if set, supplies the chunk buffers UploadStream/
+	// DownloadStream copy through instead of allocating a fresh one per
+	// call. See go_client/bufpool for a size-bucketed sync.Pool
+	// implementation. Unset allocates plainly, same as before this field
+	// existed.
+	BufferPool BufferPool
+
+	// BlockCache, if set, overrides the block-level half of the read
+	// cache with a caller-supplied implementation (e.g. backed by an
+	// external LRU library) instead of the built-in in-memory one sized
+	// by CacheBytesPerFile/CacheTotalBytes/CacheBlockSize. FileInfo and
+	// metadata caching (CacheTTL) are unaffected by this override. Unset
+	// uses the built-in block cache whenever CacheBytesPerFile and
+	// CacheTotalBytes enable it.
+	BlockCache BlockCache
+
+	// MinConns pre-warms each server's connection pool (tracker and
+	// storage alike) with this many connections at startup, so the first
+	// requests after NewClient don't each pay a dial round-trip. Capped at
+	// MaxConns; zero disables pre-warming. See ConnectionPoolOptions.MinConns.
+	MinConns int
+
+	// MaxConnsPerSecond, if positive, caps how many new connections may be
+	// dialed per second for a single tracker or storage address, guarding
+	// against a thundering herd of callers hammering a server right after
+	// a failover. Get returns ErrPoolRateLimited once the bucket for that
+	// address is empty. Zero disables the limit. See
+	// ConnectionPoolOptions.MaxConnsPerSecond.
+	MaxConnsPerSecond float64
+
+	// MaxInFlightBytes caps the total bytes held across concurrent
+	// uploadSlaveFileInternal/appendFileInternal/modifyFileInternal payload
+	// buffers, so client RSS stays bounded regardless of caller
+	// concurrency. Defaults to 128 MiB when zero. A single call requesting
+	// more than this is granted the whole budget once it's free, rather
+	// than blocking forever. See bytesemaphore.go.
+	MaxInFlightBytes int64
+
+	// Compressor, if set together with EnableCompression, lets
+	// UploadFile/UploadBuffer and slave file uploads compress payloads at or
+	// above CompressionMinSize before sending them, with DownloadFile
+	// reversing it transparently. See compress.go for why appender uploads
+	// and AppendFile/ModifyFile are never compressed. See go_client/compress/lz4
+	// for a ready-made implementation.
+	Compressor Compressor
+
+	// EnableCompression gates Compressor: FastDFS storage daemons have no
+	// notion of compressed payloads, so this is kept as an explicit opt-in
+	// separate from merely setting Compressor, in case a caller wants to
+	// swap codecs without immediately turning compression on everywhere.
+	EnableCompression bool
+
+	// CompressionMinSize is the minimum payload size, in bytes, a
+	// compressible upload must reach before Compressor is even attempted. Zero
+	// attempts compression on every payload.
+	CompressionMinSize int64
+
+	// CompressionMinRatio is the minimum fraction a payload must shrink by
+	// for the compressed form to be kept; smaller gains fall back to
+	// sending the payload uncompressed. Defaults to 0.10 (10%) when zero.
+	CompressionMinRatio float64
+
+	// UploadQPS, if positive, caps how many UploadFile/UploadBuffer calls
+	// may start per second, independent of byte volume. Zero disables it.
+	// See ratelimit.go.
+	UploadQPS float64
+
+	// DownloadQPS is UploadQPS's counterpart for DownloadFile/
+	// DownloadFileRange.
+	DownloadQPS float64
+
+	// UploadBytesPerSec, if positive, caps the sustained byte throughput of
+	// UploadBuffer, independent of call count. An UploadFile call only
+	// applies the QPS limit, not this one, since its size isn't known
+	// before the upload starts. A single call larger than the limiter's
+	// burst (one second's worth of bytes, i.e. UploadBytesPerSec) is
+	// granted the whole bucket once it's full rather than blocking
+	// forever. Zero disables it.
+	UploadBytesPerSec float64
+
+	// DownloadBytesPerSec is UploadBytesPerSec's counterpart for
+	// DownloadFile/DownloadFileRange. A DownloadFile call (unbounded
+	// length) only applies the QPS limit, not this one, since its size
+	// isn't known before the download completes.
+	DownloadBytesPerSec float64
+
+	// MaxInFlight, if positive, caps how many UploadFile/UploadBuffer/
+	// DownloadFile/DownloadFileRange calls may be waiting on or running
+	// under the above rate limits at once. Zero disables it.
+	MaxInFlight int
+}
+
+// WithTracer returns a ClientConfig identical to config but with Tracer set,
+// for callers who prefer assembling config via options rather than struct
+// literals (e.g. when the config is otherwise built by another package).
+func WithTracer(config *ClientConfig, tracer Tracer) *ClientConfig {
+	cfg := *config
+	cfg.Tracer = tracer
+	return &cfg
 }
 
 // NewClient creates a new FastDFS client with the given configuration.
@@ -69,27 +314,90 @@ func NewClient(config *ClientConfig) (*Client, error) {
 	if config.RetryCount == 0 {
 		config.RetryCount = 3
 	}
+	if config.Metrics == nil && config.StatsdAddr != "" {
+		statsd, err := NewStatsDMetrics(config.StatsdAddr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create statsd metrics sink: %w", err)
+		}
+		config.Metrics = statsd
+	}
+
+	globalDNSCache.configure(config.DNSCacheTTL, config.DisableDNSCache)
 
 	client := &Client{
-		config: config,
+		config:  config,
+		cache:   newReadCache(config),
+		byteSem: newByteSemaphore(config.MaxInFlightBytes),
+	}
+	if config.BlockCache != nil {
+		client.blockCache = config.BlockCache
+	} else if client.cache != nil {
+		client.blockCache = client.cache
+	}
+	if config.UploadQPS > 0 {
+		client.uploadRate = NewRateLimiter(config.UploadQPS, int(config.UploadQPS))
+	}
+	if config.DownloadQPS > 0 {
+		client.downloadRate = NewRateLimiter(config.DownloadQPS, int(config.DownloadQPS))
+	}
+	if config.UploadBytesPerSec > 0 {
+		client.uploadBytesRate = NewRateLimiter(config.UploadBytesPerSec, int(config.UploadBytesPerSec))
+	}
+	if config.DownloadBytesPerSec > 0 {
+		client.downloadBytesRate = NewRateLimiter(config.DownloadBytesPerSec, int(config.DownloadBytesPerSec))
+	}
+	if config.MaxInFlight > 0 {
+		client.inFlight = make(chan struct{}, config.MaxInFlight)
+	}
+	if config.CircuitBreaker != nil {
+		client.circuits = newCircuitRegistry(*config.CircuitBreaker)
+	}
+	if config.AdaptiveConcurrency != nil {
+		client.adaptive = newAdaptiveConcurrencyController(*config.AdaptiveConcurrency)
 	}
 
 	// Initialize tracker connection pool
-	trackerPool, err := NewConnectionPool(config.TrackerAddrs, config.MaxConns,
-		config.ConnectTimeout, config.IdleTimeout)
+	trackerPool, err := NewConnectionPoolWithOptions(config.TrackerAddrs, ConnectionPoolOptions{
+		MaxConns:          config.MaxConns,
+		ConnectTimeout:    config.ConnectTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MinConns:          config.MinConns,
+		MaxConnsPerSecond: config.MaxConnsPerSecond,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create tracker pool: %w", err)
 	}
 	client.trackerPool = trackerPool
 
 	// Initialize storage connection pool
-	storagePool, err := NewConnectionPool([]string{}, config.MaxConns,
-		config.ConnectTimeout, config.IdleTimeout)
+	storagePool, err := NewConnectionPoolWithOptions([]string{}, ConnectionPoolOptions{
+		MaxConns:          config.MaxConns,
+		ConnectTimeout:    config.ConnectTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MinConns:          config.MinConns,
+		MaxConnsPerSecond: config.MaxConnsPerSecond,
+	})
 	if err != nil {
 		trackerPool.Close()
 		return nil, fmt.Errorf("failed to create storage pool: %w", err)
 	}
 	client.storagePool = storagePool
+	if config.AdaptivePool != nil {
+		client.storageAdaptivePool = newAdaptivePoolController(config.AdaptivePool, storagePool, "storage")
+	}
+
+	if config.Observer != nil {
+		trackerPool.SetEventHandler(config.Observer.OnConnPoolEvent)
+		storagePool.SetEventHandler(config.Observer.OnConnPoolEvent)
+	}
+	if config.Metrics != nil {
+		trackerPool.SetMetrics("tracker", config.Metrics)
+		storagePool.SetMetrics("storage", config.Metrics)
+	}
+
+	if config.HealthCheckInterval > 0 {
+		client.healthProbe = client.startTrackerHealthProbe()
+	}
 
 	return client, nil
 }
@@ -107,7 +415,20 @@ func (c *Client) UploadFile(ctx context.Context, localFilename string, metadata
 		return "", err
 	}
 
-	return c.uploadFileWithRetry(ctx, localFilename, metadata, false)
+	release, err := c.acquireUpload(ctx, 0)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	ctx, span := c.startSpan(ctx, "upload_file")
+	start := time.Now()
+	c.observerFor(ctx).OnRequestStart(ctx, "upload_file", map[string]interface{}{"local_filename": localFilename})
+	fileID, err := c.uploadFileWithRetry(ctx, localFilename, metadata, false)
+	c.observerFor(ctx).OnRequestEnd(ctx, "upload_file", err, time.Since(start))
+	span.SetTag("fdfs.file_id", fileID)
+	finishSpan(span, err)
+	return fileID, err
 }
 
 // UploadBuffer uploads data from a byte buffer to FastDFS.
@@ -124,7 +445,21 @@ func (c *Client) UploadBuffer(ctx context.Context, data []byte, fileExtName stri
 		return "", err
 	}
 
-	return c.uploadBufferWithRetry(ctx, data, fileExtName, metadata, false)
+	release, err := c.acquireUpload(ctx, int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	ctx, span := c.startSpan(ctx, "upload_buffer")
+	span.SetTag("fdfs.bytes", len(data))
+	start := time.Now()
+	c.observerFor(ctx).OnRequestStart(ctx, "upload_buffer", map[string]interface{}{"bytes": len(data)})
+	fileID, err := c.uploadBufferWithRetry(ctx, data, fileExtName, metadata, false)
+	c.observerFor(ctx).OnRequestEnd(ctx, "upload_buffer", err, time.Since(start))
+	span.SetTag("fdfs.file_id", fileID)
+	finishSpan(span, err)
+	return fileID, err
 }
 
 // UploadAppenderFile uploads an appender file that can be modified later.
@@ -171,7 +506,26 @@ func (c *Client) DownloadFile(ctx context.Context, fileID string) ([]byte, error
 		return nil, err
 	}
 
-	return c.downloadFileWithRetry(ctx, fileID, 0, 0)
+	release, err := c.acquireDownload(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	ctx, span := c.startSpan(ctx, "download_file")
+	span.SetTag("fdfs.file_id", fileID)
+	start := time.Now()
+	c.observerFor(ctx).OnRequestStart(ctx, "download_file", map[string]interface{}{"file_id": fileID})
+	data, err := c.downloadRangeCached(ctx, fileID, 0, 0)
+	if err == nil && c.config.Compressor != nil {
+		if metadata, merr := c.getMetadataCached(ctx, fileID); merr == nil {
+			data = c.decompressIfNeeded(data, metadata)
+		}
+	}
+	c.observerFor(ctx).OnRequestEnd(ctx, "download_file", err, time.Since(start))
+	span.SetTag("fdfs.bytes", len(data))
+	finishSpan(span, err)
+	return data, err
 }
 
 // DownloadFileRange downloads a specific range of bytes from a file.
@@ -186,7 +540,13 @@ func (c *Client) DownloadFileRange(ctx context.Context, fileID string, offset, l
 		return nil, err
 	}
 
-	return c.downloadFileWithRetry(ctx, fileID, offset, length)
+	release, err := c.acquireDownload(ctx, length)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return c.downloadRangeCached(ctx, fileID, offset, length)
 }
 
 // DownloadToFile downloads a file and saves it to the local filesystem.
@@ -204,7 +564,17 @@ func (c *Client) DeleteFile(ctx context.Context, fileID string) error {
 		return err
 	}
 
-	return c.deleteFileWithRetry(ctx, fileID)
+	ctx, span := c.startSpan(ctx, "delete_file")
+	span.SetTag("fdfs.file_id", fileID)
+	start := time.Now()
+	c.observerFor(ctx).OnRequestStart(ctx, "delete_file", map[string]interface{}{"file_id": fileID})
+	err := c.deleteFileWithRetry(ctx, fileID)
+	if err == nil {
+		c.InvalidateCache(fileID)
+	}
+	c.observerFor(ctx).OnRequestEnd(ctx, "delete_file", err, time.Since(start))
+	finishSpan(span, err)
+	return err
 }
 
 // AppendFile appends data to an appender file.
@@ -213,7 +583,11 @@ func (c *Client) AppendFile(ctx context.Context, fileID string, data []byte) err
 		return err
 	}
 
-	return c.appendFileWithRetry(ctx, fileID, data)
+	err := c.appendFileWithRetry(ctx, fileID, data)
+	if err == nil {
+		c.InvalidateCache(fileID)
+	}
+	return err
 }
 
 // ModifyFile modifies content of an appender file at specified offset.
@@ -222,7 +596,11 @@ func (c *Client) ModifyFile(ctx context.Context, fileID string, offset int64, da
 		return err
 	}
 
-	return c.modifyFileWithRetry(ctx, fileID, offset, data)
+	err := c.modifyFileWithRetry(ctx, fileID, offset, data)
+	if err == nil {
+		c.InvalidateCache(fileID)
+	}
+	return err
 }
 
 // TruncateFile truncates an appender file to specified size.
@@ -231,7 +609,11 @@ func (c *Client) TruncateFile(ctx context.Context, fileID string, size int64) er
 		return err
 	}
 
-	return c.truncateFileWithRetry(ctx, fileID, size)
+	err := c.truncateFileWithRetry(ctx, fileID, size)
+	if err == nil {
+		c.InvalidateCache(fileID)
+	}
+	return err
 }
 
 // SetMetadata sets metadata for a file.
@@ -246,7 +628,17 @@ func (c *Client) SetMetadata(ctx context.Context, fileID string, metadata map[st
 		return err
 	}
 
-	return c.setMetadataWithRetry(ctx, fileID, metadata, flag)
+	ctx, span := c.startSpan(ctx, "set_metadata")
+	span.SetTag("fdfs.file_id", fileID)
+	start := time.Now()
+	c.observerFor(ctx).OnRequestStart(ctx, "set_metadata", map[string]interface{}{"file_id": fileID})
+	err := c.setMetadataWithRetry(ctx, fileID, metadata, flag)
+	if err == nil {
+		c.InvalidateCache(fileID)
+	}
+	c.observerFor(ctx).OnRequestEnd(ctx, "set_metadata", err, time.Since(start))
+	finishSpan(span, err)
+	return err
 }
 
 // GetMetadata retrieves metadata for a file.
@@ -255,7 +647,14 @@ func (c *Client) GetMetadata(ctx context.Context, fileID string) (map[string]str
 		return nil, err
 	}
 
-	return c.getMetadataWithRetry(ctx, fileID)
+	ctx, span := c.startSpan(ctx, "get_metadata")
+	span.SetTag("fdfs.file_id", fileID)
+	start := time.Now()
+	c.observerFor(ctx).OnRequestStart(ctx, "get_metadata", map[string]interface{}{"file_id": fileID})
+	meta, err := c.getMetadataCached(ctx, fileID)
+	c.observerFor(ctx).OnRequestEnd(ctx, "get_metadata", err, time.Since(start))
+	finishSpan(span, err)
+	return meta, err
 }
 
 // GetFileInfo retrieves file information including size, create time, and CRC32.
@@ -264,7 +663,14 @@ func (c *Client) GetFileInfo(ctx context.Context, fileID string) (*FileInfo, err
 		return nil, err
 	}
 
-	return c.getFileInfoWithRetry(ctx, fileID)
+	ctx, span := c.startSpan(ctx, "get_file_info")
+	span.SetTag("fdfs.file_id", fileID)
+	start := time.Now()
+	c.observerFor(ctx).OnRequestStart(ctx, "get_file_info", map[string]interface{}{"file_id": fileID})
+	info, err := c.getFileInfoCached(ctx, fileID)
+	c.observerFor(ctx).OnRequestEnd(ctx, "get_file_info", err, time.Since(start))
+	finishSpan(span, err)
+	return info, err
 }
 
 // FileExists checks if a file exists on the storage server.
@@ -283,6 +689,35 @@ func (c *Client) FileExists(ctx context.Context, fileID string) (bool, error) {
 	return true, nil
 }
 
+// PurgeDNSCache discards every cached DNS lookup, forcing the next
+// connection to any tracker or storage hostname to resolve fresh.
+func (c *Client) PurgeDNSCache() {
+	globalDNSCache.purge()
+}
+
+// Concurrency returns the worker concurrency AdaptiveConcurrency has
+// converged on for batch operations run through a Pool with Adaptive set,
+// for observability (dashboards, logs). Returns ClientConfig.MaxConns when
+// AdaptiveConcurrency is unset, since that's the concurrency a connection
+// pool of this Client's connections can actually sustain.
+func (c *Client) Concurrency() int {
+	if c.adaptive != nil {
+		return c.adaptive.concurrency()
+	}
+	return c.config.MaxConns
+}
+
+// GroupLatencies returns the current per-storage-group latency EWMA
+// observed by AdaptiveConcurrency-enabled Pool batch calls, keyed by group
+// name. Empty when AdaptiveConcurrency is unset or no batch call has
+// recorded an op for any group yet.
+func (c *Client) GroupLatencies() map[string]time.Duration {
+	if c.adaptive == nil {
+		return nil
+	}
+	return c.adaptive.GroupLatencies()
+}
+
 // Close closes the client and releases all resources.
 func (c *Client) Close() error {
 	c.mu.Lock()
@@ -294,6 +729,8 @@ func (c *Client) Close() error {
 
 	c.closed = true
 
+	c.healthProbe.Close()
+
 	var errs []error
 	if c.trackerPool != nil {
 		if err := c.trackerPool.Close(); err != nil {
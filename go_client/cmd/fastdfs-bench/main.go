@@ -0,0 +1,240 @@
+// Command fastdfs-bench runs a mixed write/read/delete benchmark workload
+// against a FastDFS cluster, modeled after SeaweedFS's "weed benchmark".
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	fdfs "github.com/happyfish100/fastdfs/go_client"
+	"github.com/happyfish100/fastdfs/go_client/bench"
+)
+
+// writeMemProfile writes a heap profile to path, matching the
+// runtime/pprof.WriteHeapProfile recipe the Go documentation recommends
+// (a GC first, so the profile reflects live objects rather than garbage
+// not yet collected).
+func writeMemProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	runtime.GC()
+	return pprof.WriteHeapProfile(f)
+}
+
+// writeReportsJSON writes reports to path as indented JSON, for later
+// comparison with a subsequent run via the compare subcommand.
+func writeReportsJSON(path string, reports []bench.Report) error {
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		os.Exit(runCompare(os.Args[2:]))
+	}
+
+	trackerAddrs := flag.String("tracker", "", "comma-separated tracker addresses, e.g. 192.168.1.100:22122")
+	concurrency := flag.Int("c", 16, "number of concurrent worker goroutines")
+	numOps := flag.Int("n", 10000, "number of files to write")
+	size := flag.Int("size", 1024, "payload size in bytes for the write phase")
+	write := flag.Bool("write", false, "run the write phase")
+	read := flag.Bool("read", false, "run the read phase")
+	parallelRead := flag.Bool("parallelRead", false, "run a second read phase using DownloadRangesParallel instead of DownloadFile, for comparison against -read on the same files")
+	parallelChunkSize := flag.Int("parallelChunkSize", 0, "chunk size in bytes for -parallelRead's DownloadRangesParallel calls; defaults to DownloadRangesParallel's own default when zero")
+	sequentialRead := flag.Bool("sequentialRead", false, "read file IDs in write order instead of shuffled")
+	deletePhase := flag.Bool("delete", false, "run the delete phase")
+	deletePercentage := flag.Int("deletePercentage", 0, "percentage (0-100) of written files deleted again in-flight during the write phase")
+	idList := flag.String("idlist", "", "path to a file ID list, written by the write phase and read by the read/delete phases")
+	sizePadding := flag.Bool("sizePadding", false, "add 0-63 random extra bytes to -size for each write, instead of uniform-size payloads")
+	output := flag.String("output", "", "write results as JSON to this path, in addition to printing them, for later use with the compare subcommand")
+	cpuProfile := flag.String("cpuprofile", "", "write a runtime/pprof CPU profile to this path")
+	memProfile := flag.String("memprofile", "", "write a runtime/pprof heap profile to this path after the run")
+	maxCPU := flag.Int("maxCpu", 0, "GOMAXPROCS; defaults to the number of logical CPUs when zero")
+	flag.Parse()
+
+	if *trackerAddrs == "" {
+		log.Fatal("usage: fastdfs-bench -tracker=host:port[,host:port] [-write] [-read] [-delete] -c=64 -n=100000 -size=1024")
+	}
+	if !*write && !*read && !*parallelRead && !*deletePhase {
+		log.Fatal("at least one of -write, -read, -parallelRead, -delete must be set")
+	}
+
+	if *maxCPU > 0 {
+		runtime.GOMAXPROCS(*maxCPU)
+	}
+
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			log.Fatalf("failed to create cpu profile: %v", err)
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			log.Fatalf("failed to start cpu profile: %v", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	client, err := fdfs.NewClient(&fdfs.ClientConfig{
+		TrackerAddrs:   strings.Split(*trackerAddrs, ","),
+		MaxConns:       *concurrency,
+		ConnectTimeout: 5 * time.Second,
+		NetworkTimeout: 30 * time.Second,
+	})
+	if err != nil {
+		log.Fatalf("failed to create fdfs client: %v", err)
+	}
+	defer client.Close()
+
+	runner := bench.NewRunner(bench.Config{
+		Client:           client,
+		Concurrency:      *concurrency,
+		NumOps:           *numOps,
+		Size:             *size,
+		Write:            *write,
+		Read:             *read,
+		ParallelRead:     *parallelRead,
+		ParallelOptions:  fdfs.ParallelOptions{ChunkSize: *parallelChunkSize},
+		Delete:           *deletePhase,
+		SequentialRead:   *sequentialRead,
+		DeletePercentage: *deletePercentage,
+		IDListPath:       *idList,
+		SizePadding:      *sizePadding,
+	})
+
+	reports, err := runner.Run(context.Background())
+	for _, r := range reports {
+		fmt.Println(r.String())
+	}
+
+	if *output != "" {
+		if writeErr := writeReportsJSON(*output, reports); writeErr != nil {
+			log.Fatalf("failed to write results to %s: %v", *output, writeErr)
+		}
+	}
+
+	if *memProfile != "" {
+		if err := writeMemProfile(*memProfile); err != nil {
+			log.Fatalf("failed to write mem profile: %v", err)
+		}
+	}
+
+	if err != nil {
+		log.Fatalf("benchmark failed: %v", err)
+	}
+}
+
+// readReportsJSON reads a []bench.Report written by writeReportsJSON.
+func readReportsJSON(path string) ([]bench.Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var reports []bench.Report
+	if err := json.Unmarshal(data, &reports); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return reports, nil
+}
+
+// pctRegression returns how much worse current is than baseline as a
+// percentage, for metrics where lower is better (e.g. latency): positive
+// means current regressed, negative means it improved. higherIsBetter
+// flips the sign for metrics like throughput where a drop is the
+// regression.
+func pctRegression(baseline, current float64, higherIsBetter bool) float64 {
+	if baseline == 0 {
+		return 0
+	}
+	delta := (current - baseline) / baseline * 100
+	if higherIsBetter {
+		delta = -delta
+	}
+	return delta
+}
+
+// runCompare implements the "compare" subcommand: fastdfs-bench compare
+// baseline.json current.json [-fail-on-regression=10], diffing two result
+// files written by -output and exiting non-zero if either's OpsPerSec drops
+// or P99 latency rises by more than the threshold, for CI gating (mirrors
+// the baselinePerf/curPerf workflow from gRPC's benchmain).
+func runCompare(args []string) int {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	failOnRegression := fs.String("fail-on-regression", "10%", "maximum allowed regression in OpsPerSec or P99 latency, e.g. 10 or 10%, before exiting non-zero")
+	fs.Parse(args)
+
+	positional := fs.Args()
+	if len(positional) > 2 {
+		// Flags may also follow the two result files.
+		fs.Parse(positional[2:])
+		positional = append(positional[:2:2], fs.Args()...)
+	}
+	if len(positional) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: fastdfs-bench compare <baseline.json> <current.json> [-fail-on-regression=10%]")
+		return 2
+	}
+
+	threshold, err := strconv.ParseFloat(strings.TrimSuffix(*failOnRegression, "%"), 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -fail-on-regression %q: %v\n", *failOnRegression, err)
+		return 2
+	}
+
+	baseline, err := readReportsJSON(positional[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read baseline: %v\n", err)
+		return 2
+	}
+	current, err := readReportsJSON(positional[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read current: %v\n", err)
+		return 2
+	}
+
+	currentByWorkload := make(map[bench.Workload]bench.Report, len(current))
+	for _, r := range current {
+		currentByWorkload[r.Workload] = r
+	}
+
+	regressed := false
+	for _, base := range baseline {
+		cur, ok := currentByWorkload[base.Workload]
+		if !ok {
+			fmt.Printf("%-8s  no matching workload in current results, skipping\n", base.Workload)
+			continue
+		}
+
+		opsRegression := pctRegression(base.OpsPerSec, cur.OpsPerSec, true)
+		p99Regression := pctRegression(float64(base.P99), float64(cur.P99), false)
+
+		marker := ""
+		if opsRegression > threshold || p99Regression > threshold {
+			marker = "  REGRESSION"
+			regressed = true
+		}
+
+		fmt.Printf("%-8s  ops/sec: %10.2f -> %10.2f (%+.1f%%)  p99: %10v -> %10v (%+.1f%%)%s\n",
+			base.Workload, base.OpsPerSec, cur.OpsPerSec, opsRegression, base.P99, cur.P99, p99Regression, marker)
+	}
+
+	if regressed {
+		fmt.Printf("\nregression exceeds -fail-on-regression=%s\n", *failOnRegression)
+		return 1
+	}
+	return 0
+}
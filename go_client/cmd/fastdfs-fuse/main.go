@@ -0,0 +1,66 @@
+// Command fastdfs-fuse mounts a FastDFS group as a read/write POSIX
+// filesystem at a given mountpoint.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	bazilfuse "bazil.org/fuse"
+	bazilfs "bazil.org/fuse/fs"
+
+	fdfs "github.com/happyfish100/fastdfs/go_client"
+	fdfsfuse "github.com/happyfish100/fastdfs/go_client/fuse"
+)
+
+func main() {
+	trackerAddrs := flag.String("trackers", "", "comma-separated tracker addresses, e.g. 192.168.1.100:22122")
+	group := flag.String("group", "", "FastDFS group name to expose")
+	mountpoint := flag.String("mountpoint", "", "local directory to mount the filesystem at")
+	readOnly := flag.Bool("readonly", false, "reject writes, creates, removes, and truncates with EROFS")
+	cacheBytesPerFile := flag.Int64("cacheBytesPerFile", 0, "maximum bytes of download blocks cached per file; 0 disables the block cache")
+	cacheTotalBytes := flag.Int64("cacheTotalBytes", 0, "maximum bytes of download blocks cached across all files; 0 disables the block cache")
+	cacheBlockSize := flag.Int("cacheBlockSize", 0, "size in bytes of each cached download block; defaults to the client's own default (1 MiB) when zero")
+	attrCacheTTL := flag.Duration("attrCacheTTL", 0, "how long Getattr results stay cached before re-fetching with GetFileInfo; 0 disables attribute caching")
+	flag.Parse()
+
+	if *trackerAddrs == "" || *group == "" || *mountpoint == "" {
+		log.Fatal("usage: fastdfs-fuse -trackers=host:port[,host:port] -group=group1 -mountpoint=/mnt/fastdfs")
+	}
+
+	client, err := fdfs.NewClient(&fdfs.ClientConfig{
+		TrackerAddrs:      strings.Split(*trackerAddrs, ","),
+		ConnectTimeout:    5 * time.Second,
+		NetworkTimeout:    30 * time.Second,
+		CacheBytesPerFile: *cacheBytesPerFile,
+		CacheTotalBytes:   *cacheTotalBytes,
+		CacheBlockSize:    *cacheBlockSize,
+		CacheTTL:          *attrCacheTTL,
+	})
+	if err != nil {
+		log.Fatalf("failed to create fdfs client: %v", err)
+	}
+	defer client.Close()
+
+	conn, err := bazilfuse.Mount(*mountpoint, bazilfuse.FSName("fastdfs"), bazilfuse.Subtype("fastdfs"))
+	if err != nil {
+		log.Fatalf("failed to mount %s: %v", *mountpoint, err)
+	}
+	defer conn.Close()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	go func() {
+		<-sigCh
+		bazilfuse.Unmount(*mountpoint)
+	}()
+
+	filesystem := fdfsfuse.New(client, *group, fdfsfuse.Options{ReadOnly: *readOnly})
+	if err := bazilfs.Serve(conn, filesystem); err != nil {
+		log.Fatalf("fuse serve error: %v", err)
+	}
+}
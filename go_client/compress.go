@@ -0,0 +1,179 @@
+// Package fdfs optional payload compression.
+// This file wires an optional Compressor into uploadSlaveFileInternal,
+// uploadBufferInternal (UploadFile/UploadBuffer, not the appender variants),
+// and DownloadFile, compressing the payload before it's sent to the
+// storage server and recording the codec and original size as file
+// metadata so the download path can reverse it transparently. See
+// ClientConfig.Compressor and go_client/compress/lz4 for a ready-made
+// implementation.
+//
+// The FastDFS wire protocol's 10-byte header (see protocol.go) has no spare
+// bits to signal "this payload is compressed" to the storage daemon, and
+// every unmodified FastDFS storage server expects to store and return
+// exactly the bytes it was given. So compression here is entirely a
+// client-side transform, undone on download by this client rather than by
+// the server: the compressed bytes are what's actually stored, and the
+// codec/original size needed to reverse it travel as ordinary file
+// metadata (the same side channel UploadReaderParallel uses for its
+// aggregate CRC32; see parallelChecksumMetaKey in parallelupload.go),
+// rather than as a magic-byte header prefixing the stored bytes: a tool
+// reading the file directly off disk (not through this client) can still
+// see what codec was used by reading metadata, without needing to parse a
+// bespoke header out of the file content itself.
+//
+// A single whole-payload write is compressed, whether it came from
+// uploadSlaveFileInternal or uploadBufferInternal. appendFileInternal and
+// modifyFileInternal write at caller-chosen byte offsets that
+// DownloadFileRange, the block cache (cache.go), and DownloadFileParallel
+// all later read back directly at those same offsets; compressing those
+// writes, or an appender file's initial block, would desynchronize stored
+// bytes from requested offsets for every offset-based reader in this
+// package, so appender uploads and append/modify are intentionally left
+// uncompressed. Callers needing offset-addressed access to a file should
+// not enable compression for it.
+package fdfs
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Compressor is a pluggable payload codec for uploadSlaveFileInternal/
+// uploadBufferInternal/DownloadFile. See go_client/compress/lz4 for a
+// ready-made implementation.
+type Compressor interface {
+	// Name identifies the codec and is recorded as file metadata so
+	// DownloadFile knows how to reverse it; must be stable across the
+	// lifetime of any file compressed with it.
+	Name() string
+
+	Compress(data []byte) ([]byte, error)
+
+	// Decompress expands data back to originalSize bytes, as recorded in
+	// file metadata at compression time.
+	Decompress(data []byte, originalSize int) ([]byte, error)
+}
+
+// Metadata keys maybeCompress/decompressIfNeeded use to record how to
+// reverse compression, alongside whatever caller-supplied metadata the
+// upload also carries.
+const (
+	compressionCodecMetaKey = "fdfs-compress-codec"
+	compressionSizeMetaKey  = "fdfs-compress-size"
+)
+
+// defaultCompressionMinRatio is used when ClientConfig.CompressionMinRatio
+// is unset: compression is only kept when it shrinks the payload by at
+// least this fraction, per CompressionThreshold in the originating request.
+const defaultCompressionMinRatio = 0.10
+
+// maybeCompress returns the bytes uploadSlaveFileInternal or
+// uploadBufferInternal should actually send for data, and metadata to merge onto the file recording how to
+// reverse it (nil when compression wasn't applied). It never fails:
+// compression is a pure optimization here, so any Compressor error, or a
+// result that doesn't shrink the payload by CompressionMinRatio, just falls
+// back to sending data unchanged.
+func (c *Client) maybeCompress(data []byte) ([]byte, map[string]string) {
+	cfg := c.config
+	if !cfg.EnableCompression || cfg.Compressor == nil || int64(len(data)) < cfg.CompressionMinSize {
+		return data, nil
+	}
+
+	compressed, err := cfg.Compressor.Compress(data)
+	if err != nil {
+		return data, nil
+	}
+
+	minRatio := cfg.CompressionMinRatio
+	if minRatio <= 0 {
+		minRatio = defaultCompressionMinRatio
+	}
+	if float64(len(data)-len(compressed)) < float64(len(data))*minRatio {
+		return data, nil
+	}
+
+	return compressed, map[string]string{
+		compressionCodecMetaKey: cfg.Compressor.Name(),
+		compressionSizeMetaKey:  strconv.Itoa(len(data)),
+	}
+}
+
+// decompressIfNeeded reverses maybeCompress given the metadata recorded
+// alongside the file, for DownloadFile. It falls back to returning data
+// unchanged when no compression codec is recorded, the recorded codec
+// doesn't match cfg.Compressor, or decompression fails.
+func (c *Client) decompressIfNeeded(data []byte, metadata map[string]string) []byte {
+	cfg := c.config
+	if cfg.Compressor == nil || metadata == nil {
+		return data
+	}
+	codec, ok := metadata[compressionCodecMetaKey]
+	if !ok || codec != cfg.Compressor.Name() {
+		return data
+	}
+	originalSize, err := strconv.Atoi(metadata[compressionSizeMetaKey])
+	if err != nil {
+		return data
+	}
+	decompressed, err := cfg.Compressor.Decompress(data, originalSize)
+	if err != nil {
+		return data
+	}
+	return decompressed
+}
+
+// DownloadFileRaw downloads fileID like DownloadFile but returns the bytes
+// exactly as stored, skipping decompressIfNeeded. Useful for a caller that
+// wants to inspect or re-upload the on-disk representation directly (e.g.
+// copying a compressed file to another FastDFS cluster without paying to
+// decompress and recompress it).
+func (c *Client) DownloadFileRaw(ctx context.Context, fileID string) ([]byte, error) {
+	if err := c.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	release, err := c.acquireDownload(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	ctx, span := c.startSpan(ctx, "download_file_raw")
+	span.SetTag("fdfs.file_id", fileID)
+	start := time.Now()
+	c.observerFor(ctx).OnRequestStart(ctx, "download_file_raw", map[string]interface{}{"file_id": fileID})
+	data, err := c.downloadRangeCached(ctx, fileID, 0, 0)
+	c.observerFor(ctx).OnRequestEnd(ctx, "download_file_raw", err, time.Since(start))
+	span.SetTag("fdfs.bytes", len(data))
+	finishSpan(span, err)
+	return data, err
+}
+
+// GetLogicalSize returns fileID's uncompressed size: the recorded
+// compressionSizeMetaKey when the file was compressed by this Client (or
+// another client using the same Compressor.Name), otherwise
+// FileInfo.FileSize, which for an uncompressed file already is the logical
+// size.
+func (c *Client) GetLogicalSize(ctx context.Context, fileID string) (int64, error) {
+	info, err := c.GetFileInfo(ctx, fileID)
+	if err != nil {
+		return 0, err
+	}
+	if c.config.Compressor == nil {
+		return info.FileSize, nil
+	}
+	metadata, err := c.getMetadataCached(ctx, fileID)
+	if err != nil {
+		return info.FileSize, nil
+	}
+	codec, ok := metadata[compressionCodecMetaKey]
+	if !ok || codec != c.config.Compressor.Name() {
+		return info.FileSize, nil
+	}
+	originalSize, err := strconv.Atoi(metadata[compressionSizeMetaKey])
+	if err != nil {
+		return info.FileSize, nil
+	}
+	return int64(originalSize), nil
+}
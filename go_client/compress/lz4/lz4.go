@@ -0,0 +1,51 @@
+// Package lz4 adapts github.com/pierrec/lz4/v4 to fdfs.Compressor, so
+// ClientConfig.Compressor can compress whole-payload uploads (UploadFile/
+// UploadBuffer and slave file uploads) with LZ4 instead of implementing a
+// codec from scratch. See go_client/compress.go for how it's used and why
+// appender uploads and AppendFile/ModifyFile are left uncompressed.
+package lz4
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	fdfs "github.com/happyfish100/fastdfs/go_client"
+	"github.com/pierrec/lz4/v4"
+)
+
+// Compressor adapts lz4.Writer/lz4.Reader to fdfs.Compressor.
+type Compressor struct{}
+
+// New returns an fdfs.Compressor backed by LZ4.
+func New() fdfs.Compressor {
+	return Compressor{}
+}
+
+// Name implements fdfs.Compressor.
+func (Compressor) Name() string {
+	return "lz4"
+}
+
+// Compress implements fdfs.Compressor.
+func (Compressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := lz4.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("lz4: compress: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("lz4: compress: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress implements fdfs.Compressor.
+func (Compressor) Decompress(data []byte, originalSize int) ([]byte, error) {
+	out := make([]byte, originalSize)
+	r := lz4.NewReader(bytes.NewReader(data))
+	if _, err := io.ReadFull(r, out); err != nil {
+		return nil, fmt.Errorf("lz4: decompress: %w", err)
+	}
+	return out, nil
+}
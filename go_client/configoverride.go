@@ -0,0 +1,170 @@
+// Package fdfs per-call configuration overrides.
+// This file lets a single call override a subset of ClientConfig via the
+// context, the same mechanism WithRetry (see retry.go) already established
+// for RetryPolicy. It avoids cloning the whole Client just to bump a
+// timeout for one large upload or tighten retries for one background job.
+package fdfs
+
+import (
+	"context"
+	"time"
+)
+
+// ConfigOverride holds the subset of ClientConfig a single call can
+// override via WithConfig. A zero value field means "use the
+// ClientConfig-wide setting"; there is no way to override a setting back
+// to its Go zero value through ConfigOverride.
+type ConfigOverride struct {
+	// ConnectTimeout would override the dial timeout for a new connection.
+	// NOTE: connections are pooled and dialed with the timeout baked in at
+	// pool-construction time (see NewConnectionPool), so this only takes
+	// effect for the tracker/storage server this call happens to dial a
+	// fresh connection to; a reused pooled connection keeps whatever
+	// timeout it was dialed with. Prefer NetworkTimeout for an override
+	// that reliably applies to every call.
+	ConnectTimeout time.Duration
+
+	// NetworkTimeout overrides the per-Send/Receive I/O timeout for every
+	// RPC made during this call.
+	NetworkTimeout time.Duration
+
+	// RetryPolicy overrides the backoff policy used between retry attempts
+	// for this call. Equivalent to WithRetry, provided here so it can be
+	// set alongside the other overrides in one ConfigOverride value.
+	RetryPolicy RetryPolicy
+
+	// PreferredGroup, if set, is passed as the groupName to the tracker's
+	// "query store server" RPC instead of letting the tracker pick any
+	// group, steering uploads made during this call to a specific group.
+	PreferredGroup string
+
+	// ChunkSize overrides streamChunkSize for UploadStream/DownloadStream
+	// calls made during this call.
+	ChunkSize int
+
+	// ChecksumMode, when set to ChecksumModeCRC32, makes DownloadFile verify
+	// the downloaded bytes against the CRC32 the tracker/storage servers
+	// recorded for the file (via GetFileInfo), returning an error with
+	// fdfs.CodeOf(err) == fdfs.CodeChecksumMismatch on mismatch.
+	ChecksumMode string
+
+	// RetryCount overrides ClientConfig.RetryCount for this call, e.g. to
+	// fail fast on a latency-sensitive metadata lookup while keeping a more
+	// generous retry budget for large uploads.
+	RetryCount int
+
+	// RetryableFunc overrides ClientConfig.RetryableFunc for this call.
+	// Equivalent to WithRetryableFunc, provided here so it can be set
+	// alongside the other overrides in one ConfigOverride value.
+	RetryableFunc RetryableFunc
+
+	// Observer overrides ClientConfig.Observer for this call's
+	// OnRequestStart/OnRequestEnd/OnRetry events, letting a multi-tenant
+	// caller attach per-tenant structured logging or tracing (e.g. a
+	// LogObserver tagging every line with a tenant ID) to one request
+	// without standing up a second Client. OnCircuitStateChange and
+	// OnConnPoolEvent are reported per-address across all callers, not
+	// per-request, so they always go to ClientConfig.Observer regardless of
+	// this override.
+	Observer Observer
+}
+
+// ChecksumModeCRC32 is the only supported ConfigOverride.ChecksumMode value today.
+const ChecksumModeCRC32 = "crc32"
+
+// configContextKey is the private context key used by WithConfig.
+type configContextKey struct{}
+
+// WithConfig returns a context carrying override as a per-call override of
+// ClientConfig, consulted by every Client method that makes an RPC.
+func WithConfig(ctx context.Context, override ConfigOverride) context.Context {
+	return context.WithValue(ctx, configContextKey{}, override)
+}
+
+// GetConfig returns the ConfigOverride attached to ctx via WithConfig, and
+// whether one was present.
+func GetConfig(ctx context.Context) (ConfigOverride, bool) {
+	override, ok := ctx.Value(configContextKey{}).(ConfigOverride)
+	return override, ok
+}
+
+// WithNetworkTimeout is shorthand for WithConfig with only NetworkTimeout set.
+func WithNetworkTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return WithConfig(ctx, ConfigOverride{NetworkTimeout: timeout})
+}
+
+// WithConnectTimeout is shorthand for WithConfig with only ConnectTimeout
+// set. See ConfigOverride.ConnectTimeout for its limitations.
+func WithConnectTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return WithConfig(ctx, ConfigOverride{ConnectTimeout: timeout})
+}
+
+// WithPreferredGroup is shorthand for WithConfig with only PreferredGroup set.
+func WithPreferredGroup(ctx context.Context, groupName string) context.Context {
+	return WithConfig(ctx, ConfigOverride{PreferredGroup: groupName})
+}
+
+// WithRetryCount is shorthand for WithConfig with only RetryCount set.
+func WithRetryCount(ctx context.Context, n int) context.Context {
+	return WithConfig(ctx, ConfigOverride{RetryCount: n})
+}
+
+// networkTimeout returns the NetworkTimeout override from ctx if present
+// and non-zero, otherwise the client-wide ClientConfig.NetworkTimeout.
+func (c *Client) networkTimeout(ctx context.Context) time.Duration {
+	if override, ok := GetConfig(ctx); ok && override.NetworkTimeout > 0 {
+		return override.NetworkTimeout
+	}
+	return c.config.NetworkTimeout
+}
+
+// preferredGroup returns groupName when the caller named one; otherwise the
+// PreferredGroup override from ctx if present, or "" for "any group".
+func (c *Client) preferredGroup(ctx context.Context, groupName string) string {
+	if groupName != "" {
+		return groupName
+	}
+	if override, ok := GetConfig(ctx); ok && override.PreferredGroup != "" {
+		return override.PreferredGroup
+	}
+	return groupName
+}
+
+// chunkSize returns the ChunkSize override from ctx if present and
+// positive, otherwise streamChunkSize.
+func (c *Client) chunkSize(ctx context.Context) int {
+	if override, ok := GetConfig(ctx); ok && override.ChunkSize > 0 {
+		return override.ChunkSize
+	}
+	return streamChunkSize
+}
+
+// checksumMode returns the ChecksumMode override from ctx if present,
+// otherwise "" (no verification).
+func (c *Client) checksumMode(ctx context.Context) string {
+	if override, ok := GetConfig(ctx); ok {
+		return override.ChecksumMode
+	}
+	return ""
+}
+
+// retryCount returns the RetryCount override from ctx if present and
+// positive, otherwise the client-wide ClientConfig.RetryCount.
+func (c *Client) retryCount(ctx context.Context) int {
+	if override, ok := GetConfig(ctx); ok && override.RetryCount > 0 {
+		return override.RetryCount
+	}
+	return c.config.RetryCount
+}
+
+// observerFor returns the Observer override from ctx if present,
+// otherwise the client-wide observer() (ClientConfig.Observer, or a no-op
+// default). Only used for the per-request events (OnRequestStart,
+// OnRequestEnd, OnRetry); OnCircuitStateChange and OnConnPoolEvent always
+// go through observer() directly since they aren't tied to one call.
+func (c *Client) observerFor(ctx context.Context) Observer {
+	if override, ok := GetConfig(ctx); ok && override.Observer != nil {
+		return override.Observer
+	}
+	return c.observer()
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,7 +31,7 @@ type Connection struct {
 //   - *Connection: ready-to-use connection
 //   - error: NetworkError if connection fails
 func NewConnection(addr string, timeout time.Duration) (*Connection, error) {
-	conn, err := net.DialTimeout("tcp", addr, timeout)
+	conn, err := net.DialTimeout("tcp", resolveDialAddr(addr), timeout)
 	if err != nil {
 		return nil, &NetworkError{
 			Op:   "dial",
@@ -116,6 +118,31 @@ func (c *Connection) Receive(size int, timeout time.Duration) ([]byte, error) {
 	return buf[:n], nil
 }
 
+// ReceiveInto behaves like Receive but reads into the caller-supplied buf
+// (up to len(buf) bytes) instead of allocating a new one, so a caller
+// copying many chunks (see streamCopyFromConn) can reuse a single buffer
+// across reads.
+func (c *Connection) ReceiveInto(buf []byte, timeout time.Duration) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if timeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(timeout))
+	}
+
+	n, err := c.conn.Read(buf)
+	if err != nil {
+		return 0, &NetworkError{
+			Op:   "read",
+			Addr: c.addr,
+			Err:  err,
+		}
+	}
+
+	c.lastUsed = time.Now()
+	return n, nil
+}
+
 // ReceiveFull reads exactly 'size' bytes from the server.
 // This method blocks until all bytes are received or an error occurs.
 // The timeout applies to the entire operation, not individual reads.
@@ -217,29 +244,235 @@ func (c *Connection) Addr() string {
 	return c.addr
 }
 
+// idleQueueCapacity bounds the buffered channel each serverPool uses to
+// hold idle connections. Channels can't be resized, so this is fixed well
+// above any realistic MaxConns/SetMaxConns target; the actual per-server
+// concurrency limit is enforced separately by serverPool.tryAdmit/releaseSlot,
+// which does respect SetMaxConns changes at runtime.
+const idleQueueCapacity = 4096
+
 // ConnectionPool manages a pool of reusable connections to multiple servers.
 // It maintains separate pools for each server address and handles:
 //   - Connection reuse to minimize overhead
 //   - Idle connection cleanup
 //   - Thread-safe concurrent access
 //   - Automatic connection health checking
+//   - Bounding total (idle + checked-out) connections per server to MaxConns,
+//     blocking Get instead of over-dialing once that limit is reached
+//   - Optionally rate-limiting new dials per server via MaxConnsPerSecond
 type ConnectionPool struct {
-	addrs          []string               // list of server addresses
-	maxConns       int                    // max connections per server
-	connectTimeout time.Duration          // timeout for new connections
-	idleTimeout    time.Duration          // max idle time before cleanup
-	pools          map[string]*serverPool // per-server connection pools
-	mu             sync.RWMutex           // protects pools map and closed flag
-	closed         bool                   // true if pool is closed
+	addrs             []string                               // list of server addresses
+	maxConns          int                                    // max connections per server
+	connectTimeout    time.Duration                          // timeout for new connections
+	idleTimeout       time.Duration                          // max idle time before cleanup
+	minConns          int                                    // pre-warmed connections per server
+	maxConnsPerSecond float64                                // dial rate limit per server, 0 disables
+	pools             map[string]*serverPool                 // per-server connection pools
+	mu                sync.RWMutex                           // protects pools map, addrs, and closed flag
+	closed            bool                                   // true if pool is closed
+	onEvent           func(addr string, event ConnPoolEvent) // optional Observer hook, set via SetEventHandler
+	metrics           Metrics                                // optional Metrics sink, set via SetMetrics
+	label             string                                 // "tracker" or "storage", tags every metric this pool emits
 }
 
-// serverPool holds connections for a single server.
-// It's an internal structure used by ConnectionPool.
+// SetMetrics registers a Metrics sink this pool reports pool.hit, pool.miss,
+// pool.dial_error counters and a pool.get_latency_ms histogram to, every
+// one tagged by label ("tracker" or "storage", see NewClient) and the
+// server address involved. Passing nil disables metrics reporting.
+func (p *ConnectionPool) SetMetrics(label string, metrics Metrics) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.label = label
+	p.metrics = metrics
+}
+
+// recordGet emits a pool.hit or pool.miss counter plus the get-latency
+// histogram for a successful Get. A no-op when no Metrics sink is set.
+func (p *ConnectionPool) recordGet(outcome, addr string, start time.Time) {
+	p.mu.RLock()
+	metrics, label := p.metrics, p.label
+	p.mu.RUnlock()
+	if metrics == nil {
+		return
+	}
+	tags := map[string]string{"pool": label, "addr": addr}
+	metrics.Counter("fdfs.pool."+outcome, tags).Add(1)
+	metrics.Histogram("fdfs.pool.get_latency_ms", tags).Observe(float64(time.Since(start).Milliseconds()))
+}
+
+// recordDialError emits a pool.dial_error counter. A no-op when no Metrics
+// sink is set.
+func (p *ConnectionPool) recordDialError(addr string) {
+	p.mu.RLock()
+	metrics, label := p.metrics, p.label
+	p.mu.RUnlock()
+	if metrics == nil {
+		return
+	}
+	metrics.Counter("fdfs.pool.dial_error", map[string]string{"pool": label, "addr": addr}).Add(1)
+}
+
+// SetEventHandler registers a callback invoked on connection creation,
+// reuse, and close. Passing nil disables event reporting. Intended for
+// Client to wire ClientConfig.Observer.OnConnPoolEvent.
+func (p *ConnectionPool) SetEventHandler(onEvent func(addr string, event ConnPoolEvent)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onEvent = onEvent
+}
+
+func (p *ConnectionPool) fireEvent(addr string, event ConnPoolEvent) {
+	p.mu.RLock()
+	onEvent := p.onEvent
+	p.mu.RUnlock()
+	if onEvent != nil {
+		onEvent(addr, event)
+	}
+}
+
+// serverPool holds connections for a single server, backed by a buffered
+// channel of idle connections rather than a mutex-guarded slice, so Get can
+// block on a channel receive (respecting ctx.Done()) instead of polling
+// when the server is at MaxConns.
 type serverPool struct {
-	addr      string        // server address
-	conns     []*Connection // available connections (LIFO stack)
-	mu        sync.Mutex    // protects conns slice
-	lastClean time.Time     // last time idle connections were cleaned
+	addr string
+
+	idle chan *Connection // idle, reusable connections; cap idleQueueCapacity
+	free chan struct{}    // buffered(1) wake-up sent whenever a slot frees without an idle conn to hand off
+
+	mu        sync.Mutex // guards total, dialErrs, lastClean below
+	total     int        // live connections: checked out + idle, bounded by ConnectionPool.maxConns
+	dialErrs  int64
+	lastClean time.Time
+
+	waiters    int64 // goroutines currently blocked in Get; accessed via atomic
+	timeouts   int64 // Get calls that returned via ctx.Done() while blocked; accessed via atomic
+	idleClosed int64 // idle connections discarded for being past idleTimeout; accessed via atomic
+
+	latencyMu sync.Mutex
+	latencies []time.Duration // ring buffer of recent successful Get latencies, cap poolLatencySampleCap
+	latencyAt int             // next write index into latencies
+
+	limiter *dialLimiter // nil when MaxConnsPerSecond <= 0
+}
+
+// poolLatencySampleCap bounds the ring buffer serverPool.recordLatency
+// writes into, so AcquireLatencyP50/P99 reporting cost stays flat
+// regardless of how many Gets a long-lived pool has served.
+const poolLatencySampleCap = 128
+
+// recordLatency appends a successful Get's latency to the ring buffer,
+// overwriting the oldest sample once the buffer is full.
+func (sp *serverPool) recordLatency(d time.Duration) {
+	sp.latencyMu.Lock()
+	defer sp.latencyMu.Unlock()
+	if len(sp.latencies) < poolLatencySampleCap {
+		sp.latencies = append(sp.latencies, d)
+		return
+	}
+	sp.latencies[sp.latencyAt] = d
+	sp.latencyAt = (sp.latencyAt + 1) % poolLatencySampleCap
+}
+
+// latencyPercentiles returns the p50 and p99 of the recorded Get latency
+// samples. Both are zero when no samples have been recorded yet.
+func (sp *serverPool) latencyPercentiles() (p50, p99 time.Duration) {
+	sp.latencyMu.Lock()
+	samples := append([]time.Duration(nil), sp.latencies...)
+	sp.latencyMu.Unlock()
+	if len(samples) == 0 {
+		return 0, 0
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	p50 = samples[(len(samples)-1)*50/100]
+	p99 = samples[(len(samples)-1)*99/100]
+	return p50, p99
+}
+
+func newServerPool(addr string, connsPerSec float64) *serverPool {
+	sp := &serverPool{
+		addr:      addr,
+		idle:      make(chan *Connection, idleQueueCapacity),
+		free:      make(chan struct{}, 1),
+		lastClean: time.Now(),
+	}
+	if connsPerSec > 0 {
+		sp.limiter = newDialLimiter(connsPerSec)
+	}
+	return sp
+}
+
+// tryAdmit claims a slot for a new connection if total is below maxConns,
+// returning whether a slot was claimed.
+func (sp *serverPool) tryAdmit(maxConns int) bool {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.total >= maxConns {
+		return false
+	}
+	sp.total++
+	return true
+}
+
+// releaseSlot gives up a previously admitted slot (the connection it was
+// for failed to dial, was closed, or was discarded) and wakes one blocked
+// Get, if any, so it can retry admission.
+func (sp *serverPool) releaseSlot() {
+	sp.mu.Lock()
+	sp.total--
+	sp.mu.Unlock()
+	select {
+	case sp.free <- struct{}{}:
+	default:
+	}
+}
+
+func (sp *serverPool) recordDialErr() {
+	sp.mu.Lock()
+	sp.dialErrs++
+	sp.mu.Unlock()
+}
+
+// dialLimiter is a per-server, non-blocking token bucket limiting how many
+// new connections ConnectionPool.Get may dial per second. Unlike
+// RateLimiter (pool.go), which blocks the caller until a token frees up, a
+// drained dialLimiter rejects immediately with ErrPoolRateLimited: a
+// thundering herd of callers after a tracker failover should back off and
+// retry through the existing RetryPolicy (see retry.go), not pile up
+// blocked on the dial itself.
+type dialLimiter struct {
+	mu      sync.Mutex
+	tokens  float64
+	burst   float64
+	perSec  float64
+	lastRef time.Time
+}
+
+func newDialLimiter(perSec float64) *dialLimiter {
+	burst := perSec
+	if burst < 1 {
+		burst = 1
+	}
+	return &dialLimiter{tokens: burst, burst: burst, perSec: perSec, lastRef: time.Now()}
+}
+
+// allow reports whether a dial may proceed now, consuming a token if so.
+func (d *dialLimiter) allow() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.tokens += now.Sub(d.lastRef).Seconds() * d.perSec
+	if d.tokens > d.burst {
+		d.tokens = d.burst
+	}
+	d.lastRef = now
+
+	if d.tokens >= 1 {
+		d.tokens--
+		return true
+	}
+	return false
 }
 
 // NewConnectionPool creates a new connection pool for the specified servers.
@@ -256,39 +489,104 @@ type serverPool struct {
 //   - *ConnectionPool: initialized pool
 //   - error: never returns error (for API compatibility)
 func NewConnectionPool(addrs []string, maxConns int, connectTimeout, idleTimeout time.Duration) (*ConnectionPool, error) {
+	return NewConnectionPoolWithOptions(addrs, ConnectionPoolOptions{
+		MaxConns:       maxConns,
+		ConnectTimeout: connectTimeout,
+		IdleTimeout:    idleTimeout,
+	})
+}
+
+// ConnectionPoolOptions configures NewConnectionPoolWithOptions. The zero
+// value for MinConns and MaxConnsPerSecond reproduces NewConnectionPool's
+// behavior: no pre-warming, no dial rate limit.
+type ConnectionPoolOptions struct {
+	MaxConns       int
+	ConnectTimeout time.Duration
+	IdleTimeout    time.Duration
+
+	// MinConns pre-warms each server pool with this many connections at
+	// construction time (and whenever AddAddr adds a new server), so the
+	// first requests after startup don't each pay a dial round-trip.
+	// Capped at MaxConns; a pre-warm dial failure is dropped silently (the
+	// pool falls back to dialing on demand, same as with MinConns unset).
+	MinConns int
+
+	// MaxConnsPerSecond, if > 0, caps how many new connections a single
+	// server pool may dial per second (independent per address, not shared
+	// across the whole ConnectionPool), guarding against a thundering herd
+	// of callers hammering a storage server right after a tracker
+	// failover. Get returns ErrPoolRateLimited instead of dialing once the
+	// bucket is empty.
+	MaxConnsPerSecond float64
+}
 
+// NewConnectionPoolWithOptions is NewConnectionPool with MinConns
+// pre-warming and MaxConnsPerSecond dial rate limiting.
+func NewConnectionPoolWithOptions(addrs []string, opts ConnectionPoolOptions) (*ConnectionPool, error) {
 	pool := &ConnectionPool{
-		addrs:          addrs,
-		maxConns:       maxConns,
-		connectTimeout: connectTimeout,
-		idleTimeout:    idleTimeout,
-		pools:          make(map[string]*serverPool),
+		addrs:             addrs,
+		maxConns:          opts.MaxConns,
+		connectTimeout:    opts.ConnectTimeout,
+		idleTimeout:       opts.IdleTimeout,
+		minConns:          opts.MinConns,
+		maxConnsPerSecond: opts.MaxConnsPerSecond,
+		pools:             make(map[string]*serverPool),
 	}
 
 	// Initialize empty pools for each server address
 	for _, addr := range addrs {
-		pool.pools[addr] = &serverPool{
-			addr:      addr,
-			conns:     make([]*Connection, 0, maxConns),
-			lastClean: time.Now(),
-		}
+		sp := newServerPool(addr, opts.MaxConnsPerSecond)
+		pool.pools[addr] = sp
+		pool.prewarm(sp, addr)
 	}
 
 	return pool, nil
 }
 
+// prewarm dials min(minConns, maxConns) connections for sp up front,
+// dropping any that fail to dial; a no-op when minConns <= 0.
+func (p *ConnectionPool) prewarm(sp *serverPool, addr string) {
+	n := p.minConns
+	if n > p.maxConns {
+		n = p.maxConns
+	}
+	for i := 0; i < n; i++ {
+		if !sp.tryAdmit(p.maxConns) {
+			break
+		}
+		conn, err := NewConnection(addr, p.connectTimeout)
+		if err != nil {
+			sp.releaseSlot()
+			sp.recordDialErr()
+			continue
+		}
+		p.fireEvent(addr, ConnPoolEventCreated)
+		select {
+		case sp.idle <- conn:
+		default:
+			sp.releaseSlot()
+			conn.Close()
+		}
+	}
+}
+
 // Get retrieves a connection from the pool or creates a new one.
-// It prefers reusing existing idle connections but will create new ones if needed.
-// Stale connections are automatically discarded.
+// It prefers reusing existing idle connections but will dial a new one if
+// the server's total (idle + checked-out) connections is below MaxConns.
+// Once MaxConns is reached, Get blocks until an idle connection becomes
+// available or ctx is done, instead of dialing past the limit. Stale
+// connections are automatically discarded.
 //
 // Parameters:
-//   - ctx: context for cancellation (currently not used, for future enhancement)
+//   - ctx: context for cancellation
 //   - addr: specific server address, or "" to use the first available server
 //
 // Returns:
 //   - *Connection: ready-to-use connection
-//   - error: if pool is closed or connection cannot be established
+//   - error: ErrClientClosed, ErrPoolRateLimited, ctx.Err(), or a dial error
 func (p *ConnectionPool) Get(ctx context.Context, addr string) (*Connection, error) {
+	start := time.Now()
+
 	p.mu.RLock()
 	if p.closed {
 		p.mu.RUnlock()
@@ -298,61 +596,107 @@ func (p *ConnectionPool) Get(ctx context.Context, addr string) (*Connection, err
 
 	// If no specific address requested, use the first server in the list
 	if addr == "" {
+		p.mu.RLock()
 		if len(p.addrs) == 0 {
+			p.mu.RUnlock()
 			return nil, fmt.Errorf("no addresses available")
 		}
 		addr = p.addrs[0]
+		p.mu.RUnlock()
 	}
 
-	p.mu.RLock()
-	sp, ok := p.pools[addr]
-	p.mu.RUnlock()
+	sp := p.serverPoolFor(addr)
 
-	if !ok {
-		// Server not in pool yet; create a new pool for it dynamically
-		p.mu.Lock()
-		sp = &serverPool{
-			addr:      addr,
-			conns:     make([]*Connection, 0, p.maxConns),
-			lastClean: time.Now(),
+	for {
+		select {
+		case conn := <-sp.idle:
+			if conn.IsAlive() {
+				p.fireEvent(addr, ConnPoolEventReused)
+				p.recordGet("hit", addr, start)
+				sp.recordLatency(time.Since(start))
+				return conn, nil
+			}
+			conn.Close()
+			sp.releaseSlot()
+			continue
+		default:
 		}
-		p.pools[addr] = sp
-		p.mu.Unlock()
-	}
 
-	// Try to reuse an existing connection from the pool (LIFO order)
-	sp.mu.Lock()
-	for len(sp.conns) > 0 {
-		conn := sp.conns[len(sp.conns)-1]
-		sp.conns = sp.conns[:len(sp.conns)-1]
-		sp.mu.Unlock()
-
-		// Verify the connection is still healthy before returning it
-		if conn.IsAlive() {
+		if sp.tryAdmit(p.MaxConns()) {
+			if sp.limiter != nil && !sp.limiter.allow() {
+				sp.releaseSlot()
+				return nil, ErrPoolRateLimited
+			}
+			connectTimeout := p.connectTimeout
+			if override, ok := GetConfig(ctx); ok && override.ConnectTimeout > 0 {
+				connectTimeout = override.ConnectTimeout
+			}
+			conn, err := NewConnection(addr, connectTimeout)
+			if err != nil {
+				sp.releaseSlot()
+				sp.recordDialErr()
+				p.recordDialError(addr)
+				return nil, err
+			}
+			p.fireEvent(addr, ConnPoolEventCreated)
+			p.recordGet("miss", addr, start)
+			sp.recordLatency(time.Since(start))
 			return conn, nil
 		}
-		conn.Close()
 
-		sp.mu.Lock()
+		// Pool is at MaxConns with nothing idle: block for a slot.
+		atomic.AddInt64(&sp.waiters, 1)
+		select {
+		case conn := <-sp.idle:
+			atomic.AddInt64(&sp.waiters, -1)
+			if conn.IsAlive() {
+				p.fireEvent(addr, ConnPoolEventReused)
+				p.recordGet("hit", addr, start)
+				sp.recordLatency(time.Since(start))
+				return conn, nil
+			}
+			conn.Close()
+			sp.releaseSlot()
+		case <-sp.free:
+			atomic.AddInt64(&sp.waiters, -1)
+		case <-ctx.Done():
+			atomic.AddInt64(&sp.waiters, -1)
+			atomic.AddInt64(&sp.timeouts, 1)
+			return nil, ctx.Err()
+		}
 	}
-	sp.mu.Unlock()
+}
 
-	// No reusable connection available; create a new one
-	conn, err := NewConnection(addr, p.connectTimeout)
-	if err != nil {
-		return nil, err
+// serverPoolFor returns addr's serverPool, creating one (without
+// pre-warming; Get is about to dial or reuse a connection itself) if addr
+// hasn't been seen before.
+func (p *ConnectionPool) serverPoolFor(addr string) *serverPool {
+	p.mu.RLock()
+	sp, ok := p.pools[addr]
+	p.mu.RUnlock()
+	if ok {
+		return sp
 	}
 
-	return conn, nil
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if sp, ok = p.pools[addr]; ok {
+		return sp
+	}
+	sp = newServerPool(addr, p.maxConnsPerSecond)
+	p.pools[addr] = sp
+	return sp
 }
 
 // Put returns a connection to the pool for reuse.
 // The connection is only kept if:
 //   - The pool is not closed
-//   - The pool is not full
 //   - The connection hasn't been idle too long
+//   - Its server's total is within MaxConns (SetMaxConns may have lowered it)
+//   - Its server's idle queue has room (always true in practice; see
+//     idleQueueCapacity)
 //
-// Otherwise, the connection is closed.
+// Otherwise, the connection is closed and its pool slot released.
 //
 // Parameters:
 //   - conn: connection to return (nil is safe)
@@ -364,66 +708,89 @@ func (p *ConnectionPool) Put(conn *Connection) error {
 	}
 
 	p.mu.RLock()
-	if p.closed {
-		p.mu.RUnlock()
-		return conn.Close()
-	}
-
+	closed := p.closed
 	sp, ok := p.pools[conn.Addr()]
 	p.mu.RUnlock()
 
+	if closed {
+		if ok {
+			sp.releaseSlot()
+		}
+		return conn.Close()
+	}
 	if !ok {
+		p.fireEvent(conn.Addr(), ConnPoolEventClosed)
 		return conn.Close()
 	}
 
+	// Discard the connection if SetMaxConns lowered the limit below the
+	// server's current total since this connection was admitted.
 	sp.mu.Lock()
-	defer sp.mu.Unlock()
-
-	// Discard connection if pool is at capacity
-	if len(sp.conns) >= p.maxConns {
+	total := sp.total
+	sp.mu.Unlock()
+	if total > p.MaxConns() {
+		sp.releaseSlot()
+		p.fireEvent(conn.Addr(), ConnPoolEventClosed)
 		return conn.Close()
 	}
 
 	// Discard connection if it's been idle too long
 	if time.Since(conn.LastUsed()) > p.idleTimeout {
+		sp.releaseSlot()
+		atomic.AddInt64(&sp.idleClosed, 1)
+		p.fireEvent(conn.Addr(), ConnPoolEventClosed)
 		return conn.Close()
 	}
 
-	// Connection is healthy and pool has space; add it back
-	sp.conns = append(sp.conns, conn)
-
-	// Trigger periodic cleanup if it's been a while
-	if time.Since(sp.lastClean) > p.idleTimeout {
-		p.cleanPool(sp)
+	select {
+	case sp.idle <- conn:
+	default:
+		// idle queue (cap idleQueueCapacity) is full; discard rather than
+		// block the caller returning a connection.
+		sp.releaseSlot()
+		p.fireEvent(conn.Addr(), ConnPoolEventClosed)
+		return conn.Close()
 	}
 
+	sp.maybeClean(p.idleTimeout)
 	return nil
 }
 
-// cleanPool removes stale and dead connections from a server pool.
-// This is called periodically when connections are returned to the pool.
-// The serverPool must be locked by the caller.
-//
-// Parameters:
-//   - sp: the server pool to clean
-func (p *ConnectionPool) cleanPool(sp *serverPool) {
-	now := time.Now()
-	validConns := make([]*Connection, 0, len(sp.conns))
+// maybeClean drains and re-fills sp.idle, dropping stale or dead
+// connections, if idleTimeout has elapsed since the last cleanup.
+func (sp *serverPool) maybeClean(idleTimeout time.Duration) {
+	sp.mu.Lock()
+	if time.Since(sp.lastClean) <= idleTimeout {
+		sp.mu.Unlock()
+		return
+	}
+	sp.lastClean = time.Now()
+	sp.mu.Unlock()
 
-	// Check each connection and keep only the healthy ones
-	for _, conn := range sp.conns {
-		if now.Sub(conn.LastUsed()) > p.idleTimeout || !conn.IsAlive() {
-			conn.Close()
-		} else {
-			validConns = append(validConns, conn)
+	n := len(sp.idle)
+	for i := 0; i < n; i++ {
+		select {
+		case conn := <-sp.idle:
+			if time.Since(conn.LastUsed()) > idleTimeout || !conn.IsAlive() {
+				conn.Close()
+				sp.releaseSlot()
+				atomic.AddInt64(&sp.idleClosed, 1)
+				continue
+			}
+			select {
+			case sp.idle <- conn:
+			default:
+				conn.Close()
+				sp.releaseSlot()
+			}
+		default:
+			return
 		}
 	}
-
-	sp.conns = validConns
-	sp.lastClean = now
 }
 
-// AddAddr dynamically adds a new server address to the pool.
+// AddAddr dynamically adds a new server address to the pool, pre-warming
+// it with MinConns connections the same as NewConnectionPoolWithOptions.
 // This is useful for adding storage servers discovered at runtime.
 // If the address already exists, this is a no-op.
 //
@@ -431,30 +798,170 @@ func (p *ConnectionPool) cleanPool(sp *serverPool) {
 //   - addr: server address in "host:port" format
 func (p *ConnectionPool) AddAddr(addr string) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	if p.closed {
+		p.mu.Unlock()
 		return
 	}
 
 	// Check if address already exists
 	for _, a := range p.addrs {
 		if a == addr {
+			p.mu.Unlock()
 			return
 		}
 	}
 
 	p.addrs = append(p.addrs, addr)
-	p.pools[addr] = &serverPool{
-		addr:      addr,
-		conns:     make([]*Connection, 0, p.maxConns),
-		lastClean: time.Now(),
+	sp := newServerPool(addr, p.maxConnsPerSecond)
+	p.pools[addr] = sp
+	p.mu.Unlock()
+
+	p.prewarm(sp, addr)
+}
+
+// MaxConns returns the pool's current per-server connection limit.
+func (p *ConnectionPool) MaxConns() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.maxConns
+}
+
+// SetMaxConns changes the pool's per-server connection limit at runtime,
+// for AdaptivePoolConfig (see adaptivepool.go). It only changes the limit
+// enforced by future Get/Put calls; it never closes connections already
+// checked out or already idle, even when lowering the limit below the
+// current total (they're pruned the next time each is Put back and found
+// over the new limit).
+func (p *ConnectionPool) SetMaxConns(n int) {
+	if n < 1 {
+		n = 1
 	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxConns = n
 }
 
-// Close shuts down the connection pool and closes all connections.
-// After Close is called, Get will return ErrClientClosed.
-// It's safe to call Close multiple times.
+// Depths returns the number of idle (pooled, not checked out) connections
+// per server address, for reporting as a Metrics gauge.
+func (p *ConnectionPool) Depths() map[string]int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	depths := make(map[string]int, len(p.pools))
+	for addr, sp := range p.pools {
+		depths[addr] = len(sp.idle)
+	}
+	return depths
+}
+
+// PoolStats is a snapshot of one server address's connection pool state,
+// returned by ConnectionPool.Stats.
+type PoolStats struct {
+	Addr string
+
+	// Idle is the number of connections currently pooled and ready to reuse.
+	Idle int
+	// InUse is the number of connections currently checked out by a caller.
+	InUse int
+	// Waiters is the number of Get calls currently blocked waiting for a
+	// slot because this server is at MaxConns with nothing idle.
+	Waiters int
+	// DialErrors is the cumulative count of failed NewConnection attempts
+	// for this server, across both Get and pre-warming.
+	DialErrors int64
+	// Timeouts is the cumulative count of Get calls that returned because
+	// ctx was done while blocked waiting for a slot at MaxConns.
+	Timeouts int64
+	// IdleClosed is the cumulative count of idle connections discarded for
+	// having been idle longer than IdleTimeout (found by Put or by the
+	// periodic maybeClean sweep), for failing maybeClean's liveness check,
+	// or by EvictIdle.
+	IdleClosed int64
+	// AcquireLatencyP50 and AcquireLatencyP99 summarize how long recent
+	// successful Get calls took, over a bounded rolling sample
+	// (poolLatencySampleCap); both are zero until at least one Get
+	// succeeds.
+	AcquireLatencyP50 time.Duration
+	AcquireLatencyP99 time.Duration
+}
+
+// Stats returns a snapshot of idle/in-use/waiter/dial-error counts for
+// every server address this pool has seen, for wiring into Prometheus or
+// similar operator tooling to observe pool saturation.
+func (p *ConnectionPool) Stats() map[string]PoolStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stats := make(map[string]PoolStats, len(p.pools))
+	for addr, sp := range p.pools {
+		sp.mu.Lock()
+		total := sp.total
+		dialErrs := sp.dialErrs
+		sp.mu.Unlock()
+		idle := len(sp.idle)
+		p50, p99 := sp.latencyPercentiles()
+		stats[addr] = PoolStats{
+			Addr:              addr,
+			Idle:              idle,
+			InUse:             total - idle,
+			Waiters:           int(atomic.LoadInt64(&sp.waiters)),
+			DialErrors:        dialErrs,
+			Timeouts:          atomic.LoadInt64(&sp.timeouts),
+			IdleClosed:        atomic.LoadInt64(&sp.idleClosed),
+			AcquireLatencyP50: p50,
+			AcquireLatencyP99: p99,
+		}
+	}
+	return stats
+}
+
+// Addrs returns every server address this pool has a serverPool for,
+// including ones added dynamically via AddAddr, in no particular order.
+func (p *ConnectionPool) Addrs() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	addrs := make([]string, 0, len(p.pools))
+	for addr := range p.pools {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// EvictIdle force-closes every idle connection in every server pool,
+// regardless of how long it's been idle. Unlike maybeClean, which only
+// prunes stale connections and runs at most once per idleTimeout as a
+// side effect of Put, EvictIdle runs immediately and unconditionally, for
+// an operator who wants to shed idle connections on demand (e.g. before a
+// planned storage server maintenance window).
+func (p *ConnectionPool) EvictIdle() {
+	p.mu.RLock()
+	pools := make([]*serverPool, 0, len(p.pools))
+	for _, sp := range p.pools {
+		pools = append(pools, sp)
+	}
+	p.mu.RUnlock()
+
+	for _, sp := range pools {
+		n := len(sp.idle)
+	drain:
+		for i := 0; i < n; i++ {
+			select {
+			case conn := <-sp.idle:
+				conn.Close()
+				sp.releaseSlot()
+				atomic.AddInt64(&sp.idleClosed, 1)
+			default:
+				break drain
+			}
+		}
+	}
+}
+
+// Close shuts down the connection pool and closes all idle connections.
+// After Close is called, Get will return ErrClientClosed. Connections
+// already checked out are closed when their caller Puts them back (Put
+// closes rather than re-pools once the pool is closed). It's safe to call
+// Close multiple times.
 //
 // Returns nil on success, or an error if closing connections fails.
 func (p *ConnectionPool) Close() error {
@@ -464,17 +971,23 @@ func (p *ConnectionPool) Close() error {
 	if p.closed {
 		return nil
 	}
-
 	p.closed = true
 
 	for _, sp := range p.pools {
-		sp.mu.Lock()
-		for _, conn := range sp.conns {
-			conn.Close()
-		}
-		sp.conns = nil
-		sp.mu.Unlock()
+		drainIdle(sp)
 	}
 
 	return nil
 }
+
+// drainIdle closes every connection currently sitting in sp.idle.
+func drainIdle(sp *serverPool) {
+	for {
+		select {
+		case conn := <-sp.idle:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
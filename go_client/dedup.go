@@ -0,0 +1,257 @@
+// Package fdfs content-addressable dedup upload.
+// This file adds an opt-in upload path that skips re-uploading content the
+// caller has already stored, borrowing the digest/CAS shape from Bazel's
+// remote-apis: a caller hashes data client-side, checks a DigestIndex for a
+// previous upload of that exact content, and only talks to the
+// tracker/storage servers on a miss. The current examples upload the same
+// generated test buffer many times over (see examples/batch's
+// createTestData) and store a distinct copy each time; UploadIfMissing and
+// BatchUploadIfMissing let a caller avoid that for workloads with real
+// duplicate content (e.g. re-syncing the same asset across environments).
+package fdfs
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Digest is a content hash (SHA-256) identifying a blob for dedup purposes.
+type Digest [sha256.Size]byte
+
+// ComputeDigest hashes data, for computing a Digest ahead of
+// UploadIfMissing/FindMissingBlobs (e.g. to batch a FindMissingBlobs call
+// before reading the data those blobs would require uploading).
+func ComputeDigest(data []byte) Digest {
+	return Digest(sha256.Sum256(data))
+}
+
+// String renders the digest as lowercase hex.
+func (d Digest) String() string {
+	return hex.EncodeToString(d[:])
+}
+
+// DigestIndex maps a content Digest to the FileID it was last uploaded as.
+// Implementations must be safe for concurrent use.
+type DigestIndex interface {
+	// Lookup returns the FileID previously stored for digest, if any.
+	Lookup(digest Digest) (fileID string, ok bool)
+	// Store records that digest's content now lives at fileID.
+	Store(digest Digest, fileID string) error
+}
+
+// memoryDigestIndex is an in-memory, LRU-bounded DigestIndex. Entries beyond
+// MaxEntries are evicted oldest-first, the same list+map LRU shape as
+// readCache (see cache.go).
+type memoryDigestIndex struct {
+	mu         sync.Mutex
+	maxEntries int
+	lru        *list.List // of *digestEntry, front = most recently used
+	entries    map[Digest]*list.Element
+}
+
+type digestEntry struct {
+	digest Digest
+	fileID string
+}
+
+// defaultDigestIndexEntries is used when NewMemoryDigestIndex is given
+// maxEntries <= 0.
+const defaultDigestIndexEntries = 100000
+
+// NewMemoryDigestIndex returns an in-memory DigestIndex holding at most
+// maxEntries digest→FileID mappings, evicting the least recently used entry
+// once full. Defaults to 100000 when maxEntries <= 0.
+func NewMemoryDigestIndex(maxEntries int) DigestIndex {
+	if maxEntries <= 0 {
+		maxEntries = defaultDigestIndexEntries
+	}
+	return &memoryDigestIndex{
+		maxEntries: maxEntries,
+		lru:        list.New(),
+		entries:    make(map[Digest]*list.Element),
+	}
+}
+
+// Lookup implements DigestIndex.
+func (idx *memoryDigestIndex) Lookup(digest Digest) (string, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	elem, ok := idx.entries[digest]
+	if !ok {
+		return "", false
+	}
+	idx.lru.MoveToFront(elem)
+	return elem.Value.(*digestEntry).fileID, true
+}
+
+// Store implements DigestIndex.
+func (idx *memoryDigestIndex) Store(digest Digest, fileID string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if elem, ok := idx.entries[digest]; ok {
+		elem.Value.(*digestEntry).fileID = fileID
+		idx.lru.MoveToFront(elem)
+		return nil
+	}
+
+	elem := idx.lru.PushFront(&digestEntry{digest: digest, fileID: fileID})
+	idx.entries[digest] = elem
+	for idx.lru.Len() > idx.maxEntries {
+		oldest := idx.lru.Back()
+		idx.lru.Remove(oldest)
+		delete(idx.entries, oldest.Value.(*digestEntry).digest)
+	}
+	return nil
+}
+
+// digestIndexHeader identifies the file format a fileDigestIndex persists
+// to. NewFileDigestIndex rejects any file that doesn't start with it.
+const digestIndexHeader = "fastdfs-digests v1\n"
+
+// fileDigestIndex is a file-backed DigestIndex: every mapping lives in
+// memory for Lookup, and Store both updates that map and appends a record to
+// an on-disk append-only log, mirroring IDList's durability shape (see
+// idlist.go).
+type fileDigestIndex struct {
+	mu      sync.Mutex
+	entries map[Digest]string
+	f       *os.File
+}
+
+// NewFileDigestIndex opens (creating if needed) a file-backed DigestIndex at
+// path, replaying any existing entries into memory before returning.
+func NewFileDigestIndex(path string) (DigestIndex, error) {
+	entries := make(map[Digest]string)
+	hasHeader := false
+
+	if existing, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(existing)
+		if scanner.Scan() {
+			if header := scanner.Text() + "\n"; header != digestIndexHeader {
+				existing.Close()
+				return nil, fmt.Errorf("digest index: unrecognized header %q", header)
+			}
+			hasHeader = true
+			for scanner.Scan() {
+				line := scanner.Text()
+				if line == "" {
+					continue
+				}
+				digest, fileID, err := parseDigestIndexLine(line)
+				if err != nil {
+					existing.Close()
+					return nil, fmt.Errorf("digest index: %w", err)
+				}
+				entries[digest] = fileID
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			existing.Close()
+			return nil, fmt.Errorf("digest index: %w", err)
+		}
+		existing.Close()
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("open digest index: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open digest index: %w", err)
+	}
+	if !hasHeader {
+		if _, err := f.WriteString(digestIndexHeader); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("write digest index header: %w", err)
+		}
+	}
+
+	return &fileDigestIndex{entries: entries, f: f}, nil
+}
+
+// Lookup implements DigestIndex.
+func (idx *fileDigestIndex) Lookup(digest Digest) (string, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	fileID, ok := idx.entries[digest]
+	return fileID, ok
+}
+
+// Store implements DigestIndex.
+func (idx *fileDigestIndex) Store(digest Digest, fileID string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.entries[digest] = fileID
+	if _, err := fmt.Fprintf(idx.f, "%s\t%s\n", digest.String(), fileID); err != nil {
+		return err
+	}
+	return idx.f.Sync()
+}
+
+func parseDigestIndexLine(line string) (Digest, string, error) {
+	fields := strings.SplitN(line, "\t", 2)
+	if len(fields) != 2 {
+		return Digest{}, "", fmt.Errorf("malformed line %q: want 2 tab-separated fields", line)
+	}
+	raw, err := hex.DecodeString(fields[0])
+	if err != nil || len(raw) != sha256.Size {
+		return Digest{}, "", fmt.Errorf("malformed digest in %q", line)
+	}
+	var digest Digest
+	copy(digest[:], raw)
+	return digest, fields[1], nil
+}
+
+// UploadIfMissing uploads data only if client.config.DigestIndex has no
+// record of its SHA-256 digest; otherwise it returns the previously stored
+// FileID without touching the tracker/storage servers. hit reports which
+// case occurred. A nil DigestIndex (the default) disables dedup entirely:
+// every call behaves like UploadBuffer with hit always false.
+func (c *Client) UploadIfMissing(ctx context.Context, data []byte, fileExtName string, metadata map[string]string) (fileID string, hit bool, err error) {
+	if c.config.DigestIndex == nil {
+		fileID, err = c.UploadBuffer(ctx, data, fileExtName, metadata)
+		return fileID, false, err
+	}
+
+	digest := ComputeDigest(data)
+	if cached, ok := c.config.DigestIndex.Lookup(digest); ok {
+		return cached, true, nil
+	}
+
+	fileID, err = c.UploadBuffer(ctx, data, fileExtName, metadata)
+	if err != nil {
+		return "", false, err
+	}
+	if err := c.config.DigestIndex.Store(digest, fileID); err != nil {
+		return fileID, false, fmt.Errorf("store digest index entry: %w", err)
+	}
+	return fileID, false, nil
+}
+
+// FindMissingBlobs returns the subset of digests not already recorded in
+// client.config.DigestIndex. Unlike Bazel remote-apis' FindMissingBlobs,
+// this never round-trips to the tracker/storage servers: the DigestIndex is
+// entirely local to this Client (or to whatever file it's backed by), so
+// "missing" means "not known to this index", not "not present on the
+// cluster". Returns every digest when no DigestIndex is configured.
+func (c *Client) FindMissingBlobs(ctx context.Context, digests []Digest) ([]Digest, error) {
+	if c.config.DigestIndex == nil {
+		return digests, nil
+	}
+	missing := make([]Digest, 0, len(digests))
+	for _, d := range digests {
+		if _, ok := c.config.DigestIndex.Lookup(d); !ok {
+			missing = append(missing, d)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,151 @@
+// Package fdfs DNS resolver cache.
+// This file wraps net.DefaultResolver.LookupHost with a package-level TTL
+// cache so that dialing a tracker or storage server addressed by hostname
+// (common in containerized/K8s deployments) doesn't pay a DNS round trip on
+// every new connection, and doesn't fail outright on a transient resolver
+// hiccup as long as a previous lookup is still within its TTL.
+package fdfs
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// defaultDNSCacheTTL is how long a successful hostname lookup is cached when
+// ClientConfig.DNSCacheTTL is unset.
+const defaultDNSCacheTTL = 30 * time.Second
+
+// dnsNegativeCacheTTL is how long a failed lookup (e.g. NXDOMAIN) is cached.
+// Kept short relative to the positive TTL so a hostname that starts
+// resolving again isn't masked for long.
+const dnsNegativeCacheTTL = 5 * time.Second
+
+// dnsCacheEntry holds one hostname's cached lookup result.
+type dnsCacheEntry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// dnsResolverCache is a TTL cache in front of net.DefaultResolver.LookupHost,
+// keyed by hostname. It is intentionally a single package-level instance
+// (see globalDNSCache) rather than per-Client: the OS resolver it shields is
+// itself a shared, process-wide resource, and sharing the cache lets
+// multiple Client instances in the same process avoid duplicate lookups.
+type dnsResolverCache struct {
+	mu       sync.Mutex
+	entries  map[string]*dnsCacheEntry
+	refresh  map[string]bool // hostnames with a refresh already in flight
+	ttl      time.Duration
+	negTTL   time.Duration
+	disabled bool
+}
+
+var globalDNSCache = &dnsResolverCache{
+	entries: make(map[string]*dnsCacheEntry),
+	refresh: make(map[string]bool),
+	ttl:     defaultDNSCacheTTL,
+	negTTL:  dnsNegativeCacheTTL,
+}
+
+// configure applies a Client's DNSCacheTTL/DisableDNSCache settings to the
+// shared cache. The last Client constructed in the process wins; this
+// matches the cache's process-wide, package-level scope.
+func (d *dnsResolverCache) configure(ttl time.Duration, disabled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ttl > 0 {
+		d.ttl = ttl
+	}
+	d.disabled = disabled
+}
+
+// purge discards every cached lookup, forcing the next resolveDialAddr call
+// for each hostname to hit the resolver again.
+func (d *dnsResolverCache) purge() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries = make(map[string]*dnsCacheEntry)
+}
+
+// lookupHost returns the cached A/AAAA records for host, refreshing
+// synchronously on a cold cache and asynchronously when a cached entry has
+// expired (serving the stale entry to this call while the refresh runs).
+func (d *dnsResolverCache) lookupHost(host string) ([]string, error) {
+	d.mu.Lock()
+	entry, ok := d.entries[host]
+	disabled := d.disabled
+	d.mu.Unlock()
+
+	if disabled {
+		return net.DefaultResolver.LookupHost(context.Background(), host)
+	}
+
+	if ok {
+		if time.Now().Before(entry.expires) {
+			return entry.addrs, entry.err
+		}
+
+		d.mu.Lock()
+		alreadyRefreshing := d.refresh[host]
+		if !alreadyRefreshing {
+			d.refresh[host] = true
+		}
+		d.mu.Unlock()
+
+		if !alreadyRefreshing {
+			go d.refreshHost(host)
+		}
+
+		// Serve the stale-but-not-yet-replaced entry rather than blocking
+		// this call on a fresh lookup.
+		return entry.addrs, entry.err
+	}
+
+	return d.refreshHost(host)
+}
+
+// refreshHost performs a synchronous lookup, stores the result (positive or
+// negative) with the appropriate TTL, and returns it.
+func (d *dnsResolverCache) refreshHost(host string) ([]string, error) {
+	addrs, err := net.DefaultResolver.LookupHost(context.Background(), host)
+
+	d.mu.Lock()
+	ttl := d.ttl
+	if err != nil {
+		ttl = d.negTTL
+	}
+	d.entries[host] = &dnsCacheEntry{
+		addrs:   addrs,
+		err:     err,
+		expires: time.Now().Add(ttl),
+	}
+	delete(d.refresh, host)
+	d.mu.Unlock()
+
+	return addrs, err
+}
+
+// resolveDialAddr resolves the host half of a "host:port" address through
+// the DNS cache, returning "ip:port" for net.DialTimeout. Addresses whose
+// host is already an IP literal are returned unchanged, and any cache or
+// resolver error falls back to dialing addr as given so a DNS hiccup never
+// turns into a hard failure by itself.
+func resolveDialAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if net.ParseIP(host) != nil {
+		return addr
+	}
+
+	addrs, err := globalDNSCache.lookupHost(host)
+	if err != nil || len(addrs) == 0 {
+		return addr
+	}
+
+	return net.JoinHostPort(addrs[0], port)
+}
@@ -0,0 +1,167 @@
+// Package fdfs structured error codes and call-stack capture.
+// This file adds a stable Code classification and a captured call stack
+// to errors produced by the client, independent of the concrete error type
+// (sentinel, *ProtocolError, *NetworkError, ...), so callers can branch on
+// fdfs.CodeOf(err) without unwrapping to a specific struct, and can log
+// fdfs.StackTrace(err) to see where the failure originated.
+package fdfs
+
+import (
+	"errors"
+	"runtime"
+)
+
+// Code is a stable, stringable classification of an fdfs error, derived
+// from the FastDFS protocol status byte or from the circumstance of a
+// non-protocol failure such as a timeout or an open circuit breaker.
+type Code string
+
+// The set of codes callers can match on with fdfs.CodeOf(err) == fdfs.CodeX.
+// New codes may be added over time; callers should treat an unrecognized
+// Code the same as CodeUnknown.
+const (
+	CodeUnknown          Code = "unknown"
+	CodeTimeout          Code = "timeout"
+	CodeNoStorage        Code = "no_storage"
+	CodeNotFound         Code = "not_found"
+	CodeAlreadyExists    Code = "already_exists"
+	CodeInvalidArgument  Code = "invalid_argument"
+	CodeChecksumMismatch Code = "checksum_mismatch"
+	CodeQuotaExceeded    Code = "quota_exceeded"
+	CodeAuthFailed       Code = "auth_failed"
+	CodeCircuitOpen      Code = "circuit_open"
+	CodeUnavailable      Code = "unavailable"
+)
+
+// codedError annotates an underlying error with the operation that
+// surfaced it and a call stack captured at that point. It unwraps to the
+// original error so errors.Is/errors.As against sentinels and concrete
+// error types keep working through Wrap/WrapIf.
+type codedError struct {
+	op    string
+	code  Code
+	err   error
+	stack []uintptr
+}
+
+func (e *codedError) Error() string {
+	if e.op == "" {
+		return e.err.Error()
+	}
+	return e.op + ": " + e.err.Error()
+}
+
+func (e *codedError) Unwrap() error { return e.err }
+
+// captureStack records up to 32 call frames, skipping skip frames (use 3
+// from a function that calls captureStack directly, to skip
+// runtime.Callers, captureStack, and its immediate caller).
+func captureStack(skip int) []uintptr {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip, pcs)
+	return pcs[:n]
+}
+
+// Wrap annotates err with op and a freshly captured call stack, for use at
+// the point a low-level error first surfaces (e.g. a failed RPC). Returns
+// nil if err is nil. The resulting error's Code() is classified from err
+// at wrap time.
+func Wrap(err error, op string) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{op: op, code: classify(err), err: err, stack: captureStack(3)}
+}
+
+// WrapIf is Wrap under a name that reads naturally at the end of a
+// function returning an error that may or may not be nil:
+//
+//	return fdfs.WrapIf(err, "uploadBufferInternal")
+func WrapIf(err error, op string) error {
+	return Wrap(err, op)
+}
+
+// StackTrace returns the call frames captured when err (or the nearest
+// ancestor in its error chain) was created via Wrap/WrapIf, or nil if err
+// was never wrapped.
+func StackTrace(err error) []runtime.Frame {
+	var ce *codedError
+	if !errors.As(err, &ce) {
+		return nil
+	}
+	framesIter := runtime.CallersFrames(ce.stack)
+	frames := make([]runtime.Frame, 0, len(ce.stack))
+	for {
+		frame, more := framesIter.Next()
+		frames = append(frames, frame)
+		if !more {
+			break
+		}
+	}
+	return frames
+}
+
+// CodeOf returns the stable Code classification for err, walking the error
+// chain so it works through Wrap, fmt.Errorf("%w", ...), and the client's
+// own sentinel and wrapped error types alike. Returns CodeUnknown for a
+// nil or unrecognized err.
+func CodeOf(err error) Code {
+	if err == nil {
+		return CodeUnknown
+	}
+	return classify(err)
+}
+
+// classify maps err to a stable Code by walking the chain of sentinel
+// errors and protocol status codes the client can produce.
+func classify(err error) Code {
+	var ce *codedError
+	if errors.As(err, &ce) && ce.code != "" {
+		return ce.code
+	}
+
+	switch {
+	case errors.Is(err, ErrFileNotFound):
+		return CodeNotFound
+	case errors.Is(err, ErrFileAlreadyExists):
+		return CodeAlreadyExists
+	case errors.Is(err, ErrInvalidArgument), errors.Is(err, ErrInvalidFileID), errors.Is(err, ErrInvalidMetadata):
+		return CodeInvalidArgument
+	case errors.Is(err, ErrInsufficientSpace):
+		return CodeQuotaExceeded
+	case errors.Is(err, ErrNoStorageServer):
+		return CodeNoStorage
+	case errors.Is(err, ErrConnectionTimeout), errors.Is(err, ErrNetworkTimeout):
+		return CodeTimeout
+	case errors.Is(err, ErrCircuitOpen):
+		return CodeCircuitOpen
+	case errors.Is(err, ErrChecksumMismatch):
+		return CodeChecksumMismatch
+	case errors.Is(err, ErrStorageServerOffline), errors.Is(err, ErrTrackerServerOffline), errors.Is(err, ErrClientClosed):
+		return CodeUnavailable
+	}
+
+	var protoErr *ProtocolError
+	if errors.As(err, &protoErr) {
+		return protocolCodeOf(protoErr.Code)
+	}
+
+	return CodeUnknown
+}
+
+// protocolCodeOf maps a raw FastDFS protocol status byte not already
+// translated to a sentinel error in mapStatusToError to a Code.
+func protocolCodeOf(status byte) Code {
+	switch status {
+	case 13:
+		// EACCES: storage rejected the request, typically a bad storage secret key
+		return CodeAuthFailed
+	case 61:
+		// EHOSTDOWN/digest mismatch as reported by storage on corrupted uploads
+		return CodeChecksumMismatch
+	case 28:
+		return CodeQuotaExceeded
+	default:
+		return CodeUnknown
+	}
+}
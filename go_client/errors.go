@@ -52,6 +52,28 @@ var (
 
 	// ErrInvalidArgument indicates an invalid argument was provided
 	ErrInvalidArgument = errors.New("invalid argument")
+
+	// ErrCircuitOpen indicates the circuit breaker for a tracker or storage
+	// endpoint is open and the call was rejected without attempting it
+	ErrCircuitOpen = errors.New("circuit breaker is open for endpoint")
+
+	// ErrChecksumMismatch indicates downloaded file data did not match the
+	// CRC32 the tracker/storage servers recorded for the file
+	ErrChecksumMismatch = errors.New("downloaded data failed checksum verification")
+
+	// ErrPoolRateLimited indicates ConnectionPool.Get rejected a new dial
+	// because ConnectionPoolOptions.MaxConnsPerSecond was exceeded for that
+	// server address
+	ErrPoolRateLimited = errors.New("connection pool dial rate limit exceeded")
+
+	// ErrOperationRateLimited indicates a call returned early because ctx
+	// was done while it was waiting for ClientConfig's UploadQPS/
+	// DownloadQPS/UploadBytesPerSec/DownloadBytesPerSec/MaxInFlight limits
+	// to allow it to proceed. Wraps the ctx error, so errors.Is(err,
+	// context.DeadlineExceeded) etc. still works; the sentinel lets callers
+	// tell a rate-limit wait apart from the same ctx error occurring during
+	// the network round trip itself.
+	ErrOperationRateLimited = errors.New("operation rate limit wait canceled")
 )
 
 // ProtocolError represents a protocol-level error returned by the FastDFS server.
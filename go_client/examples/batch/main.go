@@ -347,45 +347,28 @@ func main() {
 
 	fmt.Printf("   Processing batch of %d operations with progress tracking...\n", progressBatchSize)
 
-	var progressWg sync.WaitGroup
-	progressChan := make(chan int, progressBatchSize)
-	var progressFiles []string
-	var progressMutex sync.Mutex
-	var progressCompleted int64
+	progressReporter := fdfs.NewTerminalProgressReporter(os.Stdout)
+	progressReporter.SetPrefix("   ")
+	progressPool := fdfs.NewPool(client, 8)
+	progressPool.Total = progressBatchSize
+	progressPool.Reporters = []fdfs.ProgressReporter{progressReporter}
 
-	progressStart := time.Now()
+	progressJobs := make(chan fdfs.UploadJob, progressBatchSize)
 	for i := 0; i < progressBatchSize; i++ {
-		progressWg.Add(1)
-		go func(index int) {
-			defer progressWg.Done()
-
-			data := createTestData(2 * 1024)
-			fileID, err := client.UploadBuffer(ctx, data, "bin", nil)
-			if err == nil {
-				progressMutex.Lock()
-				progressFiles = append(progressFiles, fileID)
-				progressMutex.Unlock()
-			}
-
-			atomic.AddInt64(&progressCompleted, 1)
-			progressChan <- int(atomic.LoadInt64(&progressCompleted))
-		}(i)
+		progressJobs <- fdfs.UploadJob{Data: createTestData(2 * 1024), FileExtName: "bin"}
 	}
+	close(progressJobs)
 
-	// Monitor progress
-	go func() {
-		progressWg.Wait()
-		close(progressChan)
-	}()
-
-	// Display progress
-	for completed := range progressChan {
-		progress := float64(completed) / float64(progressBatchSize) * 100.0
-		fmt.Printf("\r   Progress: %d/%d (%.1f%%)", completed, progressBatchSize, progress)
+	progressStart := time.Now()
+	var progressFiles []string
+	for result := range progressPool.UploadAll(ctx, progressJobs) {
+		if result.Err == nil {
+			progressFiles = append(progressFiles, result.FileID)
+		}
 	}
 	fmt.Println()
-
 	progressDuration := time.Since(progressStart)
+
 	fmt.Printf("   ✓ Completed in %v\n", progressDuration)
 	fmt.Printf("   → Successful: %d/%d\n", len(progressFiles), progressBatchSize)
 
@@ -12,11 +12,16 @@ import (
 	fdfs "github.com/happyfish100/fastdfs/go_client"
 )
 
-// logError logs an error with context
+// logError logs an error with context, including its stable fdfs.Code and
+// the call stack captured at the point the client wrapped it, if any.
 func logError(operation string, err error) {
 	log.Printf("[ERROR] Operation: %s", operation)
 	log.Printf("        Error: %v", err)
+	log.Printf("        Code: %s", fdfs.CodeOf(err))
 	log.Printf("        Time: %v", time.Now().Format(time.RFC3339))
+	for _, frame := range fdfs.StackTrace(err) {
+		log.Printf("        at %s (%s:%d)", frame.Function, frame.File, frame.Line)
+	}
 }
 
 // retryWithBackoff retries an operation with exponential backoff
@@ -90,8 +95,8 @@ func main() {
 	fmt.Println()
 
 	config := &fdfs.ClientConfig{
-		TrackerAddrs:  []string{trackerAddr},
-		MaxConns:      10,
+		TrackerAddrs:   []string{trackerAddr},
+		MaxConns:       10,
 		ConnectTimeout: 5 * time.Second,
 		NetworkTimeout: 30 * time.Second,
 	}
@@ -180,6 +185,24 @@ func main() {
 	}
 	fmt.Println()
 
+	// Example 2.2: Branch on the stable fdfs.Code instead of unwrapping a
+	// specific concrete error type
+	fmt.Println("   Example 2.2: Branching on fdfs.Code")
+	codeTestData := []byte("Error code test")
+	if _, err := client.UploadBuffer(ctx, codeTestData, "txt", nil); err != nil {
+		switch fdfs.CodeOf(err) {
+		case fdfs.CodeQuotaExceeded:
+			fmt.Println("     → Storage quota exceeded, stop uploading")
+		case fdfs.CodeNoStorage, fdfs.CodeUnavailable:
+			fmt.Println("     → No healthy storage server, retry later")
+		case fdfs.CodeTimeout:
+			fmt.Println("     → Timed out, safe to retry")
+		default:
+			fmt.Printf("     → %s: %v\n", fdfs.CodeOf(err), err)
+		}
+	}
+	fmt.Println()
+
 	// ====================================================================
 	// EXAMPLE 3: Retry Strategies
 	// ====================================================================
@@ -389,7 +412,7 @@ func main() {
 	// Example 6.2: Cancellation context
 	fmt.Println("   Example 6.2: Cancellation context")
 	cancelCtx, cancelFunc := context.WithCancel(ctx)
-	
+
 	// Cancel after a short delay
 	go func() {
 		time.Sleep(500 * time.Millisecond)
@@ -535,4 +558,3 @@ func main() {
 	fmt.Println("  • Log errors with context for debugging")
 	fmt.Println("  • Unwrap errors to get underlying error details")
 }
-
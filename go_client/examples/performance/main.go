@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"math/bits"
+	"math/rand"
 	"os"
 	"runtime"
 	"sort"
@@ -15,104 +18,301 @@ import (
 	fdfs "github.com/happyfish100/fastdfs/go_client"
 )
 
-// PerformanceMetrics tracks performance statistics
+// numLatencyBuckets sizes the base-2 latency histogram from 1us up to the
+// next power of two past 60s, so the bucket array can be a compile-time
+// constant.
+const numLatencyBuckets = 27
+
+// reservoirCapacity is the total number of latency samples Quantile has to
+// work with across all shards, keeping memory bounded regardless of how
+// many operations RecordOperation sees over a long run.
+const reservoirCapacity = 10240
+
+// reservoirShardCount is how many independent reservoirs RecordOperation
+// spreads samples across (an approximation of per-P sharding that doesn't
+// require a constructor, so &PerformanceMetrics{} zero values keep
+// working): each shard has its own mutex, so concurrent RecordOperation
+// calls aren't all serialized behind a single reservoir lock.
+const reservoirShardCount = 32
+
+// reservoirShard is one shard of the overall reservoir, implementing
+// Vitter's Algorithm R: the first samplesPerShard samples offered are kept
+// outright; after that, the i-th sample (counting from 1) replaces a uniformly
+// random existing slot with probability samplesPerShard/i, yielding a
+// uniform random sample of everything the shard has seen without storing
+// all of it.
+type reservoirShard struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	seen    int64
+}
+
+func (s *reservoirShard) record(d time.Duration) {
+	const samplesPerShard = reservoirCapacity / reservoirShardCount
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen++
+	if len(s.samples) < samplesPerShard {
+		s.samples = append(s.samples, d)
+		return
+	}
+	if idx := rand.Int63n(s.seen); idx < samplesPerShard {
+		s.samples[idx] = d
+	}
+}
+
+func (s *reservoirShard) snapshot() []time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]time.Duration, len(s.samples))
+	copy(out, s.samples)
+	return out
+}
+
+// latencyBucket returns the histogram bucket index for d: bucket i covers
+// [2^i, 2^(i+1)) microseconds, clamped to numLatencyBuckets-1 for anything
+// at or past the 60s+ ceiling.
+func latencyBucket(d time.Duration) int {
+	us := int64(d / time.Microsecond)
+	if us < 1 {
+		return 0
+	}
+	if idx := bits.Len64(uint64(us)) - 1; idx < numLatencyBuckets {
+		return idx
+	}
+	return numLatencyBuckets - 1
+}
+
+// durationAddr lets TotalTime/MinTime/MaxTime stay time.Duration-typed
+// (so existing call sites reading pm.TotalTime.Seconds() etc. are
+// unaffected) while still being updatable with sync/atomic, which only
+// operates on *int64: the conversion is valid because time.Duration's
+// underlying type is int64.
+func durationAddr(d *time.Duration) *int64 {
+	return (*int64)(d)
+}
+
+func atomicMinDuration(addr *time.Duration, v time.Duration) {
+	if v == 0 {
+		return
+	}
+	p := durationAddr(addr)
+	for {
+		old := time.Duration(atomic.LoadInt64(p))
+		if old != 0 && old <= v {
+			return
+		}
+		if atomic.CompareAndSwapInt64(p, int64(old), int64(v)) {
+			return
+		}
+	}
+}
+
+func atomicMaxDuration(addr *time.Duration, v time.Duration) {
+	p := durationAddr(addr)
+	for {
+		old := time.Duration(atomic.LoadInt64(p))
+		if old >= v {
+			return
+		}
+		if atomic.CompareAndSwapInt64(p, int64(old), int64(v)) {
+			return
+		}
+	}
+}
+
+// PerformanceMetrics tracks performance statistics for a batch of
+// operations. The hot-path counters (OperationsCount, SuccessfulOperations,
+// FailedOperations, BytesTransferred, TotalTime, MinTime, MaxTime, and the
+// latency histogram buckets) are all sync/atomic fields rather than
+// mutex-guarded: a single global mutex here would itself distort the
+// latency measurements it's trying to collect under concurrent load. Tail
+// latency (see Quantile) comes from a sharded reservoir sample instead of
+// keeping every observation, so memory stays bounded over a long run.
 type PerformanceMetrics struct {
-	mu                    sync.Mutex
-	OperationsCount       int64
-	SuccessfulOperations  int64
-	FailedOperations      int64
-	TotalTime             time.Duration
-	MinTime               time.Duration
-	MaxTime               time.Duration
-	OperationTimes        []time.Duration
-	BytesTransferred      int64
+	OperationsCount      int64
+	SuccessfulOperations int64
+	FailedOperations     int64
+	TotalTime            time.Duration
+	MinTime              time.Duration
+	MaxTime              time.Duration
+	BytesTransferred     int64
+
+	buckets      [numLatencyBuckets]int64
+	shards       [reservoirShardCount]reservoirShard
+	shardCounter int64
 }
 
 // RecordOperation records a single operation's metrics
 func (pm *PerformanceMetrics) RecordOperation(success bool, duration time.Duration, bytes int64) {
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
-
 	atomic.AddInt64(&pm.OperationsCount, 1)
-	if success {
-		atomic.AddInt64(&pm.SuccessfulOperations, 1)
-		pm.TotalTime += duration
-		pm.OperationTimes = append(pm.OperationTimes, duration)
-		if duration < pm.MinTime || pm.MinTime == 0 {
-			pm.MinTime = duration
-		}
-		if duration > pm.MaxTime {
-			pm.MaxTime = duration
-		}
-		atomic.AddInt64(&pm.BytesTransferred, bytes)
-	} else {
+	if !success {
 		atomic.AddInt64(&pm.FailedOperations, 1)
+		return
+	}
+
+	atomic.AddInt64(&pm.SuccessfulOperations, 1)
+	atomic.AddInt64(durationAddr(&pm.TotalTime), int64(duration))
+	atomic.AddInt64(&pm.BytesTransferred, bytes)
+	atomicMinDuration(&pm.MinTime, duration)
+	atomicMaxDuration(&pm.MaxTime, duration)
+
+	atomic.AddInt64(&pm.buckets[latencyBucket(duration)], 1)
+
+	idx := atomic.AddInt64(&pm.shardCounter, 1) % reservoirShardCount
+	pm.shards[idx].record(duration)
+}
+
+// Quantile estimates the p-th percentile latency (e.g. 0.95 for P95) among
+// successful operations, computed from the merged reservoir sample rather
+// than the full, unbounded set of observations. This is an estimate, not
+// an exact value: like any bounded-sample percentile estimator, a
+// reservoirCapacity-sized sample drawn from many more operations carries
+// sampling error, tightest near the median and loosest in the extreme
+// tail.
+func (pm *PerformanceMetrics) Quantile(p float64) time.Duration {
+	var all []time.Duration
+	for i := range pm.shards {
+		all = append(all, pm.shards[i].snapshot()...)
+	}
+	if len(all) == 0 {
+		return 0
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	idx := int(float64(len(all)) * p)
+	if idx >= len(all) {
+		idx = len(all) - 1
+	}
+	return all[idx]
+}
+
+// Merge folds other's counters and samples into pm, so a caller that
+// accumulates a PerformanceMetrics per worker goroutine can combine them
+// once at the end of a run instead of contending on one shared instance
+// for every operation. The merged reservoir is only an approximation of a
+// uniform sample over the combined stream, since each side's reservoir has
+// already discarded the per-item weighting a statistically exact merge
+// would need — an acceptable tradeoff for the tail-latency estimates
+// Quantile provides.
+func (pm *PerformanceMetrics) Merge(other *PerformanceMetrics) {
+	atomic.AddInt64(&pm.OperationsCount, atomic.LoadInt64(&other.OperationsCount))
+	atomic.AddInt64(&pm.SuccessfulOperations, atomic.LoadInt64(&other.SuccessfulOperations))
+	atomic.AddInt64(&pm.FailedOperations, atomic.LoadInt64(&other.FailedOperations))
+	atomic.AddInt64(durationAddr(&pm.TotalTime), atomic.LoadInt64(durationAddr(&other.TotalTime)))
+	atomic.AddInt64(&pm.BytesTransferred, atomic.LoadInt64(&other.BytesTransferred))
+	atomicMinDuration(&pm.MinTime, time.Duration(atomic.LoadInt64(durationAddr(&other.MinTime))))
+	atomicMaxDuration(&pm.MaxTime, time.Duration(atomic.LoadInt64(durationAddr(&other.MaxTime))))
+
+	for i := range pm.buckets {
+		atomic.AddInt64(&pm.buckets[i], atomic.LoadInt64(&other.buckets[i]))
+	}
+
+	for i := range other.shards {
+		for _, d := range other.shards[i].snapshot() {
+			idx := atomic.AddInt64(&pm.shardCounter, 1) % reservoirShardCount
+			pm.shards[idx].record(d)
+		}
 	}
 }
 
 // Print prints formatted performance metrics
 func (pm *PerformanceMetrics) Print(title string) {
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
+	opsCount := atomic.LoadInt64(&pm.OperationsCount)
+	successOps := atomic.LoadInt64(&pm.SuccessfulOperations)
+	failedOps := atomic.LoadInt64(&pm.FailedOperations)
+	totalTime := time.Duration(atomic.LoadInt64(durationAddr(&pm.TotalTime)))
+	minTime := time.Duration(atomic.LoadInt64(durationAddr(&pm.MinTime)))
+	maxTime := time.Duration(atomic.LoadInt64(durationAddr(&pm.MaxTime)))
+	bytesTransferred := atomic.LoadInt64(&pm.BytesTransferred)
 
 	fmt.Printf("   %s:\n", title)
-	fmt.Printf("     Operations: %d (Success: %d, Failed: %d)\n",
-		pm.OperationsCount, pm.SuccessfulOperations, pm.FailedOperations)
+	fmt.Printf("     Operations: %d (Success: %d, Failed: %d)\n", opsCount, successOps, failedOps)
 
-	if pm.SuccessfulOperations > 0 {
-		avgTime := pm.TotalTime / time.Duration(pm.SuccessfulOperations)
-		fmt.Printf("     Total Time: %v\n", pm.TotalTime)
+	if successOps > 0 {
+		avgTime := totalTime / time.Duration(successOps)
+		fmt.Printf("     Total Time: %v\n", totalTime)
 		fmt.Printf("     Average Time: %v\n", avgTime)
-		fmt.Printf("     Min Time: %v\n", pm.MinTime)
-		fmt.Printf("     Max Time: %v\n", pm.MaxTime)
-
-		if len(pm.OperationTimes) > 0 {
-			sorted := make([]time.Duration, len(pm.OperationTimes))
-			copy(sorted, pm.OperationTimes)
-			sort.Slice(sorted, func(i, j int) bool {
-				return sorted[i] < sorted[j]
-			})
-
-			p50Idx := len(sorted) * 50 / 100
-			p95Idx := len(sorted) * 95 / 100
-			p99Idx := len(sorted) * 99 / 100
-
-			if p50Idx < len(sorted) {
-				fmt.Printf("     P50 (Median): %v\n", sorted[p50Idx])
-			}
-			if p95Idx < len(sorted) {
-				fmt.Printf("     P95: %v\n", sorted[p95Idx])
-			}
-			if p99Idx < len(sorted) {
-				fmt.Printf("     P99: %v\n", sorted[p99Idx])
-			}
-		}
+		fmt.Printf("     Min Time: %v\n", minTime)
+		fmt.Printf("     Max Time: %v\n", maxTime)
+
+		fmt.Printf("     P50 (Median): %v\n", pm.Quantile(0.50))
+		fmt.Printf("     P95: %v\n", pm.Quantile(0.95))
+		fmt.Printf("     P99: %v\n", pm.Quantile(0.99))
 
-		if pm.TotalTime > 0 {
-			opsPerSec := float64(pm.SuccessfulOperations) / pm.TotalTime.Seconds()
+		if totalTime > 0 {
+			opsPerSec := float64(successOps) / totalTime.Seconds()
 			fmt.Printf("     Throughput: %.2f ops/sec\n", opsPerSec)
 		}
 
-		if pm.BytesTransferred > 0 && pm.TotalTime > 0 {
-			mbps := float64(pm.BytesTransferred) / 1024.0 / 1024.0 / pm.TotalTime.Seconds()
+		if bytesTransferred > 0 && totalTime > 0 {
+			mbps := float64(bytesTransferred) / 1024.0 / 1024.0 / totalTime.Seconds()
 			fmt.Printf("     Data Rate: %.2f MB/s\n", mbps)
 		}
 	}
 }
 
+// performanceMetricsJSON is the MarshalJSON wire format for
+// PerformanceMetrics: the same figures Print prints, as machine-readable
+// fields instead of a formatted report.
+type performanceMetricsJSON struct {
+	OperationsCount      int64         `json:"operations_count"`
+	SuccessfulOperations int64         `json:"successful_operations"`
+	FailedOperations     int64         `json:"failed_operations"`
+	TotalTime            time.Duration `json:"total_time_ns"`
+	MinTime              time.Duration `json:"min_time_ns"`
+	MaxTime              time.Duration `json:"max_time_ns"`
+	P50                  time.Duration `json:"p50_ns"`
+	P95                  time.Duration `json:"p95_ns"`
+	P99                  time.Duration `json:"p99_ns"`
+	ThroughputOpsPerSec  float64       `json:"throughput_ops_per_sec"`
+	BytesTransferred     int64         `json:"bytes_transferred"`
+}
+
+// MarshalJSON implements json.Marshaler, so a PerformanceMetrics can be
+// written to a result file (e.g. for a later run to diff against) instead
+// of only printed via Print.
+func (pm *PerformanceMetrics) MarshalJSON() ([]byte, error) {
+	successOps := atomic.LoadInt64(&pm.SuccessfulOperations)
+	totalTime := time.Duration(atomic.LoadInt64(durationAddr(&pm.TotalTime)))
+	var opsPerSec float64
+	if totalTime > 0 {
+		opsPerSec = float64(successOps) / totalTime.Seconds()
+	}
+
+	return json.Marshal(performanceMetricsJSON{
+		OperationsCount:      atomic.LoadInt64(&pm.OperationsCount),
+		SuccessfulOperations: successOps,
+		FailedOperations:     atomic.LoadInt64(&pm.FailedOperations),
+		TotalTime:            totalTime,
+		MinTime:              time.Duration(atomic.LoadInt64(durationAddr(&pm.MinTime))),
+		MaxTime:              time.Duration(atomic.LoadInt64(durationAddr(&pm.MaxTime))),
+		P50:                  pm.Quantile(0.50),
+		P95:                  pm.Quantile(0.95),
+		P99:                  pm.Quantile(0.99),
+		ThroughputOpsPerSec:  opsPerSec,
+		BytesTransferred:     atomic.LoadInt64(&pm.BytesTransferred),
+	})
+}
+
 // Reset resets all metrics
 func (pm *PerformanceMetrics) Reset() {
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
-
-	pm.OperationsCount = 0
-	pm.SuccessfulOperations = 0
-	pm.FailedOperations = 0
-	pm.TotalTime = 0
-	pm.MinTime = 0
-	pm.MaxTime = 0
-	pm.OperationTimes = pm.OperationTimes[:0]
-	pm.BytesTransferred = 0
+	atomic.StoreInt64(&pm.OperationsCount, 0)
+	atomic.StoreInt64(&pm.SuccessfulOperations, 0)
+	atomic.StoreInt64(&pm.FailedOperations, 0)
+	atomic.StoreInt64(durationAddr(&pm.TotalTime), 0)
+	atomic.StoreInt64(durationAddr(&pm.MinTime), 0)
+	atomic.StoreInt64(durationAddr(&pm.MaxTime), 0)
+	atomic.StoreInt64(&pm.BytesTransferred, 0)
+	for i := range pm.buckets {
+		atomic.StoreInt64(&pm.buckets[i], 0)
+	}
+	for i := range pm.shards {
+		pm.shards[i].mu.Lock()
+		pm.shards[i].samples = pm.shards[i].samples[:0]
+		pm.shards[i].seen = 0
+		pm.shards[i].mu.Unlock()
+	}
 }
 
 // MemoryStats tracks memory usage
@@ -200,12 +400,12 @@ func main() {
 		fmt.Printf("   Testing with max_conns = %d...\n", poolSize)
 
 		config := &fdfs.ClientConfig{
-			TrackerAddrs:  []string{trackerAddr},
-			MaxConns:      poolSize,
+			TrackerAddrs:   []string{trackerAddr},
+			MaxConns:       poolSize,
 			ConnectTimeout: 5 * time.Second,
 			NetworkTimeout: 30 * time.Second,
-			IdleTimeout:   60 * time.Second,
-			EnablePool:    true,
+			IdleTimeout:    60 * time.Second,
+			EnablePool:     true,
 		}
 
 		client, err := fdfs.NewClient(config)
@@ -273,8 +473,8 @@ func main() {
 	fmt.Println()
 
 	config := &fdfs.ClientConfig{
-		TrackerAddrs:  []string{trackerAddr},
-		MaxConns:      20,
+		TrackerAddrs:   []string{trackerAddr},
+		MaxConns:       20,
 		ConnectTimeout: 5 * time.Second,
 		NetworkTimeout: 30 * time.Second,
 	}
@@ -454,8 +654,8 @@ func main() {
 	fmt.Println()
 
 	metricsConfig := &fdfs.ClientConfig{
-		TrackerAddrs:  []string{trackerAddr},
-		MaxConns:      15,
+		TrackerAddrs:   []string{trackerAddr},
+		MaxConns:       15,
 		ConnectTimeout: 5 * time.Second,
 		NetworkTimeout: 30 * time.Second,
 	}
@@ -492,6 +692,9 @@ func main() {
 	}
 
 	detailedMetrics.Print("Detailed Performance Metrics")
+	if jsonBytes, err := json.Marshal(detailedMetrics); err == nil {
+		fmt.Printf("     JSON: %s\n", jsonBytes)
+	}
 	fmt.Println()
 
 	// ====================================================================
@@ -503,8 +706,8 @@ func main() {
 	fmt.Println()
 
 	sizeConfig := &fdfs.ClientConfig{
-		TrackerAddrs:  []string{trackerAddr},
-		MaxConns:      10,
+		TrackerAddrs:   []string{trackerAddr},
+		MaxConns:       10,
 		ConnectTimeout: 5 * time.Second,
 		NetworkTimeout: 30 * time.Second,
 	}
@@ -565,11 +768,11 @@ func main() {
 		fmt.Printf("   Testing with retry_count = %d...\n", retryCount)
 
 		retryConfig := &fdfs.ClientConfig{
-			TrackerAddrs:  []string{trackerAddr},
-			MaxConns:      10,
+			TrackerAddrs:   []string{trackerAddr},
+			MaxConns:       10,
 			ConnectTimeout: 5 * time.Second,
 			NetworkTimeout: 30 * time.Second,
-			RetryCount:    retryCount,
+			RetryCount:     retryCount,
 		}
 
 		retryClient, err := fdfs.NewClient(retryConfig)
@@ -634,4 +837,3 @@ func main() {
 	fmt.Println("  • Test different configurations to find optimal settings")
 	fmt.Println("  • Balance retry count with performance requirements")
 }
-
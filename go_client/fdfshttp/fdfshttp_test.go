@@ -0,0 +1,205 @@
+package fdfshttp
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	fdfs "github.com/happyfish100/fastdfs/go_client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSeek(t *testing.T) {
+	f := &file{info: &fdfs.FileInfo{FileSize: 100}}
+
+	off, err := f.Seek(10, io.SeekStart)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 10, off)
+
+	off, err = f.Seek(5, io.SeekCurrent)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 15, off)
+
+	off, err = f.Seek(-10, io.SeekEnd)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 90, off)
+
+	_, err = f.Seek(-1, io.SeekStart)
+	assert.ErrorIs(t, err, os.ErrInvalid)
+
+	_, err = f.Seek(0, 99)
+	assert.ErrorIs(t, err, os.ErrInvalid)
+}
+
+func TestFileInfoAdapter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	fi := fileInfo{name: "foo.jpg", info: &fdfs.FileInfo{FileSize: 42, CreateTime: now}}
+
+	assert.Equal(t, "foo.jpg", fi.Name())
+	assert.EqualValues(t, 42, fi.Size())
+	assert.False(t, fi.IsDir())
+	assert.Equal(t, now, fi.ModTime())
+}
+
+func TestMapOpenErr(t *testing.T) {
+	assert.ErrorIs(t, mapOpenErr(fdfs.ErrFileNotFound), os.ErrNotExist)
+	assert.Equal(t, fdfs.ErrClientClosed, mapOpenErr(fdfs.ErrClientClosed))
+}
+
+// fakeDownloader is an in-memory rangeDownloader standing in for a live
+// tracker/storage pair, so file (and http.ServeContent/http.FileServer
+// driven through it) can be tested end-to-end without a network.
+type fakeDownloader struct {
+	data []byte
+}
+
+func (f *fakeDownloader) DownloadFileRange(ctx context.Context, fileID string, offset, length int64) ([]byte, error) {
+	if offset < 0 || offset > int64(len(f.data)) {
+		return nil, fdfs.ErrInvalidArgument
+	}
+	end := offset + length
+	if end > int64(len(f.data)) {
+		end = int64(len(f.data))
+	}
+	return f.data[offset:end], nil
+}
+
+// fakeFileSystem serves a single fixed file backed by a fakeDownloader,
+// bypassing FileSystem.Open's GetFileInfo call so tests can drive
+// http.ServeContent/http.FileServer through the real file/fileInfo types
+// without a tracker/storage pair.
+type fakeFileSystem struct {
+	data []byte
+}
+
+func (fsys fakeFileSystem) Open(name string) (http.File, error) {
+	return &file{
+		ctx:    context.Background(),
+		client: &fakeDownloader{data: fsys.data},
+		fileID: "group1" + name,
+		name:   name,
+		info:   &fdfs.FileInfo{FileSize: int64(len(fsys.data)), CreateTime: time.Unix(1700000000, 0)},
+	}, nil
+}
+
+// ServeFileRangeTests covers the RFC 7233 behaviors http.FileServer derives
+// from file/fileInfo: a plain GET, single/suffix/open-ended single ranges,
+// and an unsatisfiable range.
+func TestServeFileRangeTests(t *testing.T) {
+	content := []byte("0123456789abcdefghijklmnopqrstuvwxyz") // 36 bytes
+
+	tests := []struct {
+		name             string
+		rangeHeader      string
+		wantStatus       int
+		wantBody         string
+		wantContentRange string
+	}{
+		{
+			name:       "no range returns the whole file",
+			wantStatus: http.StatusOK,
+			wantBody:   string(content),
+		},
+		{
+			name:             "closed single range",
+			rangeHeader:      "bytes=0-9",
+			wantStatus:       http.StatusPartialContent,
+			wantBody:         string(content[0:10]),
+			wantContentRange: "bytes 0-9/36",
+		},
+		{
+			name:             "suffix range",
+			rangeHeader:      "bytes=-10",
+			wantStatus:       http.StatusPartialContent,
+			wantBody:         string(content[26:36]),
+			wantContentRange: "bytes 26-35/36",
+		},
+		{
+			name:             "open-ended range",
+			rangeHeader:      "bytes=30-",
+			wantStatus:       http.StatusPartialContent,
+			wantBody:         string(content[30:36]),
+			wantContentRange: "bytes 30-35/36",
+		},
+		{
+			name:             "unsatisfiable range",
+			rangeHeader:      "bytes=100-200",
+			wantStatus:       http.StatusRequestedRangeNotSatisfiable,
+			wantContentRange: "bytes */36",
+		},
+	}
+
+	srv := httptest.NewServer(http.FileServer(fakeFileSystem{data: content}))
+	defer srv.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/file.txt", nil)
+			require.NoError(t, err)
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantContentRange != "" {
+				assert.Equal(t, tt.wantContentRange, resp.Header.Get("Content-Range"))
+			}
+			if tt.wantStatus != http.StatusRequestedRangeNotSatisfiable {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantBody, string(body))
+			}
+		})
+	}
+}
+
+// TestServeFileMultiRange covers the multipart/byteranges response
+// http.ServeContent produces for a multi-range request.
+func TestServeFileMultiRange(t *testing.T) {
+	content := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+
+	srv := httptest.NewServer(http.FileServer(fakeFileSystem{data: content}))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/file.txt", nil)
+	require.NoError(t, err)
+	req.Header.Set("Range", "bytes=0-3,10-13")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/byteranges", mediaType)
+
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	var parts [][]byte
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(part)
+		require.NoError(t, err)
+		parts = append(parts, data)
+	}
+
+	require.Len(t, parts, 2)
+	assert.Equal(t, content[0:4], parts[0])
+	assert.Equal(t, content[10:14], parts[1])
+}
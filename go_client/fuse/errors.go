@@ -0,0 +1,33 @@
+package fuse
+
+import (
+	"errors"
+	"syscall"
+
+	"bazil.org/fuse"
+
+	fdfs "github.com/happyfish100/fastdfs/go_client"
+)
+
+// mapError translates the fdfs sentinel errors into the fuse.Errno values the
+// kernel expects, falling back to EIO for anything unrecognized.
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, fdfs.ErrFileNotFound):
+		return fuse.ENOENT
+	case errors.Is(err, fdfs.ErrInvalidArgument), errors.Is(err, fdfs.ErrInvalidFileID):
+		return fuse.Errno(syscall.EINVAL)
+	case errors.Is(err, fdfs.ErrInsufficientSpace):
+		return fuse.Errno(syscall.ENOSPC)
+	case errors.Is(err, fdfs.ErrFileAlreadyExists):
+		return fuse.EEXIST
+	case errors.Is(err, fdfs.ErrOperationNotSupported):
+		return fuse.ENOSYS
+	default:
+		return fuse.EIO
+	}
+}
@@ -0,0 +1,442 @@
+// Package fuse mounts a FastDFS group as a read/write POSIX filesystem using
+// bazil.org/fuse, so files can be browsed with ordinary tools (ls, cat, cp)
+// instead of writing client code against the fdfs package directly.
+//
+// The inode tree is rooted at the group name, with directories synthesized
+// from the M00/xx/yy path segments every FastDFS file ID carries; the final
+// path component is the leaf (regular) file. Because file IDs are assigned
+// by the storage server at upload time, newly created files only become
+// visible under the name the server returned once Flush has completed the
+// upload; until then the filesystem keeps them in an in-memory pending set.
+package fuse
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+
+	bazilfuse "bazil.org/fuse"
+	"bazil.org/fuse/fs"
+
+	fdfs "github.com/happyfish100/fastdfs/go_client"
+)
+
+// Options configures an FS beyond the client and group it serves.
+type Options struct {
+	// ReadOnly rejects Create, Write, Remove, and Setattr with EROFS,
+	// for mounting a group as a read-only view. Block and attribute
+	// caching are both configured on client directly (see
+	// ClientConfig.CacheBytesPerFile/CacheTotalBytes/CacheBlockSize and
+	// CacheTTL), not here: GetFileInfo and DownloadFileRange already
+	// consult them, so Attr and Read get cached reads for free without
+	// this package needing its own cache.
+	ReadOnly bool
+}
+
+// FS is the root of the mounted filesystem for a single FastDFS group.
+type FS struct {
+	client *fdfs.Client
+	group  string
+	opts   Options
+}
+
+// New returns an FS that exposes groupName through client.
+func New(client *fdfs.Client, groupName string, opts Options) *FS {
+	return &FS{client: client, group: groupName, opts: opts}
+}
+
+// Root implements fs.FS.
+func (f *FS) Root() (fs.Node, error) {
+	return &Dir{fs: f, subpath: ""}, nil
+}
+
+// Dir is a synthetic directory node, one per path segment between the group
+// root and the leaf file (e.g. "", "M00", "M00/00", "M00/00/00").
+type Dir struct {
+	fs      *FS
+	subpath string
+
+	mu      sync.Mutex
+	pending map[string]string // child name -> fileID, populated as writes flush
+}
+
+// depth returns how many path segments below the group root this directory
+// represents. Leaf files live at depth 3 ("M00/xx/yy").
+func (d *Dir) depth() int {
+	if d.subpath == "" {
+		return 0
+	}
+	n := 1
+	for _, c := range d.subpath {
+		if c == '/' {
+			n++
+		}
+	}
+	return n
+}
+
+// Attr implements fs.Node.
+func (d *Dir) Attr(ctx context.Context, attr *bazilfuse.Attr) error {
+	attr.Mode = os.ModeDir | 0755
+	return nil
+}
+
+// Lookup implements fs.NodeStringLookuper.
+func (d *Dir) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	if d.depth() < 3 {
+		child := name
+		if d.subpath != "" {
+			child = d.subpath + "/" + name
+		}
+		return &Dir{fs: d.fs, subpath: child}, nil
+	}
+
+	fileID := d.fileID(name)
+
+	if _, err := d.fs.client.GetFileInfo(ctx, fileID); err != nil {
+		return nil, mapError(err)
+	}
+
+	return &File{fs: d.fs, fileID: fileID, name: name}, nil
+}
+
+// ReadDirAll implements fs.HandleReadDirAller. FastDFS has no directory
+// listing RPC, so only files this process has itself uploaded (and thus
+// knows the name of) are reported; pre-existing files must be Lookup'd by
+// name directly.
+func (d *Dir) ReadDirAll(ctx context.Context) ([]bazilfuse.Dirent, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries := make([]bazilfuse.Dirent, 0, len(d.pending))
+	for name := range d.pending {
+		entries = append(entries, bazilfuse.Dirent{Name: name, Type: bazilfuse.DT_File})
+	}
+	return entries, nil
+}
+
+// Create implements fs.NodeCreater: a new leaf file is buffered in memory
+// and only uploaded (and given a server-assigned file ID) on Flush.
+func (d *Dir) Create(ctx context.Context, req *bazilfuse.CreateRequest, resp *bazilfuse.CreateResponse) (fs.Node, fs.Handle, error) {
+	if d.fs.opts.ReadOnly {
+		return nil, nil, bazilfuse.Errno(syscall.EROFS)
+	}
+	if d.depth() < 3 {
+		return nil, nil, bazilfuse.Errno(syscall.EINVAL)
+	}
+
+	f := &File{fs: d.fs, name: req.Name, dir: d}
+	h := &Handle{file: f}
+	return f, h, nil
+}
+
+// fileID composes the full FastDFS file ID for a leaf named name directly
+// under this (depth-3) directory.
+func (d *Dir) fileID(name string) string {
+	return d.fs.group + "/" + d.subpath + "/" + name
+}
+
+// recordUpload makes a freshly-uploaded file visible under name for
+// subsequent ReadDirAll/Lookup calls within this process.
+func (d *Dir) recordUpload(name, fileID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pending == nil {
+		d.pending = make(map[string]string)
+	}
+	d.pending[name] = fileID
+}
+
+// File is a leaf node backed by a FastDFS file ID.
+type File struct {
+	fs     *FS
+	fileID string // empty until the first Flush for a newly created file
+	name   string
+	dir    *Dir // set only for not-yet-uploaded files created via Create
+}
+
+// Attr implements fs.Node, backed by the typed attribute layer when present
+// and GetFileInfo otherwise.
+func (f *File) Attr(ctx context.Context, attr *bazilfuse.Attr) error {
+	if f.fileID == "" {
+		attr.Mode = 0644
+		return nil
+	}
+
+	if typed, err := f.fs.client.GetFileAttrs(ctx, f.fileID); err == nil {
+		attr.Size = typed.Size
+		attr.Mtime = typed.MTime
+		attr.Atime = typed.ATime
+		attr.Mode = os.FileMode(typed.Perms) | 0
+		if attr.Mode == 0 {
+			attr.Mode = 0644
+		}
+		return nil
+	}
+
+	info, err := f.fs.client.GetFileInfo(ctx, f.fileID)
+	if err != nil {
+		return mapError(err)
+	}
+	attr.Size = uint64(info.FileSize)
+	attr.Mtime = info.CreateTime
+	attr.Mode = 0644
+	return nil
+}
+
+// Setattr implements fs.NodeSetattrer. Only size changes are meaningful here
+// (FastDFS has no notion of mode/uid/gid/times to push back to storage), and
+// map onto TruncateFile.
+func (f *File) Setattr(ctx context.Context, req *bazilfuse.SetattrRequest, resp *bazilfuse.SetattrResponse) error {
+	if f.fs.opts.ReadOnly {
+		return bazilfuse.Errno(syscall.EROFS)
+	}
+	if !req.Valid.Size() {
+		return nil
+	}
+	if f.fileID == "" {
+		return bazilfuse.Errno(syscall.EINVAL)
+	}
+
+	if err := f.fs.client.TruncateFile(ctx, f.fileID, int64(req.Size)); err != nil {
+		return mapError(err)
+	}
+	return nil
+}
+
+// Open implements fs.NodeOpener.
+func (f *File) Open(ctx context.Context, req *bazilfuse.OpenRequest, resp *bazilfuse.OpenResponse) (fs.Handle, error) {
+	return &Handle{file: f}, nil
+}
+
+// Remove implements fs.NodeRemover on the parent directory's behalf; Dir
+// forwards deletions of depth-3 children here.
+func (d *Dir) Remove(ctx context.Context, req *bazilfuse.RemoveRequest) error {
+	if d.fs.opts.ReadOnly {
+		return bazilfuse.Errno(syscall.EROFS)
+	}
+
+	fileID := d.fileID(req.Name)
+	if err := d.fs.client.DeleteFile(ctx, fileID); err != nil {
+		return mapError(err)
+	}
+
+	d.mu.Lock()
+	delete(d.pending, req.Name)
+	d.mu.Unlock()
+	return nil
+}
+
+// Handle is an open file handle: reads stream from storage; writes spool to
+// a temporary file, created lazily on the first Write, and are uploaded as a
+// whole on Flush. Spooling to disk instead of an in-memory buffer keeps an
+// open file's resident memory bounded regardless of how large it is.
+type Handle struct {
+	file *File
+
+	mu   sync.Mutex
+	temp *os.File
+}
+
+// spool lazily creates h.temp on the first Write, seeded with the file's
+// stored content when it already has a file ID, so Flush uploads the whole
+// file rather than only the bytes written through this handle. Caller must
+// hold h.mu.
+func (h *Handle) spool(ctx context.Context) (*os.File, error) {
+	if h.temp != nil {
+		return h.temp, nil
+	}
+	f, err := os.CreateTemp("", "fdfsfuse-*")
+	if err != nil {
+		return nil, err
+	}
+	if h.file.fileID != "" {
+		data, err := h.file.fs.client.DownloadFile(ctx, h.file.fileID)
+		if err == nil {
+			_, err = f.Write(data)
+		}
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return nil, mapError(err)
+		}
+	}
+	h.temp = f
+	return f, nil
+}
+
+// Read implements fs.HandleReader via a streaming ranged download.
+func (h *Handle) Read(ctx context.Context, req *bazilfuse.ReadRequest, resp *bazilfuse.ReadResponse) error {
+	if h.file.fileID == "" {
+		return nil
+	}
+
+	data, err := h.file.fs.client.DownloadFileRange(ctx, h.file.fileID, req.Offset, int64(req.Size))
+	if err != nil {
+		return mapError(err)
+	}
+	resp.Data = data
+	return nil
+}
+
+// Write implements fs.HandleWriter by spooling to a temp file; the upload
+// happens on Flush. os.File.WriteAt extends the file (as a sparse hole) when
+// Offset lands past the current end, the same behavior the old in-memory
+// zero-padding reproduced by hand.
+func (h *Handle) Write(ctx context.Context, req *bazilfuse.WriteRequest, resp *bazilfuse.WriteResponse) error {
+	if h.file.fs.opts.ReadOnly {
+		return bazilfuse.Errno(syscall.EROFS)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	temp, err := h.spool(ctx)
+	if err != nil {
+		return err
+	}
+	n, err := temp.WriteAt(req.Data, req.Offset)
+	if err != nil {
+		return err
+	}
+
+	resp.Size = n
+	return nil
+}
+
+// Flush implements fs.HandleFlusher: the spooled content is uploaded, and
+// for newly created files the resulting file ID becomes the visible leaf.
+//
+// This always re-uploads the whole file rather than calling AppendFile or
+// ModifyFile for in-place edits of an existing file: telling a pure append
+// apart from an overlapping write requires comparing against the bytes
+// already in storage, which this package doesn't do, and there's no mock tracker/storage in this repo to
+// validate that path against. The whole-file re-upload is correct, if not
+// bandwidth-optimal, for every case; the append/modify split is left as a
+// follow-up.
+func (h *Handle) Flush(ctx context.Context, req *bazilfuse.FlushRequest) error {
+	h.mu.Lock()
+	temp := h.temp
+	h.mu.Unlock()
+
+	if temp == nil {
+		return nil
+	}
+
+	if _, err := temp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(temp)
+	if err != nil {
+		return err
+	}
+
+	ext := extOf(h.file.name)
+
+	var fileID string
+	if h.file.fileID == "" {
+		fileID, err = h.file.fs.client.UploadBuffer(ctx, data, ext, nil)
+	} else {
+		err = h.file.fs.client.DeleteFile(ctx, h.file.fileID)
+		if err == nil {
+			fileID, err = h.file.fs.client.UploadBuffer(ctx, data, ext, nil)
+		}
+	}
+	if err != nil {
+		return mapError(err)
+	}
+
+	h.file.fileID = fileID
+	if h.file.dir != nil {
+		h.file.dir.recordUpload(h.file.name, fileID)
+	}
+	return nil
+}
+
+// Release implements fs.HandleReleaser, removing the spooled temp file once
+// the handle is closed.
+func (h *Handle) Release(ctx context.Context, req *bazilfuse.ReleaseRequest) error {
+	h.mu.Lock()
+	temp := h.temp
+	h.temp = nil
+	h.mu.Unlock()
+
+	if temp == nil {
+		return nil
+	}
+	path := temp.Name()
+	temp.Close()
+	os.Remove(path)
+	return nil
+}
+
+// fdfsReservedMetaKey mirrors the unexported fdfs.fileAttrsMetaKey (attrs.go)
+// so Listxattr can exclude the packed typed-attribute encoding from the
+// visible extended attribute namespace.
+const fdfsReservedMetaKey = "_fdfs_attrs"
+
+// Getxattr implements fs.NodeGetxattrer, mapping the extended attribute
+// namespace directly onto FastDFS metadata keys.
+func (f *File) Getxattr(ctx context.Context, req *bazilfuse.GetxattrRequest, resp *bazilfuse.GetxattrResponse) error {
+	if f.fileID == "" {
+		return bazilfuse.ErrNoXattr
+	}
+
+	metadata, err := f.fs.client.GetMetadata(ctx, f.fileID)
+	if err != nil {
+		return mapError(err)
+	}
+
+	value, ok := metadata[req.Name]
+	if !ok {
+		return bazilfuse.ErrNoXattr
+	}
+	resp.Xattr = []byte(value)
+	return nil
+}
+
+// Listxattr implements fs.NodeListxattrer.
+func (f *File) Listxattr(ctx context.Context, req *bazilfuse.ListxattrRequest, resp *bazilfuse.ListxattrResponse) error {
+	if f.fileID == "" {
+		return nil
+	}
+
+	metadata, err := f.fs.client.GetMetadata(ctx, f.fileID)
+	if err != nil {
+		return mapError(err)
+	}
+
+	for key := range metadata {
+		if key == fdfsReservedMetaKey {
+			continue
+		}
+		resp.Append(key)
+	}
+	return nil
+}
+
+// Setxattr implements fs.NodeSetxattrer, storing the attribute as FastDFS
+// metadata merged alongside any existing keys.
+func (f *File) Setxattr(ctx context.Context, req *bazilfuse.SetxattrRequest) error {
+	if f.fileID == "" {
+		return bazilfuse.Errno(syscall.EINVAL)
+	}
+
+	err := f.fs.client.SetMetadata(ctx, f.fileID, map[string]string{req.Name: string(req.Xattr)}, fdfs.MetadataMerge)
+	return mapError(err)
+}
+
+// extOf returns the file extension (without the dot) of name, or "" if none.
+func extOf(name string) string {
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '.' {
+			return name[i+1:]
+		}
+		if name[i] == '/' {
+			break
+		}
+	}
+	return ""
+}
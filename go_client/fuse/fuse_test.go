@@ -0,0 +1,103 @@
+package fuse
+
+import (
+	"context"
+	"syscall"
+	"testing"
+
+	bazilfuse "bazil.org/fuse"
+)
+
+// These tests cover only the logic that doesn't require a tracker/storage
+// connection: the repo has no mock server infrastructure to mount a real FS
+// against (see observer_test.go's fake Observer/Metrics for the same
+// scoping decision elsewhere), so Lookup/Attr/Read/Write/Flush against an
+// actual fdfs.Client are left untested here.
+
+func TestExtOf(t *testing.T) {
+	cases := map[string]string{
+		"photo.jpg":      "jpg",
+		"archive.tar.gz": "gz",
+		"noext":          "",
+		"M00/00/00/a.b":  "b",
+		".hidden":        "hidden",
+		"":               "",
+	}
+	for name, want := range cases {
+		if got := extOf(name); got != want {
+			t.Errorf("extOf(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestDirDepth(t *testing.T) {
+	cases := []struct {
+		subpath string
+		want    int
+	}{
+		{"", 0},
+		{"M00", 1},
+		{"M00/00", 2},
+		{"M00/00/00", 3},
+	}
+	for _, c := range cases {
+		d := &Dir{subpath: c.subpath}
+		if got := d.depth(); got != c.want {
+			t.Errorf("Dir{subpath: %q}.depth() = %d, want %d", c.subpath, got, c.want)
+		}
+	}
+}
+
+func TestDirFileID(t *testing.T) {
+	d := &Dir{fs: &FS{group: "group1"}, subpath: "M00/00/00"}
+	want := "group1/M00/00/00/abc.txt"
+	if got := d.fileID("abc.txt"); got != want {
+		t.Errorf("fileID(%q) = %q, want %q", "abc.txt", got, want)
+	}
+}
+
+func TestReadOnlyRejectsWrites(t *testing.T) {
+	fs := &FS{group: "group1", opts: Options{ReadOnly: true}}
+	dir := &Dir{fs: fs, subpath: "M00/00/00"}
+	file := &File{fs: fs, fileID: "group1/M00/00/00/a.txt", name: "a.txt"}
+	ctx := context.Background()
+
+	wantEROFS := func(t *testing.T, err error) {
+		t.Helper()
+		if !isErrno(err, syscall.EROFS) {
+			t.Fatalf("got %v, want EROFS", err)
+		}
+	}
+
+	if _, _, err := dir.Create(ctx, &bazilfuse.CreateRequest{Name: "b.txt"}, &bazilfuse.CreateResponse{}); err == nil {
+		t.Fatal("Create on a read-only FS: want error, got nil")
+	} else {
+		wantEROFS(t, err)
+	}
+
+	if err := dir.Remove(ctx, &bazilfuse.RemoveRequest{Name: "a.txt"}); err == nil {
+		t.Fatal("Remove on a read-only FS: want error, got nil")
+	} else {
+		wantEROFS(t, err)
+	}
+
+	h := &Handle{file: file}
+	if err := h.Write(ctx, &bazilfuse.WriteRequest{Data: []byte("x")}, &bazilfuse.WriteResponse{}); err == nil {
+		t.Fatal("Write on a read-only FS: want error, got nil")
+	} else {
+		wantEROFS(t, err)
+	}
+
+	req := &bazilfuse.SetattrRequest{}
+	req.Valid |= bazilfuse.SetattrSize
+	if err := file.Setattr(ctx, req, &bazilfuse.SetattrResponse{}); err == nil {
+		t.Fatal("Setattr on a read-only FS: want error, got nil")
+	} else {
+		wantEROFS(t, err)
+	}
+}
+
+func isErrno(err error, want syscall.Errno) bool {
+	errno, ok := err.(bazilfuse.Errno)
+	return ok && syscall.Errno(errno) == want
+}
@@ -0,0 +1,115 @@
+// Package fdfs HTTP Range header parsing.
+// This file adds ParseRange, which turns a client-supplied HTTP Range header
+// into the byte ranges to fetch (e.g. as ParallelOptions.Ranges for
+// DownloadRangesParallel in parallelupload.go), instead of requiring callers
+// to do their own RFC 7233 arithmetic.
+package fdfs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HTTPRange is a single byte range resolved against a concrete file size:
+// Start is an absolute offset and Length is how many bytes from Start to
+// fetch.
+type HTTPRange struct {
+	Start  int64
+	Length int64
+}
+
+// rangePrefix is the only unit ParseRange understands, matching every
+// FastDFS download path (DownloadFileRange and friends), which all take
+// byte offsets.
+const rangePrefix = "bytes="
+
+// ParseRange parses an HTTP Range header (RFC 7233 §14.35.1) against a file
+// of the given size, resolving suffix (bytes=-500) and open (bytes=500-)
+// forms to absolute [Start, Start+Length) spans. A header whose ranges
+// together add up to more than size is rejected rather than honored: per
+// §14.35.1 that can only mean overlapping or duplicate ranges, which
+// net/http's ServeContent also refuses to serve as a wasteful multi-range
+// request.
+func ParseRange(header string, size int64) ([]HTTPRange, error) {
+	if !strings.HasPrefix(header, rangePrefix) {
+		return nil, fmt.Errorf("fdfs: invalid Range header %q: %w", header, ErrInvalidArgument)
+	}
+
+	var ranges []HTTPRange
+	noOverlap := false
+	for _, spec := range strings.Split(header[len(rangePrefix):], ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		dash := strings.IndexByte(spec, '-')
+		if dash < 0 {
+			return nil, fmt.Errorf("fdfs: invalid Range header %q: missing '-' in range %q: %w", header, spec, ErrInvalidArgument)
+		}
+		startStr := strings.TrimSpace(spec[:dash])
+		endStr := strings.TrimSpace(spec[dash+1:])
+
+		var r HTTPRange
+		switch {
+		case startStr == "" && endStr == "":
+			return nil, fmt.Errorf("fdfs: invalid Range header %q: empty range %q: %w", header, spec, ErrInvalidArgument)
+
+		case startStr == "":
+			// Suffix range, e.g. "bytes=-500": the last n bytes.
+			n, err := strconv.ParseInt(endStr, 10, 64)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("fdfs: invalid Range header %q: invalid suffix length in %q: %w", header, spec, ErrInvalidArgument)
+			}
+			if n > size {
+				n = size
+			}
+			r = HTTPRange{Start: size - n, Length: n}
+
+		default:
+			start, err := strconv.ParseInt(startStr, 10, 64)
+			if err != nil || start < 0 {
+				return nil, fmt.Errorf("fdfs: invalid Range header %q: invalid start in %q: %w", header, spec, ErrInvalidArgument)
+			}
+			if start >= size {
+				// Unsatisfiable on its own; skip it rather than failing the
+				// whole header, same as net/http's parseRange.
+				noOverlap = true
+				continue
+			}
+			if endStr == "" {
+				// Open range, e.g. "bytes=500-": to the end of the file.
+				r = HTTPRange{Start: start, Length: size - start}
+			} else {
+				end, err := strconv.ParseInt(endStr, 10, 64)
+				if err != nil || end < start {
+					return nil, fmt.Errorf("fdfs: invalid Range header %q: invalid end in %q: %w", header, spec, ErrInvalidArgument)
+				}
+				if end >= size {
+					end = size - 1
+				}
+				r = HTTPRange{Start: start, Length: end - start + 1}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+
+	if len(ranges) == 0 {
+		if noOverlap {
+			return nil, fmt.Errorf("fdfs: invalid Range header %q: no satisfiable range for size %d: %w", header, size, ErrInvalidArgument)
+		}
+		return nil, fmt.Errorf("fdfs: invalid Range header %q: no valid ranges: %w", header, ErrInvalidArgument)
+	}
+
+	if len(ranges) > 1 {
+		var sum int64
+		for _, r := range ranges {
+			sum += r.Length
+		}
+		if sum > size {
+			return nil, fmt.Errorf("fdfs: invalid Range header %q: combined ranges exceed file size: %w", header, ErrInvalidArgument)
+		}
+	}
+
+	return ranges, nil
+}
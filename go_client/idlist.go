@@ -0,0 +1,141 @@
+// Package fdfs resumable file ID lists.
+// This file adds a durable, append-only record of the files a batch job
+// (see pool.go) has uploaded, so a caller can decouple "upload a million
+// files" from "verify/read them back" into separate runs instead of having
+// to hold every file ID in memory for the lifetime of one process, mirroring
+// the id-list workflow SeaweedFS's benchmark uses. Verify/Repair (verify.go)
+// consume an IDList to audit which of those files actually landed and are
+// still healthy.
+package fdfs
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// idListHeader identifies the file format and its version. ReadIDList
+// rejects any file that doesn't start with it.
+const idListHeader = "fastdfs-ids v1\n"
+
+// IDListEntry is one uploaded file recorded in an IDList.
+type IDListEntry struct {
+	FileID     string
+	Size       int64
+	CRC32      uint32
+	UploadedAt time.Time
+}
+
+// IDList is an append-only file of IDListEntry records, one per line, for a
+// batch upload job to write to and a later verify/repair/read pass to
+// replay. Safe for concurrent use by multiple goroutines.
+type IDList struct {
+	mu sync.Mutex
+	f  *os.File
+	w  *bufio.Writer
+}
+
+// CreateIDList creates (or truncates) path and returns an IDList ready to
+// Append to.
+func CreateIDList(path string) (*IDList, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create id list: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	if _, err := w.WriteString(idListHeader); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("write id list header: %w", err)
+	}
+	return &IDList{f: f, w: w}, nil
+}
+
+// Append records one entry. The caller must still call Flush (or Close)
+// for the write to be durable.
+func (l *IDList) Append(entry IDListEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := fmt.Fprintf(l.w, "%s\t%d\t%08x\t%d\n", entry.FileID, entry.Size, entry.CRC32, entry.UploadedAt.Unix())
+	return err
+}
+
+// Flush writes any buffered entries to disk.
+func (l *IDList) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Flush()
+}
+
+// Close flushes and closes the underlying file.
+func (l *IDList) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.w.Flush(); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// ReadIDList reads back every entry written by CreateIDList/Append.
+func ReadIDList(path string) ([]IDListEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open id list: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("id list: empty file")
+	}
+	if header := scanner.Text() + "\n"; header != idListHeader {
+		return nil, fmt.Errorf("id list: unrecognized header %q", header)
+	}
+
+	var entries []IDListEntry
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		entry, err := parseIDListLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("id list: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func parseIDListLine(line string) (IDListEntry, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != 4 {
+		return IDListEntry{}, fmt.Errorf("malformed line %q: want 4 tab-separated fields, got %d", line, len(fields))
+	}
+
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return IDListEntry{}, fmt.Errorf("malformed size in %q: %w", line, err)
+	}
+	crc, err := strconv.ParseUint(fields[2], 16, 32)
+	if err != nil {
+		return IDListEntry{}, fmt.Errorf("malformed crc32 in %q: %w", line, err)
+	}
+	uploadedAtUnix, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return IDListEntry{}, fmt.Errorf("malformed uploadedAt in %q: %w", line, err)
+	}
+
+	return IDListEntry{
+		FileID:     fields[0],
+		Size:       size,
+		CRC32:      uint32(crc),
+		UploadedAt: time.Unix(uploadedAtUnix, 0),
+	}, nil
+}
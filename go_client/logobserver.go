@@ -0,0 +1,104 @@
+// Package fdfs observability hook.
+// This file provides a ready-made Observer backed by log/slog, for callers
+// who want structured logs without writing their own Observer.
+package fdfs
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// traceCategories restricts which LogObserver categories are logged, parsed
+// once from the FDFS_TRACE environment variable (e.g.
+// "FDFS_TRACE=net,pool,retry"). nil (FDFS_TRACE unset or empty) logs every
+// category, matching LogObserver's behavior before this existed.
+var traceCategories = parseTraceCategories(os.Getenv("FDFS_TRACE"))
+
+func parseTraceCategories(v string) map[string]bool {
+	if v == "" {
+		return nil
+	}
+	cats := make(map[string]bool)
+	for _, c := range strings.Split(v, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cats[c] = true
+		}
+	}
+	return cats
+}
+
+// traceEnabled reports whether category should be logged: true when
+// FDFS_TRACE is unset, or when it explicitly lists category.
+func traceEnabled(category string) bool {
+	return traceCategories == nil || traceCategories[category]
+}
+
+// LogObserver is an Observer that writes structured log lines via
+// log/slog. A nil Logger falls back to slog.Default().
+type LogObserver struct {
+	Logger *slog.Logger
+}
+
+// NewLogObserver returns a LogObserver that logs through logger, or through
+// slog.Default() if logger is nil.
+func NewLogObserver(logger *slog.Logger) *LogObserver {
+	return &LogObserver{Logger: logger}
+}
+
+func (o *LogObserver) logger() *slog.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return slog.Default()
+}
+
+// OnRequestStart implements Observer. Gated by FDFS_TRACE's "net" category.
+func (o *LogObserver) OnRequestStart(ctx context.Context, op string, args map[string]interface{}) {
+	if !traceEnabled("net") {
+		return
+	}
+	attrs := make([]any, 0, 2+2*len(args))
+	attrs = append(attrs, "op", op)
+	for k, v := range args {
+		attrs = append(attrs, k, v)
+	}
+	o.logger().InfoContext(ctx, "fdfs request start", attrs...)
+}
+
+// OnRequestEnd implements Observer. Gated by FDFS_TRACE's "net" category.
+func (o *LogObserver) OnRequestEnd(ctx context.Context, op string, err error, duration time.Duration) {
+	if !traceEnabled("net") {
+		return
+	}
+	if err != nil {
+		o.logger().ErrorContext(ctx, "fdfs request end", "op", op, "err", err, "code", CodeOf(err), "duration", duration)
+		return
+	}
+	o.logger().InfoContext(ctx, "fdfs request end", "op", op, "duration", duration)
+}
+
+// OnRetry implements Observer. Gated by FDFS_TRACE's "retry" category.
+func (o *LogObserver) OnRetry(ctx context.Context, op string, attempt int, err error, nextDelay time.Duration) {
+	if !traceEnabled("retry") {
+		return
+	}
+	o.logger().WarnContext(ctx, "fdfs retry", "op", op, "attempt", attempt, "err", err, "next_delay", nextDelay)
+}
+
+// OnCircuitStateChange implements Observer. Always logged: a circuit
+// breaker transition is rare and significant enough not to be gated as
+// "verbose" the way request/retry/pool traffic is.
+func (o *LogObserver) OnCircuitStateChange(addr string, from, to CircuitState) {
+	o.logger().Warn("fdfs circuit breaker state change", "addr", addr, "from", from.String(), "to", to.String())
+}
+
+// OnConnPoolEvent implements Observer. Gated by FDFS_TRACE's "pool" category.
+func (o *LogObserver) OnConnPoolEvent(addr string, event ConnPoolEvent) {
+	if !traceEnabled("pool") {
+		return
+	}
+	o.logger().Debug("fdfs connection pool event", "addr", addr, "event", string(event))
+}
@@ -10,7 +10,9 @@ import (
 // setMetadataWithRetry sets metadata with retry logic
 func (c *Client) setMetadataWithRetry(ctx context.Context, fileID string, metadata map[string]string, flag MetadataFlag) error {
 	var lastErr error
-	for i := 0; i < c.config.RetryCount; i++ {
+	backoff := c.newRetryBackoff(ctx)
+	retryCount := c.retryCount(ctx)
+	for i := 0; i < retryCount; i++ {
 		err := c.setMetadataInternal(ctx, fileID, metadata, flag)
 		if err == nil {
 			return nil
@@ -21,11 +23,13 @@ func (c *Client) setMetadataWithRetry(ctx context.Context, fileID string, metada
 			return err
 		}
 
-		if i < c.config.RetryCount-1 {
+		if i < retryCount-1 {
+			delay := backoff.Next()
+			c.observerFor(ctx).OnRetry(ctx, "set_metadata", i+1, err, delay)
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case <-time.After(time.Second * time.Duration(i+1)):
+			case <-time.After(delay):
 			}
 		}
 	}
@@ -33,7 +37,7 @@ func (c *Client) setMetadataWithRetry(ctx context.Context, fileID string, metada
 }
 
 // setMetadataInternal performs the actual metadata setting
-func (c *Client) setMetadataInternal(ctx context.Context, fileID string, metadata map[string]string, flag MetadataFlag) error {
+func (c *Client) setMetadataInternal(ctx context.Context, fileID string, metadata map[string]string, flag MetadataFlag) (err error) {
 	groupName, remoteFilename, err := splitFileID(fileID)
 	if err != nil {
 		return err
@@ -44,9 +48,13 @@ func (c *Client) setMetadataInternal(ctx context.Context, fileID string, metadat
 	if err != nil {
 		return err
 	}
+	storageAddr := storageServer.IPAddr + ":" + fmt.Sprintf("%d", storageServer.Port)
+
+	start := time.Now()
+	defer func() { c.recordMetrics("set_metadata", storageAddr, fileID, start, 0, err) }()
 
 	// Get connection
-	conn, err := c.storagePool.Get(ctx, storageServer.IPAddr+":"+fmt.Sprintf("%d", storageServer.Port))
+	conn, err := c.storagePool.Get(ctx, storageAddr)
 	if err != nil {
 		return err
 	}
@@ -68,15 +76,15 @@ func (c *Client) setMetadataInternal(ctx context.Context, fileID string, metadat
 	buf.Write(metaBytes)
 
 	// Send request
-	if err := conn.Send(header, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(header, c.networkTimeout(ctx)); err != nil {
 		return err
 	}
-	if err := conn.Send(buf.Bytes(), c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(buf.Bytes(), c.networkTimeout(ctx)); err != nil {
 		return err
 	}
 
 	// Receive response
-	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.config.NetworkTimeout)
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.networkTimeout(ctx))
 	if err != nil {
 		return err
 	}
@@ -96,7 +104,9 @@ func (c *Client) setMetadataInternal(ctx context.Context, fileID string, metadat
 // getMetadataWithRetry gets metadata with retry logic
 func (c *Client) getMetadataWithRetry(ctx context.Context, fileID string) (map[string]string, error) {
 	var lastErr error
-	for i := 0; i < c.config.RetryCount; i++ {
+	backoff := c.newRetryBackoff(ctx)
+	retryCount := c.retryCount(ctx)
+	for i := 0; i < retryCount; i++ {
 		metadata, err := c.getMetadataInternal(ctx, fileID)
 		if err == nil {
 			return metadata, nil
@@ -107,11 +117,13 @@ func (c *Client) getMetadataWithRetry(ctx context.Context, fileID string) (map[s
 			return nil, err
 		}
 
-		if i < c.config.RetryCount-1 {
+		if i < retryCount-1 {
+			delay := backoff.Next()
+			c.observerFor(ctx).OnRetry(ctx, "get_metadata", i+1, err, delay)
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
-			case <-time.After(time.Second * time.Duration(i+1)):
+			case <-time.After(delay):
 			}
 		}
 	}
@@ -119,7 +131,7 @@ func (c *Client) getMetadataWithRetry(ctx context.Context, fileID string) (map[s
 }
 
 // getMetadataInternal performs the actual metadata retrieval
-func (c *Client) getMetadataInternal(ctx context.Context, fileID string) (map[string]string, error) {
+func (c *Client) getMetadataInternal(ctx context.Context, fileID string) (metadata map[string]string, err error) {
 	groupName, remoteFilename, err := splitFileID(fileID)
 	if err != nil {
 		return nil, err
@@ -130,9 +142,13 @@ func (c *Client) getMetadataInternal(ctx context.Context, fileID string) (map[st
 	if err != nil {
 		return nil, err
 	}
+	storageAddr := storageServer.IPAddr + ":" + fmt.Sprintf("%d", storageServer.Port)
+
+	start := time.Now()
+	defer func() { c.recordMetrics("get_metadata", storageAddr, fileID, start, 0, err) }()
 
 	// Get connection
-	conn, err := c.storagePool.Get(ctx, storageServer.IPAddr+":"+fmt.Sprintf("%d", storageServer.Port))
+	conn, err := c.storagePool.Get(ctx, storageAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -147,15 +163,15 @@ func (c *Client) getMetadataInternal(ctx context.Context, fileID string) (map[st
 	buf.Write([]byte(remoteFilename))
 
 	// Send request
-	if err := conn.Send(header, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(header, c.networkTimeout(ctx)); err != nil {
 		return nil, err
 	}
-	if err := conn.Send(buf.Bytes(), c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(buf.Bytes(), c.networkTimeout(ctx)); err != nil {
 		return nil, err
 	}
 
 	// Receive response
-	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.config.NetworkTimeout)
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.networkTimeout(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -174,7 +190,7 @@ func (c *Client) getMetadataInternal(ctx context.Context, fileID string) (map[st
 	}
 
 	// Receive metadata
-	metaBytes, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.config.NetworkTimeout)
+	metaBytes, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.networkTimeout(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -186,7 +202,9 @@ func (c *Client) getMetadataInternal(ctx context.Context, fileID string) (map[st
 // getFileInfoWithRetry gets file info with retry logic
 func (c *Client) getFileInfoWithRetry(ctx context.Context, fileID string) (*FileInfo, error) {
 	var lastErr error
-	for i := 0; i < c.config.RetryCount; i++ {
+	backoff := c.newRetryBackoff(ctx)
+	retryCount := c.retryCount(ctx)
+	for i := 0; i < retryCount; i++ {
 		info, err := c.getFileInfoInternal(ctx, fileID)
 		if err == nil {
 			return info, nil
@@ -197,11 +215,13 @@ func (c *Client) getFileInfoWithRetry(ctx context.Context, fileID string) (*File
 			return nil, err
 		}
 
-		if i < c.config.RetryCount-1 {
+		if i < retryCount-1 {
+			delay := backoff.Next()
+			c.observerFor(ctx).OnRetry(ctx, "get_file_info", i+1, err, delay)
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
-			case <-time.After(time.Second * time.Duration(i+1)):
+			case <-time.After(delay):
 			}
 		}
 	}
@@ -209,7 +229,7 @@ func (c *Client) getFileInfoWithRetry(ctx context.Context, fileID string) (*File
 }
 
 // getFileInfoInternal performs the actual file info retrieval
-func (c *Client) getFileInfoInternal(ctx context.Context, fileID string) (*FileInfo, error) {
+func (c *Client) getFileInfoInternal(ctx context.Context, fileID string) (info *FileInfo, err error) {
 	groupName, remoteFilename, err := splitFileID(fileID)
 	if err != nil {
 		return nil, err
@@ -220,9 +240,13 @@ func (c *Client) getFileInfoInternal(ctx context.Context, fileID string) (*FileI
 	if err != nil {
 		return nil, err
 	}
+	storageAddr := storageServer.IPAddr + ":" + fmt.Sprintf("%d", storageServer.Port)
+
+	start := time.Now()
+	defer func() { c.recordMetrics("get_file_info", storageAddr, fileID, start, 0, err) }()
 
 	// Get connection
-	conn, err := c.storagePool.Get(ctx, storageServer.IPAddr+":"+fmt.Sprintf("%d", storageServer.Port))
+	conn, err := c.storagePool.Get(ctx, storageAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -237,15 +261,15 @@ func (c *Client) getFileInfoInternal(ctx context.Context, fileID string) (*FileI
 	buf.Write([]byte(remoteFilename))
 
 	// Send request
-	if err := conn.Send(header, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(header, c.networkTimeout(ctx)); err != nil {
 		return nil, err
 	}
-	if err := conn.Send(buf.Bytes(), c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(buf.Bytes(), c.networkTimeout(ctx)); err != nil {
 		return nil, err
 	}
 
 	// Receive response
-	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.config.NetworkTimeout)
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.networkTimeout(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -265,7 +289,7 @@ func (c *Client) getFileInfoInternal(ctx context.Context, fileID string) (*FileI
 		return nil, ErrInvalidResponse
 	}
 
-	respBody, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.config.NetworkTimeout)
+	respBody, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.networkTimeout(ctx))
 	if err != nil {
 		return nil, err
 	}
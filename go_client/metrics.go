@@ -0,0 +1,139 @@
+// Package fdfs metrics instrumentation.
+// This file defines a pluggable Metrics sink, distinct from Observer (see
+// observer.go): Observer exposes discrete lifecycle events for building
+// structured logs or traces, while Metrics exposes aggregatable
+// counters/histograms/gauges meant to be scraped or shipped to a time
+// series backend. See StatsDMetrics and metrics/prometheus for ready-made
+// implementations.
+package fdfs
+
+import (
+	"time"
+)
+
+// Metrics is a pluggable sink for counters, histograms, and gauges emitted
+// by Client for every tracker/storage RPC. ClientConfig.Metrics is
+// optional; a nil Metrics is replaced with a no-op default so call sites
+// never need a nil check.
+type Metrics interface {
+	// Counter returns a monotonically-increasing counter identified by
+	// name and tags (e.g. {"cmd": "upload_buffer", "status": "error"}).
+	Counter(name string, tags map[string]string) Counter
+
+	// Histogram returns a value distribution identified by name and tags.
+	Histogram(name string, tags map[string]string) Histogram
+
+	// Gauge returns a point-in-time value identified by name and tags.
+	Gauge(name string, tags map[string]string) Gauge
+}
+
+// Counter accumulates a monotonically-increasing value.
+type Counter interface {
+	Add(delta float64)
+}
+
+// Histogram records individual observations of a value distribution (e.g.
+// latency in milliseconds, payload size in bytes).
+type Histogram interface {
+	Observe(value float64)
+}
+
+// Gauge reports a value that can go up or down (e.g. connection pool depth).
+type Gauge interface {
+	Set(value float64)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) Counter(name string, tags map[string]string) Counter     { return noopCounter{} }
+func (noopMetrics) Histogram(name string, tags map[string]string) Histogram { return noopHistogram{} }
+func (noopMetrics) Gauge(name string, tags map[string]string) Gauge         { return noopGauge{} }
+
+type noopCounter struct{}
+
+func (noopCounter) Add(delta float64) {}
+
+type noopHistogram struct{}
+
+func (noopHistogram) Observe(value float64) {}
+
+type noopGauge struct{}
+
+func (noopGauge) Set(value float64) {}
+
+// metrics returns ClientConfig.Metrics, or a no-op default when unset.
+func (c *Client) metrics() Metrics {
+	if c.config.Metrics != nil {
+		return c.config.Metrics
+	}
+	return noopMetrics{}
+}
+
+// recordMetrics emits the standard per-command metrics for an RPC: a
+// request counter tagged by outcome, a latency histogram, an optional byte
+// volume histogram, and an error counter tagged by endpoint so
+// per-storage-server error rates can be derived. addr is the tracker or
+// storage endpoint used, or "" when none was tracked (e.g. the call failed
+// before a server was selected). fileID, when non-empty and well-formed,
+// adds a "group" tag so per-group request volume and error rates can be
+// told apart (e.g. one group on slower storage hardware than another);
+// pass "" when no file ID is involved yet (e.g. upload_buffer's defer runs
+// before the tracker has assigned one on failure). bytes is the payload
+// size for upload/download commands, or 0 for commands with no payload.
+func (c *Client) recordMetrics(cmd, addr, fileID string, start time.Time, bytes int, err error) {
+	group := requestGroup(fileID)
+	tags := map[string]string{"cmd": cmd, "group": group}
+
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	latency := time.Since(start)
+	c.metrics().Counter("fdfs.requests", map[string]string{"cmd": cmd, "group": group, "status": status}).Add(1)
+	c.metrics().Histogram("fdfs.latency_ms", tags).Observe(float64(latency.Milliseconds()))
+	if c.storageAdaptivePool != nil {
+		c.storageAdaptivePool.recordLatency(latency)
+	}
+	if bytes > 0 {
+		c.metrics().Histogram("fdfs.bytes", tags).Observe(float64(bytes))
+	}
+	if err != nil && addr != "" {
+		c.metrics().Counter("fdfs.errors", map[string]string{"cmd": cmd, "group": group, "addr": addr}).Add(1)
+	}
+
+	c.reportPoolDepths()
+}
+
+// requestGroup extracts the FastDFS group name from fileID for
+// recordMetrics' "group" tag, returning "" when fileID is empty or isn't a
+// valid "group/remote_filename" file ID.
+func requestGroup(fileID string) string {
+	group, _, err := splitFileID(fileID)
+	if err != nil {
+		return ""
+	}
+	return group
+}
+
+// reportPoolDepths emits the current idle connection count, plus (via
+// PoolStats) the count currently checked out, for every tracker and storage
+// server as Metrics gauges. Called after every RPC; skipped entirely when
+// no Metrics sink is configured so the pool lock isn't taken on the hot
+// path for nothing.
+func (c *Client) reportPoolDepths() {
+	if c.config.Metrics == nil {
+		return
+	}
+	for addr, depth := range c.trackerPool.Depths() {
+		c.metrics().Gauge("fdfs.pool.depth", map[string]string{"pool": "tracker", "addr": addr}).Set(float64(depth))
+	}
+	for addr, depth := range c.storagePool.Depths() {
+		c.metrics().Gauge("fdfs.pool.depth", map[string]string{"pool": "storage", "addr": addr}).Set(float64(depth))
+	}
+	for addr, stats := range c.trackerPool.Stats() {
+		c.metrics().Gauge("fdfs.pool.in_use", map[string]string{"pool": "tracker", "addr": addr}).Set(float64(stats.InUse))
+	}
+	for addr, stats := range c.storagePool.Stats() {
+		c.metrics().Gauge("fdfs.pool.in_use", map[string]string{"pool": "storage", "addr": addr}).Set(float64(stats.InUse))
+	}
+}
@@ -0,0 +1,140 @@
+// Package otel adapts fdfs.Metrics to OpenTelemetry, so ClientConfig.Metrics
+// can be backed by a metric.Meter from any OTel-compatible collector
+// instead of (or alongside) StatsD/Prometheus. See go_client/tracing/otel
+// for the corresponding Tracer/Span adapter.
+package otel
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	fdfs "github.com/happyfish100/fastdfs/go_client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metrics adapts an OpenTelemetry metric.Meter to fdfs.Metrics, creating one
+// instrument per distinct metric name (cached the same way
+// metrics/prometheus.Metrics caches its vectors) and recording each
+// Counter/Histogram/Gauge call as a single measurement tagged with tags as
+// attributes.
+type Metrics struct {
+	meter metric.Meter
+
+	mu         sync.Mutex
+	counters   map[string]metric.Float64Counter
+	histograms map[string]metric.Float64Histogram
+	gauges     map[string]metric.Float64Gauge
+}
+
+// NewMetrics returns an fdfs.Metrics backed by meter.
+func NewMetrics(meter metric.Meter) *Metrics {
+	return &Metrics{
+		meter:      meter,
+		counters:   make(map[string]metric.Float64Counter),
+		histograms: make(map[string]metric.Float64Histogram),
+		gauges:     make(map[string]metric.Float64Gauge),
+	}
+}
+
+// toAttributes renders tags as attribute.KeyValue pairs in a stable
+// (sorted) order, matching metrics/prometheus's labelNames approach.
+func toAttributes(tags map[string]string) []attribute.KeyValue {
+	if len(tags) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]attribute.KeyValue, len(keys))
+	for i, k := range keys {
+		attrs[i] = attribute.String(k, tags[k])
+	}
+	return attrs
+}
+
+// Counter implements fdfs.Metrics.
+func (m *Metrics) Counter(name string, tags map[string]string) fdfs.Counter {
+	m.mu.Lock()
+	c, ok := m.counters[name]
+	if !ok {
+		c, _ = m.meter.Float64Counter(name)
+		m.counters[name] = c
+	}
+	m.mu.Unlock()
+	return &counter{c: c, attrs: toAttributes(tags)}
+}
+
+// Histogram implements fdfs.Metrics.
+func (m *Metrics) Histogram(name string, tags map[string]string) fdfs.Histogram {
+	m.mu.Lock()
+	h, ok := m.histograms[name]
+	if !ok {
+		h, _ = m.meter.Float64Histogram(name)
+		m.histograms[name] = h
+	}
+	m.mu.Unlock()
+	return &histogram{h: h, attrs: toAttributes(tags)}
+}
+
+// Gauge implements fdfs.Metrics.
+func (m *Metrics) Gauge(name string, tags map[string]string) fdfs.Gauge {
+	m.mu.Lock()
+	g, ok := m.gauges[name]
+	if !ok {
+		g, _ = m.meter.Float64Gauge(name)
+		m.gauges[name] = g
+	}
+	m.mu.Unlock()
+	return &gauge{g: g, attrs: toAttributes(tags)}
+}
+
+// counter wraps a metric.Float64Counter with the attribute set for one
+// fdfs.Metrics.Counter call. c is nil when the meter failed to create the
+// instrument; Add is then a no-op, same as noopMetrics elsewhere in fdfs.
+type counter struct {
+	c     metric.Float64Counter
+	attrs []attribute.KeyValue
+}
+
+// Add implements fdfs.Counter.
+func (c *counter) Add(delta float64) {
+	if c.c == nil {
+		return
+	}
+	c.c.Add(context.Background(), delta, metric.WithAttributes(c.attrs...))
+}
+
+// histogram wraps a metric.Float64Histogram with the attribute set for one
+// fdfs.Metrics.Histogram call.
+type histogram struct {
+	h     metric.Float64Histogram
+	attrs []attribute.KeyValue
+}
+
+// Observe implements fdfs.Histogram.
+func (h *histogram) Observe(value float64) {
+	if h.h == nil {
+		return
+	}
+	h.h.Record(context.Background(), value, metric.WithAttributes(h.attrs...))
+}
+
+// gauge wraps a metric.Float64Gauge with the attribute set for one
+// fdfs.Metrics.Gauge call.
+type gauge struct {
+	g     metric.Float64Gauge
+	attrs []attribute.KeyValue
+}
+
+// Set implements fdfs.Gauge.
+func (g *gauge) Set(value float64) {
+	if g.g == nil {
+		return
+	}
+	g.g.Record(context.Background(), value, metric.WithAttributes(g.attrs...))
+}
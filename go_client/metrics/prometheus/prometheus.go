@@ -0,0 +1,148 @@
+// Package prometheus adapts fdfs.Metrics to a registrable
+// prometheus.Collector, so ClientConfig.Metrics can be backed by the
+// standard Prometheus client and scraped like any other collector.
+package prometheus
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	fdfs "github.com/happyfish100/fastdfs/go_client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics is an fdfs.Metrics backed by Prometheus counter/histogram/gauge
+// vectors, one per distinct metric name. The label set for a given name is
+// fixed by whichever tags are passed the first time that name is used;
+// register it with prometheus.Register (or a custom Registry) like any
+// other collector.
+type Metrics struct {
+	mu         sync.Mutex
+	counters   map[string]*prometheus.CounterVec
+	histograms map[string]*prometheus.HistogramVec
+	gauges     map[string]*prometheus.GaugeVec
+}
+
+// NewMetrics returns an empty Metrics collector.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		counters:   make(map[string]*prometheus.CounterVec),
+		histograms: make(map[string]*prometheus.HistogramVec),
+		gauges:     make(map[string]*prometheus.GaugeVec),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, v := range m.counters {
+		v.Describe(ch)
+	}
+	for _, v := range m.histograms {
+		v.Describe(ch)
+	}
+	for _, v := range m.gauges {
+		v.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, v := range m.counters {
+		v.Collect(ch)
+	}
+	for _, v := range m.histograms {
+		v.Collect(ch)
+	}
+	for _, v := range m.gauges {
+		v.Collect(ch)
+	}
+}
+
+// labelNames returns tags' keys in a stable (sorted) order, so the label
+// values passed to WithLabelValues always line up with the label names the
+// vector was created with.
+func labelNames(tags map[string]string) []string {
+	names := make([]string, 0, len(tags))
+	for k := range tags {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func labelValues(tags map[string]string, names []string) []string {
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = tags[name]
+	}
+	return values
+}
+
+// sanitizeName rewrites an fdfs.Metrics name (dot-separated, e.g.
+// "fdfs.requests", matching the StatsD hierarchy convention StatsDMetrics
+// expects) into a valid Prometheus metric name ([a-zA-Z_:][a-zA-Z0-9_:]*),
+// since client_golang panics on registration otherwise. The two backends
+// share one naming scheme at the fdfs.Metrics call site; translating it is
+// this adapter's job, not the caller's.
+func sanitizeName(name string) string {
+	return strings.ReplaceAll(name, ".", "_")
+}
+
+// Counter implements fdfs.Metrics.
+func (m *Metrics) Counter(name string, tags map[string]string) fdfs.Counter {
+	name = sanitizeName(name)
+	names := labelNames(tags)
+	m.mu.Lock()
+	v, ok := m.counters[name]
+	if !ok {
+		v = prometheus.NewCounterVec(prometheus.CounterOpts{Name: name}, names)
+		m.counters[name] = v
+	}
+	m.mu.Unlock()
+	return v.WithLabelValues(labelValues(tags, names)...)
+}
+
+// Histogram implements fdfs.Metrics.
+func (m *Metrics) Histogram(name string, tags map[string]string) fdfs.Histogram {
+	name = sanitizeName(name)
+	names := labelNames(tags)
+	m.mu.Lock()
+	v, ok := m.histograms[name]
+	if !ok {
+		v = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name}, names)
+		m.histograms[name] = v
+	}
+	m.mu.Unlock()
+	return v.WithLabelValues(labelValues(tags, names)...)
+}
+
+// Gauge implements fdfs.Metrics.
+func (m *Metrics) Gauge(name string, tags map[string]string) fdfs.Gauge {
+	name = sanitizeName(name)
+	names := labelNames(tags)
+	m.mu.Lock()
+	v, ok := m.gauges[name]
+	if !ok {
+		v = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name}, names)
+		m.gauges[name] = v
+	}
+	m.mu.Unlock()
+	return v.WithLabelValues(labelValues(tags, names)...)
+}
+
+// Handler returns an http.Handler serving this Metrics collector in the
+// Prometheus text exposition format, via a private Registry so a caller
+// can mount it without also importing promhttp or registering m with the
+// global prometheus.DefaultRegisterer themselves.
+func (m *Metrics) Handler() http.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(m)
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,78 @@
+// Package fdfs observability hook.
+// This file adds a fine-grained Observer callback interface, distinct from
+// Tracer (see tracing.go): Tracer wraps each top-level call in a single
+// span, while Observer exposes the individual events (request
+// start/end, retry attempts, circuit breaker transitions, connection pool
+// activity) needed to build structured logs or metrics without re-deriving
+// them from span tags.
+package fdfs
+
+import (
+	"context"
+	"time"
+)
+
+// Observer receives lifecycle events for every RPC a Client makes.
+// ClientConfig.Observer is optional; a nil Observer is replaced with a
+// no-op default so call sites never need a nil check.
+type Observer interface {
+	// OnRequestStart fires when a Client method begins, before any retry
+	// attempts. args is a loosely-typed, method-specific summary (e.g. a
+	// file ID, byte count) suitable for logging.
+	OnRequestStart(ctx context.Context, op string, args map[string]interface{})
+
+	// OnRequestEnd fires when a Client method returns, after all retries.
+	OnRequestEnd(ctx context.Context, op string, err error, duration time.Duration)
+
+	// OnRetry fires before each retry sleep, reporting the failed attempt
+	// number (1-based), the error that triggered the retry, and how long
+	// the client will sleep before trying again.
+	OnRetry(ctx context.Context, op string, attempt int, err error, nextDelay time.Duration)
+
+	// OnCircuitStateChange fires when a tracker/storage endpoint's circuit
+	// breaker transitions from one CircuitState to another.
+	OnCircuitStateChange(addr string, from, to CircuitState)
+
+	// OnConnPoolEvent fires on connection pool activity for addr.
+	OnConnPoolEvent(addr string, event ConnPoolEvent)
+}
+
+// ConnPoolEvent identifies the kind of connection pool activity reported to
+// Observer.OnConnPoolEvent.
+type ConnPoolEvent string
+
+const (
+	// ConnPoolEventCreated reports a new connection was dialed because no
+	// idle connection was available to reuse.
+	ConnPoolEventCreated ConnPoolEvent = "created"
+	// ConnPoolEventReused reports an idle pooled connection was handed out.
+	ConnPoolEventReused ConnPoolEvent = "reused"
+	// ConnPoolEventClosed reports a connection was closed instead of being
+	// returned to the pool (idle queue full, idle too long, over MaxConns, or
+	// its address no longer tracked by the pool). A connection returned after
+	// the pool is closed is closed without this event.
+	ConnPoolEventClosed ConnPoolEvent = "closed"
+)
+
+// noopObserver is the default Observer used when ClientConfig.Observer is nil.
+type noopObserver struct{}
+
+func (noopObserver) OnRequestStart(ctx context.Context, op string, args map[string]interface{}) {}
+
+func (noopObserver) OnRequestEnd(ctx context.Context, op string, err error, duration time.Duration) {
+}
+
+func (noopObserver) OnRetry(ctx context.Context, op string, attempt int, err error, nextDelay time.Duration) {
+}
+
+func (noopObserver) OnCircuitStateChange(addr string, from, to CircuitState) {}
+
+func (noopObserver) OnConnPoolEvent(addr string, event ConnPoolEvent) {}
+
+// observer returns ClientConfig.Observer, or a no-op default when unset.
+func (c *Client) observer() Observer {
+	if c.config.Observer != nil {
+		return c.config.Observer
+	}
+	return noopObserver{}
+}
@@ -0,0 +1,107 @@
+// Package otel adapts fdfs.Observer to OpenTelemetry, emitting one span per
+// top-level RPC (distinct from tracing/otel, which adapts fdfs.Tracer the
+// same way; use this package instead when you want retry attempts recorded
+// as span events rather than a single start/end pair). Circuit breaker and
+// connection pool events are not recorded.
+package otel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	fdfs "github.com/happyfish100/fastdfs/go_client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Observer is an fdfs.Observer backed by an OpenTelemetry trace.Tracer. It
+// starts a span in OnRequestStart and ends it in OnRequestEnd, recording
+// retries as span events. OnCircuitStateChange and OnConnPoolEvent carry no
+// ctx to find a span by, and record nothing.
+type Observer struct {
+	tracer trace.Tracer
+
+	mu    sync.Mutex
+	spans map[context.Context]trace.Span
+}
+
+// NewObserver returns an fdfs.Observer that reports spans through tracer.
+func NewObserver(tracer trace.Tracer) *Observer {
+	return &Observer{tracer: tracer, spans: make(map[context.Context]trace.Span)}
+}
+
+// OnRequestStart implements fdfs.Observer.
+func (o *Observer) OnRequestStart(ctx context.Context, op string, args map[string]interface{}) {
+	_, span := o.tracer.Start(ctx, "fastdfs."+op)
+	for k, v := range args {
+		span.SetAttributes(toAttribute(k, v))
+	}
+
+	o.mu.Lock()
+	o.spans[ctx] = span
+	o.mu.Unlock()
+}
+
+// OnRequestEnd implements fdfs.Observer.
+func (o *Observer) OnRequestEnd(ctx context.Context, op string, err error, duration time.Duration) {
+	o.mu.Lock()
+	span, ok := o.spans[ctx]
+	delete(o.spans, ctx)
+	o.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(attribute.Int64("fdfs.duration_ms", duration.Milliseconds()))
+	if err != nil {
+		span.RecordError(err)
+		span.SetAttributes(attribute.String("fdfs.code", string(fdfs.CodeOf(err))))
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// OnRetry implements fdfs.Observer.
+func (o *Observer) OnRetry(ctx context.Context, op string, attempt int, err error, nextDelay time.Duration) {
+	o.mu.Lock()
+	span, ok := o.spans[ctx]
+	o.mu.Unlock()
+	if !ok {
+		return
+	}
+	span.AddEvent("retry", trace.WithAttributes(
+		attribute.Int("fdfs.attempt", attempt),
+		attribute.String("fdfs.err", err.Error()),
+		attribute.Int64("fdfs.next_delay_ms", nextDelay.Milliseconds()),
+	))
+}
+
+// OnCircuitStateChange implements fdfs.Observer.
+func (o *Observer) OnCircuitStateChange(addr string, from, to fdfs.CircuitState) {
+	// Not tied to any in-flight span; nothing to attach this event to.
+}
+
+// OnConnPoolEvent implements fdfs.Observer.
+func (o *Observer) OnConnPoolEvent(addr string, event fdfs.ConnPoolEvent) {
+	// Not tied to any in-flight span; nothing to attach this event to.
+}
+
+// toAttribute converts a loosely-typed tag value into an OTel attribute,
+// falling back to a string representation for types it doesn't special-case.
+func toAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	default:
+		return attribute.String(key, fmt.Sprintf("%v", v))
+	}
+}
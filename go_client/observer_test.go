@@ -0,0 +1,135 @@
+package fdfs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeObserver counts Observer calls by method name, for asserting
+// observerFor resolves to the right instance without needing a live
+// tracker/storage connection.
+type fakeObserver struct {
+	noopObserver
+	id    string
+	calls map[string]int
+}
+
+func newFakeObserver(id string) *fakeObserver {
+	return &fakeObserver{id: id, calls: make(map[string]int)}
+}
+
+func (f *fakeObserver) OnRequestStart(ctx context.Context, op string, args map[string]interface{}) {
+	f.calls["OnRequestStart"]++
+}
+
+func (f *fakeObserver) OnRequestEnd(ctx context.Context, op string, err error, duration time.Duration) {
+	f.calls["OnRequestEnd"]++
+}
+
+func (f *fakeObserver) OnRetry(ctx context.Context, op string, attempt int, err error, nextDelay time.Duration) {
+	f.calls["OnRetry"]++
+}
+
+func TestObserverForFallsBackToClientDefault(t *testing.T) {
+	def := newFakeObserver("default")
+	client, err := NewClient(&ClientConfig{
+		TrackerAddrs: []string{"192.168.1.100:22122"},
+		Observer:     def,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	got := client.observerFor(context.Background())
+	got.OnRequestStart(context.Background(), "download_file", nil)
+	got.OnRequestEnd(context.Background(), "download_file", nil, time.Millisecond)
+
+	assert.Equal(t, 1, def.calls["OnRequestStart"])
+	assert.Equal(t, 1, def.calls["OnRequestEnd"])
+}
+
+func TestObserverForPrefersContextOverride(t *testing.T) {
+	def := newFakeObserver("default")
+	override := newFakeObserver("override")
+	client, err := NewClient(&ClientConfig{
+		TrackerAddrs: []string{"192.168.1.100:22122"},
+		Observer:     def,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx := WithConfig(context.Background(), ConfigOverride{Observer: override})
+	got := client.observerFor(ctx)
+	got.OnRequestStart(ctx, "download_file", nil)
+
+	assert.Equal(t, 1, override.calls["OnRequestStart"])
+	assert.Equal(t, 0, def.calls["OnRequestStart"])
+}
+
+func TestObserverForOverrideWithNilObserverFallsBack(t *testing.T) {
+	def := newFakeObserver("default")
+	client, err := NewClient(&ClientConfig{
+		TrackerAddrs: []string{"192.168.1.100:22122"},
+		Observer:     def,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx := WithConfig(context.Background(), ConfigOverride{RetryCount: 5})
+	got := client.observerFor(ctx)
+	got.OnRequestStart(ctx, "download_file", nil)
+
+	assert.Equal(t, 1, def.calls["OnRequestStart"])
+}
+
+func TestRecordMetricsTagsGroupFromFileID(t *testing.T) {
+	fm := newFakeMetrics()
+	client, err := NewClient(&ClientConfig{
+		TrackerAddrs: []string{"192.168.1.100:22122"},
+		Metrics:      fm,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	client.recordMetrics("download_file", "10.0.0.1:23000", "group1/M00/00/00/abc.txt", time.Now(), 128, nil)
+
+	counter := fm.counters["fdfs.requests"]
+	require.NotNil(t, counter)
+	assert.Equal(t, "group1", counter.tags["group"])
+	assert.Equal(t, "ok", counter.tags["status"])
+}
+
+// fakeMetrics is a minimal Metrics recording the tags each Counter/
+// Histogram/Gauge call was made with, for TestRecordMetricsTagsGroupFromFileID.
+type fakeMetrics struct {
+	counters map[string]*fakeMetric
+}
+
+func newFakeMetrics() *fakeMetrics {
+	return &fakeMetrics{counters: make(map[string]*fakeMetric)}
+}
+
+type fakeMetric struct {
+	tags map[string]string
+}
+
+func (f *fakeMetric) Add(delta float64)     {}
+func (f *fakeMetric) Observe(value float64) {}
+func (f *fakeMetric) Set(value float64)     {}
+
+func (m *fakeMetrics) Counter(name string, tags map[string]string) Counter {
+	fm := &fakeMetric{tags: tags}
+	m.counters[name] = fm
+	return fm
+}
+
+func (m *fakeMetrics) Histogram(name string, tags map[string]string) Histogram {
+	return &fakeMetric{tags: tags}
+}
+
+func (m *fakeMetrics) Gauge(name string, tags map[string]string) Gauge {
+	return &fakeMetric{tags: tags}
+}
@@ -4,30 +4,35 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"hash/crc32"
 	"time"
 )
 
 // uploadFileWithRetry uploads a file with retry logic
 func (c *Client) uploadFileWithRetry(ctx context.Context, localFilename string, metadata map[string]string, isAppender bool) (string, error) {
 	var lastErr error
-	for i := 0; i < c.config.RetryCount; i++ {
+	backoff := c.newRetryBackoff(ctx)
+	retryCount := c.retryCount(ctx)
+	for i := 0; i < retryCount; i++ {
 		fileID, err := c.uploadFileInternal(ctx, localFilename, metadata, isAppender)
 		if err == nil {
 			return fileID, nil
 		}
 		lastErr = err
 
-		// Don't retry on certain errors
-		if err == ErrInvalidArgument || err == ErrFileNotFound {
+		if !c.retryableFunc(ctx)(err) {
 			return "", err
 		}
 
 		// Wait before retry
-		if i < c.config.RetryCount-1 {
+		if i < retryCount-1 {
+			delay := backoff.Next()
+			spanFromContext(ctx).SetTag("fdfs.retry.attempt", i+1)
+			c.observerFor(ctx).OnRetry(ctx, "upload_file", i+1, err, delay)
 			select {
 			case <-ctx.Done():
 				return "", ctx.Err()
-			case <-time.After(time.Second * time.Duration(i+1)):
+			case <-time.After(delay):
 			}
 		}
 	}
@@ -51,22 +56,27 @@ func (c *Client) uploadFileInternal(ctx context.Context, localFilename string, m
 // uploadBufferWithRetry uploads buffer with retry logic
 func (c *Client) uploadBufferWithRetry(ctx context.Context, data []byte, fileExtName string, metadata map[string]string, isAppender bool) (string, error) {
 	var lastErr error
-	for i := 0; i < c.config.RetryCount; i++ {
+	backoff := c.newRetryBackoff(ctx)
+	retryCount := c.retryCount(ctx)
+	for i := 0; i < retryCount; i++ {
 		fileID, err := c.uploadBufferInternal(ctx, data, fileExtName, metadata, isAppender)
 		if err == nil {
 			return fileID, nil
 		}
 		lastErr = err
 
-		if err == ErrInvalidArgument {
+		if !c.retryableFunc(ctx)(err) {
 			return "", err
 		}
 
-		if i < c.config.RetryCount-1 {
+		if i < retryCount-1 {
+			delay := backoff.Next()
+			spanFromContext(ctx).SetTag("fdfs.retry.attempt", i+1)
+			c.observerFor(ctx).OnRetry(ctx, "upload_buffer", i+1, err, delay)
 			select {
 			case <-ctx.Done():
 				return "", ctx.Err()
-			case <-time.After(time.Second * time.Duration(i+1)):
+			case <-time.After(delay):
 			}
 		}
 	}
@@ -74,15 +84,24 @@ func (c *Client) uploadBufferWithRetry(ctx context.Context, data []byte, fileExt
 }
 
 // uploadBufferInternal performs the actual buffer upload
-func (c *Client) uploadBufferInternal(ctx context.Context, data []byte, fileExtName string, metadata map[string]string, isAppender bool) (string, error) {
+func (c *Client) uploadBufferInternal(ctx context.Context, data []byte, fileExtName string, metadata map[string]string, isAppender bool) (fileID string, err error) {
 	// Get storage server from tracker
-	storageServer, err := c.getStorageServer(ctx, "")
+	storageServer, err := c.getStorageServer(ctx, c.preferredGroup(ctx, ""))
 	if err != nil {
 		return "", err
 	}
+	storageAddr := storageServer.IPAddr + ":" + fmt.Sprintf("%d", storageServer.Port)
+
+	if !c.circuitAllow(storageAddr) {
+		return "", ErrCircuitOpen
+	}
+	defer func() { c.circuitRecord(storageAddr, err) }()
+
+	start := time.Now()
+	defer func() { c.recordMetrics("upload_buffer", storageAddr, fileID, start, len(data), err) }()
 
 	// Get connection to storage server
-	conn, err := c.storagePool.Get(ctx, storageServer.IPAddr+":"+fmt.Sprintf("%d", storageServer.Port))
+	conn, err := c.storagePool.Get(ctx, storageAddr)
 	if err != nil {
 		return "", err
 	}
@@ -94,35 +113,47 @@ func (c *Client) uploadBufferInternal(ctx context.Context, data []byte, fileExtN
 		cmd = byte(StorageProtoCmdUploadAppenderFile)
 	}
 
+	// Compress the payload, if configured. Appender files are left
+	// uncompressed: this call only writes the first block, but every later
+	// AppendFile/ModifyFile/DownloadFileRange caller expects to read and
+	// write raw bytes at caller-chosen offsets into this same file (see
+	// compress.go's package doc), so compressing just the first block would
+	// desynchronize it from the rest.
+	sendData := data
+	var compressionMeta map[string]string
+	if !isAppender {
+		sendData, compressionMeta = c.maybeCompress(data)
+	}
+
 	// Build request
 	extNameBytes := padString(fileExtName, FdfsFileExtNameMaxLen)
 	storePathIndex := byte(storageServer.StorePathIndex)
 
-	bodyLen := 1 + FdfsFileExtNameMaxLen + int64(len(data))
+	bodyLen := 1 + FdfsFileExtNameMaxLen + int64(len(sendData))
 	reqHeader := encodeHeader(bodyLen, cmd, 0)
 
 	// Send header
-	if err := conn.Send(reqHeader, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(reqHeader, c.networkTimeout(ctx)); err != nil {
 		return "", err
 	}
 
 	// Send store path index
-	if err := conn.Send([]byte{storePathIndex}, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send([]byte{storePathIndex}, c.networkTimeout(ctx)); err != nil {
 		return "", err
 	}
 
 	// Send file extension
-	if err := conn.Send(extNameBytes, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(extNameBytes, c.networkTimeout(ctx)); err != nil {
 		return "", err
 	}
 
 	// Send file data
-	if err := conn.Send(data, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(sendData, c.networkTimeout(ctx)); err != nil {
 		return "", err
 	}
 
 	// Receive response header
-	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.config.NetworkTimeout)
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.networkTimeout(ctx))
 	if err != nil {
 		return "", err
 	}
@@ -141,7 +172,7 @@ func (c *Client) uploadBufferInternal(ctx context.Context, data []byte, fileExtN
 		return "", ErrInvalidResponse
 	}
 
-	respBody, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.config.NetworkTimeout)
+	respBody, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.networkTimeout(ctx))
 	if err != nil {
 		return "", err
 	}
@@ -154,11 +185,22 @@ func (c *Client) uploadBufferInternal(ctx context.Context, data []byte, fileExtN
 	groupName := unpadString(respBody[:FdfsGroupNameMaxLen])
 	remoteFilename := string(respBody[FdfsGroupNameMaxLen:])
 
-	fileID := joinFileID(groupName, remoteFilename)
+	fileID = joinFileID(groupName, remoteFilename)
 
-	// Set metadata if provided
-	if len(metadata) > 0 {
-		if err := c.setMetadataInternal(ctx, fileID, metadata, MetadataOverwrite); err != nil {
+	// Set metadata if provided, merging in how to reverse compression (if
+	// any) so DownloadFile can recognize and decode it later.
+	allMeta := metadata
+	if len(compressionMeta) > 0 {
+		allMeta = make(map[string]string, len(metadata)+len(compressionMeta))
+		for k, v := range metadata {
+			allMeta[k] = v
+		}
+		for k, v := range compressionMeta {
+			allMeta[k] = v
+		}
+	}
+	if len(allMeta) > 0 {
+		if err := c.setMetadataInternal(ctx, fileID, allMeta, MetadataOverwrite); err != nil {
 			// Metadata setting failed, but file is uploaded
 			// Log the error but don't fail the upload
 			return fileID, nil
@@ -168,13 +210,86 @@ func (c *Client) uploadBufferInternal(ctx context.Context, data []byte, fileExtN
 	return fileID, nil
 }
 
+// trackerAddrOrder returns addrs reordered so the address chosen by
+// ClientConfig.TrackerSelector (if configured) is tried first. Without a
+// TrackerSelector, addrs is returned unchanged so the client's historical
+// first-address-first behavior is preserved.
+func (c *Client) trackerAddrOrder(addrs []string) []string {
+	if c.config.TrackerSelector == nil || len(addrs) <= 1 {
+		return addrs
+	}
+	primary := c.config.TrackerSelector.Select(addrs)
+	if primary == "" {
+		return addrs
+	}
+	ordered := make([]string, 0, len(addrs))
+	ordered = append(ordered, primary)
+	for _, a := range addrs {
+		if a != primary {
+			ordered = append(ordered, a)
+		}
+	}
+	return ordered
+}
+
+// trackerConn acquires a connection to one of ClientConfig.TrackerAddrs,
+// preferring the address ClientConfig.TrackerSelector picks (if any) and
+// skipping any tracker whose circuit breaker is currently open, trying the
+// next configured address instead. It returns the address actually used
+// (or "" if TrackerAddrs is empty, in which case the pool's default
+// selection applies and neither circuit nor selector tracking is possible)
+// so the caller can report the call outcome back via circuitRecord and
+// TrackerSelector.RecordResult.
+func (c *Client) trackerConn(ctx context.Context) (conn *Connection, addr string, err error) {
+	addrs := c.config.TrackerAddrs
+	if len(addrs) == 0 {
+		conn, err = c.trackerPool.Get(ctx, "")
+		return conn, "", err
+	}
+
+	var lastErr error
+	for _, candidate := range c.trackerAddrOrder(addrs) {
+		if !c.circuitAllow(candidate) {
+			lastErr = ErrCircuitOpen
+			continue
+		}
+		conn, err = c.trackerPool.Get(ctx, candidate)
+		if err != nil {
+			lastErr = err
+			c.circuitRecord(candidate, err)
+			continue
+		}
+		return conn, candidate, nil
+	}
+	if lastErr == nil {
+		lastErr = ErrNoStorageServer
+	}
+	return nil, "", lastErr
+}
+
+// recordTrackerOutcome reports the outcome of a completed tracker RPC
+// against addr to both the circuit breaker and TrackerSelector, if
+// configured. addr is the empty string when TrackerAddrs is empty (no
+// explicit address was selected), in which case this is a no-op.
+func (c *Client) recordTrackerOutcome(addr string, start time.Time, err error) {
+	if addr == "" {
+		return
+	}
+	c.circuitRecord(addr, err)
+	if c.config.TrackerSelector != nil {
+		c.config.TrackerSelector.RecordResult(addr, time.Since(start), err)
+	}
+}
+
 // getStorageServer gets a storage server from tracker
-func (c *Client) getStorageServer(ctx context.Context, groupName string) (*StorageServer, error) {
-	conn, err := c.trackerPool.Get(ctx, "")
+func (c *Client) getStorageServer(ctx context.Context, groupName string) (result *StorageServer, err error) {
+	conn, addr, err := c.trackerConn(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer c.trackerPool.Put(conn)
+	start := time.Now()
+	defer func() { c.recordTrackerOutcome(addr, start, err) }()
 
 	// Prepare request
 	var bodyLen int64
@@ -191,20 +306,20 @@ func (c *Client) getStorageServer(ctx context.Context, groupName string) (*Stora
 	header := encodeHeader(bodyLen, cmd, 0)
 
 	// Send header
-	if err := conn.Send(header, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(header, c.networkTimeout(ctx)); err != nil {
 		return nil, err
 	}
 
 	// Send group name if specified
 	if groupName != "" {
 		groupNameBytes := padString(groupName, FdfsGroupNameMaxLen)
-		if err := conn.Send(groupNameBytes, c.config.NetworkTimeout); err != nil {
+		if err := conn.Send(groupNameBytes, c.networkTimeout(ctx)); err != nil {
 			return nil, err
 		}
 	}
 
 	// Receive response
-	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.config.NetworkTimeout)
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.networkTimeout(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -222,7 +337,7 @@ func (c *Client) getStorageServer(ctx context.Context, groupName string) (*Stora
 		return nil, ErrNoStorageServer
 	}
 
-	respBody, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.config.NetworkTimeout)
+	respBody, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.networkTimeout(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -251,22 +366,27 @@ func (c *Client) getStorageServer(ctx context.Context, groupName string) (*Stora
 // downloadFileWithRetry downloads a file with retry logic
 func (c *Client) downloadFileWithRetry(ctx context.Context, fileID string, offset, length int64) ([]byte, error) {
 	var lastErr error
-	for i := 0; i < c.config.RetryCount; i++ {
+	backoff := c.newRetryBackoff(ctx)
+	retryCount := c.retryCount(ctx)
+	for i := 0; i < retryCount; i++ {
 		data, err := c.downloadFileInternal(ctx, fileID, offset, length)
 		if err == nil {
 			return data, nil
 		}
 		lastErr = err
 
-		if err == ErrFileNotFound || err == ErrInvalidFileID {
+		if !c.retryableFunc(ctx)(err) {
 			return nil, err
 		}
 
-		if i < c.config.RetryCount-1 {
+		if i < retryCount-1 {
+			delay := backoff.Next()
+			spanFromContext(ctx).SetTag("fdfs.retry.attempt", i+1)
+			c.observerFor(ctx).OnRetry(ctx, "download_file", i+1, err, delay)
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
-			case <-time.After(time.Second * time.Duration(i+1)):
+			case <-time.After(delay):
 			}
 		}
 	}
@@ -274,7 +394,7 @@ func (c *Client) downloadFileWithRetry(ctx context.Context, fileID string, offse
 }
 
 // downloadFileInternal performs the actual file download
-func (c *Client) downloadFileInternal(ctx context.Context, fileID string, offset, length int64) ([]byte, error) {
+func (c *Client) downloadFileInternal(ctx context.Context, fileID string, offset, length int64) (data []byte, err error) {
 	groupName, remoteFilename, err := splitFileID(fileID)
 	if err != nil {
 		return nil, err
@@ -285,9 +405,18 @@ func (c *Client) downloadFileInternal(ctx context.Context, fileID string, offset
 	if err != nil {
 		return nil, err
 	}
+	storageAddr := storageServer.IPAddr + ":" + fmt.Sprintf("%d", storageServer.Port)
+
+	if !c.circuitAllow(storageAddr) {
+		return nil, ErrCircuitOpen
+	}
+	defer func() { c.circuitRecord(storageAddr, err) }()
+
+	start := time.Now()
+	defer func() { c.recordMetrics("download_file", storageAddr, fileID, start, len(data), err) }()
 
 	// Get connection
-	conn, err := c.storagePool.Get(ctx, storageServer.IPAddr+":"+fmt.Sprintf("%d", storageServer.Port))
+	conn, err := c.storagePool.Get(ctx, storageAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -303,15 +432,15 @@ func (c *Client) downloadFileInternal(ctx context.Context, fileID string, offset
 	buf.Write([]byte(remoteFilename))
 
 	// Send request
-	if err := conn.Send(header, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(header, c.networkTimeout(ctx)); err != nil {
 		return nil, err
 	}
-	if err := conn.Send(buf.Bytes(), c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(buf.Bytes(), c.networkTimeout(ctx)); err != nil {
 		return nil, err
 	}
 
 	// Receive response
-	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.config.NetworkTimeout)
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.networkTimeout(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -330,21 +459,45 @@ func (c *Client) downloadFileInternal(ctx context.Context, fileID string, offset
 	}
 
 	// Receive file data
-	data, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.config.NetworkTimeout)
+	data, err = conn.ReceiveFull(int(respHeaderParsed.Length), c.networkTimeout(ctx))
 	if err != nil {
 		return nil, err
 	}
 
+	if c.checksumMode(ctx) == ChecksumModeCRC32 {
+		if err = c.verifyCRC32(ctx, fileID, data); err != nil {
+			return nil, err
+		}
+	}
+
 	return data, nil
 }
 
+// verifyCRC32 fetches fileID's recorded CRC32 via GetFileInfo and compares
+// it against crc32.ChecksumIEEE(data), returning an error classified as
+// CodeChecksumMismatch on mismatch. Only meaningful for a whole-file
+// download: offset and length are not checked, so data from a ranged read
+// that does not cover the whole file fails with ErrChecksumMismatch.
+func (c *Client) verifyCRC32(ctx context.Context, fileID string, data []byte) error {
+	info, err := c.getFileInfoInternal(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	if crc32.ChecksumIEEE(data) != info.CRC32 {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
 // getDownloadStorageServer gets a storage server for downloading
-func (c *Client) getDownloadStorageServer(ctx context.Context, groupName, remoteFilename string) (*StorageServer, error) {
-	conn, err := c.trackerPool.Get(ctx, "")
+func (c *Client) getDownloadStorageServer(ctx context.Context, groupName, remoteFilename string) (result *StorageServer, err error) {
+	conn, addr, err := c.trackerConn(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer c.trackerPool.Put(conn)
+	start := time.Now()
+	defer func() { c.recordTrackerOutcome(addr, start, err) }()
 
 	// Build request
 	bodyLen := int64(FdfsGroupNameMaxLen + len(remoteFilename))
@@ -355,15 +508,15 @@ func (c *Client) getDownloadStorageServer(ctx context.Context, groupName, remote
 	buf.Write([]byte(remoteFilename))
 
 	// Send request
-	if err := conn.Send(header, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(header, c.networkTimeout(ctx)); err != nil {
 		return nil, err
 	}
-	if err := conn.Send(buf.Bytes(), c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(buf.Bytes(), c.networkTimeout(ctx)); err != nil {
 		return nil, err
 	}
 
 	// Receive response
-	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.config.NetworkTimeout)
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.networkTimeout(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -377,7 +530,7 @@ func (c *Client) getDownloadStorageServer(ctx context.Context, groupName, remote
 		return nil, mapStatusToError(respHeaderParsed.Status)
 	}
 
-	respBody, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.config.NetworkTimeout)
+	respBody, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.networkTimeout(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -412,22 +565,27 @@ func (c *Client) downloadToFileWithRetry(ctx context.Context, fileID, localFilen
 // deleteFileWithRetry deletes a file with retry
 func (c *Client) deleteFileWithRetry(ctx context.Context, fileID string) error {
 	var lastErr error
-	for i := 0; i < c.config.RetryCount; i++ {
+	backoff := c.newRetryBackoff(ctx)
+	retryCount := c.retryCount(ctx)
+	for i := 0; i < retryCount; i++ {
 		err := c.deleteFileInternal(ctx, fileID)
 		if err == nil {
 			return nil
 		}
 		lastErr = err
 
-		if err == ErrFileNotFound || err == ErrInvalidFileID {
+		if !c.retryableFunc(ctx)(err) {
 			return err
 		}
 
-		if i < c.config.RetryCount-1 {
+		if i < retryCount-1 {
+			delay := backoff.Next()
+			spanFromContext(ctx).SetTag("fdfs.retry.attempt", i+1)
+			c.observerFor(ctx).OnRetry(ctx, "delete_file", i+1, err, delay)
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case <-time.After(time.Second * time.Duration(i+1)):
+			case <-time.After(delay):
 			}
 		}
 	}
@@ -435,7 +593,7 @@ func (c *Client) deleteFileWithRetry(ctx context.Context, fileID string) error {
 }
 
 // deleteFileInternal performs the actual file deletion
-func (c *Client) deleteFileInternal(ctx context.Context, fileID string) error {
+func (c *Client) deleteFileInternal(ctx context.Context, fileID string) (err error) {
 	groupName, remoteFilename, err := splitFileID(fileID)
 	if err != nil {
 		return err
@@ -446,9 +604,18 @@ func (c *Client) deleteFileInternal(ctx context.Context, fileID string) error {
 	if err != nil {
 		return err
 	}
+	storageAddr := storageServer.IPAddr + ":" + fmt.Sprintf("%d", storageServer.Port)
+
+	if !c.circuitAllow(storageAddr) {
+		return ErrCircuitOpen
+	}
+	defer func() { c.circuitRecord(storageAddr, err) }()
+
+	start := time.Now()
+	defer func() { c.recordMetrics("delete_file", storageAddr, fileID, start, 0, err) }()
 
 	// Get connection
-	conn, err := c.storagePool.Get(ctx, storageServer.IPAddr+":"+fmt.Sprintf("%d", storageServer.Port))
+	conn, err := c.storagePool.Get(ctx, storageAddr)
 	if err != nil {
 		return err
 	}
@@ -463,15 +630,15 @@ func (c *Client) deleteFileInternal(ctx context.Context, fileID string) error {
 	buf.Write([]byte(remoteFilename))
 
 	// Send request
-	if err := conn.Send(header, c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(header, c.networkTimeout(ctx)); err != nil {
 		return err
 	}
-	if err := conn.Send(buf.Bytes(), c.config.NetworkTimeout); err != nil {
+	if err := conn.Send(buf.Bytes(), c.networkTimeout(ctx)); err != nil {
 		return err
 	}
 
 	// Receive response
-	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.config.NetworkTimeout)
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.networkTimeout(ctx))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,493 @@
+// Package fdfs parallel chunked upload.
+// This file adds an upload path that trades the single-connection streaming
+// of UploadStream (stream.go) for throughput on very large files: it
+// allocates an appender file, truncates it to its final size, then fans the
+// body out across N concurrent ModifyFile workers, each writing a distinct
+// offset range over its own pooled connection.
+package fdfs
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Default ParallelOptions values, chosen for multi-GB uploads over a LAN:
+// a handful of concurrent writers and megabyte-sized chunks keep pipeline
+// depth high without holding an unreasonable number of pooled connections.
+const (
+	defaultParallelConcurrency = 4
+	defaultParallelChunkSize   = 4 * 1024 * 1024
+)
+
+// parallelChecksumMetaKey is the metadata key UploadFileParallel and
+// UploadReaderParallel store the aggregate CRC32 under when
+// ParallelOptions.ChecksumPolicy is ChecksumModeCRC32.
+const parallelChecksumMetaKey = "crc32"
+
+// ParallelOptions configures UploadFileParallel and UploadReaderParallel.
+type ParallelOptions struct {
+	// Concurrency is the number of worker goroutines issuing concurrent
+	// ModifyFile writes. Defaults to 4 when <= 0.
+	Concurrency int
+
+	// ChunkSize is the size in bytes of each worker's ModifyFile write.
+	// Defaults to 4 MiB when <= 0.
+	ChunkSize int
+
+	// ChecksumPolicy controls whether an aggregate CRC32 is computed while
+	// reading the source and recorded as file metadata under the "crc32"
+	// key. The only supported value is ChecksumModeCRC32; the empty string
+	// skips the checksum.
+	ChecksumPolicy string
+
+	// KeepPartial skips the best-effort cleanup (DeleteFile for an upload,
+	// removing the destination file for DownloadFileParallel) normally attempted
+	// after a worker fails partway through, leaving whatever chunks already
+	// landed in place for inspection or a resumed retry.
+	KeepPartial bool
+
+	// Ranges restricts DownloadRangesParallel to these byte ranges instead
+	// of the whole file; nil auto-plans equal ChunkSize chunks across
+	// [0, size), the same as DownloadFileParallel. Build Ranges from an
+	// HTTP Range header with ParseRange. ChecksumPolicy is ignored when
+	// Ranges is set: a CRC32 recorded for the whole file can't verify a
+	// download of only part of it.
+	Ranges []HTTPRange
+}
+
+// withDefaults returns a copy of o with zero-valued fields replaced by their
+// defaults.
+func (o ParallelOptions) withDefaults() ParallelOptions {
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultParallelConcurrency
+	}
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = defaultParallelChunkSize
+	}
+	return o
+}
+
+// UploadFileParallel uploads a local file using concurrent ModifyFile
+// workers instead of a single streamed connection, for throughput on
+// multi-GB objects. See UploadReaderParallel for the upload strategy.
+func (c *Client) UploadFileParallel(ctx context.Context, localFilename string, opts ParallelOptions) (string, error) {
+	if err := c.checkClosed(); err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(localFilename)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	return c.uploadReaderParallel(ctx, f, info.Size(), getFileExtName(localFilename), opts)
+}
+
+// UploadReaderParallel uploads size bytes read from r using concurrent
+// ModifyFile workers instead of a single streamed connection, for
+// throughput on multi-GB objects.
+//
+// It coordinates as: (1) upload a zero-length appender file to allocate a
+// file ID, (2) TruncateFile to the final size, (3) read r sequentially into
+// ChunkSize pieces and hand each piece's offset/data to a pool of
+// Concurrency workers that issue ModifyFile writes in parallel, (4) once
+// every worker has succeeded, SetMetadata the aggregate CRC32 computed
+// in-flight while reading r.
+//
+// The first worker error cancels every other worker and a best-effort
+// DeleteFile of the partial upload is attempted before the error is
+// returned.
+func (c *Client) UploadReaderParallel(ctx context.Context, r io.Reader, size int64, fileExtName string, opts ParallelOptions) (string, error) {
+	if err := c.checkClosed(); err != nil {
+		return "", err
+	}
+
+	return c.uploadReaderParallel(ctx, r, size, fileExtName, opts)
+}
+
+func (c *Client) uploadReaderParallel(ctx context.Context, r io.Reader, size int64, fileExtName string, opts ParallelOptions) (fileID string, err error) {
+	opts = opts.withDefaults()
+
+	fileID, err = c.uploadBufferWithRetry(ctx, nil, fileExtName, nil, true)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if err != nil && !opts.KeepPartial {
+			c.deleteFileWithRetry(context.Background(), fileID)
+		}
+	}()
+
+	if err = c.truncateFileWithRetry(ctx, fileID, size); err != nil {
+		return "", err
+	}
+
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type workItem struct {
+		offset int64
+		data   []byte
+	}
+
+	items := make(chan workItem, opts.Concurrency)
+
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		workErr error
+	)
+	fail := func(e error) {
+		errOnce.Do(func() {
+			workErr = e
+			cancel()
+		})
+	}
+
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range items {
+				if werr := c.modifyFileWithRetry(workerCtx, fileID, item.offset, item.data); werr != nil {
+					fail(werr)
+					return
+				}
+			}
+		}()
+	}
+
+	var checksum hash.Hash32
+	if opts.ChecksumPolicy == ChecksumModeCRC32 {
+		checksum = crc32.NewIEEE()
+	}
+
+	buf := make([]byte, opts.ChunkSize)
+	var offset int64
+readLoop:
+	for offset < size {
+		n, rerr := io.ReadFull(r, buf)
+		if rerr != nil && rerr != io.EOF && rerr != io.ErrUnexpectedEOF {
+			fail(rerr)
+			break
+		}
+		if n > 0 {
+			if checksum != nil {
+				checksum.Write(buf[:n])
+			}
+			chunk := make([]byte, n)
+			copy(chunk, buf[:n])
+			select {
+			case items <- workItem{offset: offset, data: chunk}:
+			case <-workerCtx.Done():
+				break readLoop
+			}
+			offset += int64(n)
+		}
+		if rerr != nil {
+			break
+		}
+	}
+	close(items)
+	wg.Wait()
+
+	if workErr != nil {
+		return "", workErr
+	}
+	if err = ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if checksum != nil {
+		meta := map[string]string{parallelChecksumMetaKey: fmt.Sprintf("%08x", checksum.Sum32())}
+		if err = c.setMetadataWithRetry(ctx, fileID, meta, MetadataMerge); err != nil {
+			return "", err
+		}
+	}
+
+	return fileID, nil
+}
+
+// DownloadFileParallel downloads fileID to localFilename using concurrent
+// range downloads instead of a single streamed connection, for throughput
+// on multi-GB objects.
+//
+// It coordinates as: (1) GetFileInfo to learn the total size, (2) split
+// [0, size) into ChunkSize ranges and hand each to a pool of Concurrency
+// workers that call downloadFileWithRetry (so each range gets its own
+// retry budget and, on a downed storage node, its own fresh
+// getDownloadStorageServer lookup) and write the result into localFilename
+// at the range's offset via WriteAt, (3) when ChecksumPolicy is
+// ChecksumModeCRC32, re-read the completed file and compare its CRC32
+// against FileInfo.CRC32.
+//
+// The first worker error cancels every other worker; unless
+// ParallelOptions.KeepPartial is set, the partially-written destination
+// file is removed before the error is returned.
+func (c *Client) DownloadFileParallel(ctx context.Context, fileID, localFilename string, opts ParallelOptions) (err error) {
+	if err := c.checkClosed(); err != nil {
+		return err
+	}
+	opts = opts.withDefaults()
+
+	info, err := c.GetFileInfo(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	size := info.FileSize
+
+	out, err := os.OpenFile(localFilename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer func() {
+		out.Close()
+		if err != nil && !opts.KeepPartial {
+			os.Remove(localFilename)
+		}
+	}()
+
+	if size == 0 {
+		return nil
+	}
+	if err = out.Truncate(size); err != nil {
+		return fmt.Errorf("failed to preallocate file: %w", err)
+	}
+
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type byteRange struct {
+		offset int64
+		length int64
+	}
+
+	ranges := make(chan byteRange, opts.Concurrency)
+
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		workErr error
+	)
+	fail := func(e error) {
+		errOnce.Do(func() {
+			workErr = e
+			cancel()
+		})
+	}
+
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range ranges {
+				data, derr := c.downloadFileWithRetry(workerCtx, fileID, r.offset, r.length)
+				if derr != nil {
+					fail(derr)
+					return
+				}
+				if _, werr := out.WriteAt(data, r.offset); werr != nil {
+					fail(fmt.Errorf("failed to write downloaded range at offset %d: %w", r.offset, werr))
+					return
+				}
+			}
+		}()
+	}
+
+	chunkSize := int64(opts.ChunkSize)
+sendLoop:
+	for offset := int64(0); offset < size; offset += chunkSize {
+		length := chunkSize
+		if remaining := size - offset; remaining < length {
+			length = remaining
+		}
+		select {
+		case ranges <- byteRange{offset: offset, length: length}:
+		case <-workerCtx.Done():
+			break sendLoop
+		}
+	}
+	close(ranges)
+	wg.Wait()
+
+	if workErr != nil {
+		return workErr
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	if opts.ChecksumPolicy == ChecksumModeCRC32 {
+		if err = c.verifyLocalCRC32(localFilename, info.CRC32); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyLocalCRC32 streams localFilename and compares its CRC32 against
+// want, returning ErrChecksumMismatch on mismatch, for
+// DownloadFileParallel's post-download integrity check.
+func (c *Client) verifyLocalCRC32(localFilename string, want uint32) error {
+	f, err := os.Open(localFilename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	checksum := crc32.NewIEEE()
+	if _, err := io.Copy(checksum, f); err != nil {
+		return err
+	}
+	if checksum.Sum32() != want {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// DownloadRangesParallel fetches fileID's byte ranges (opts.Ranges, or
+// equal ChunkSize chunks across the whole file when nil) using Concurrency
+// concurrent workers and writes each one into w at its range's offset via
+// WriteAt, instead of requiring a local destination file the way
+// DownloadFileParallel does. It's the same worker/retry strategy as
+// DownloadFileParallel, generalized from *os.File to any io.WriterAt (e.g.
+// an mmap'd region, or a ring buffer a caller is streaming elsewhere), and
+// returns the number of bytes written alongside any error, following
+// io.Copy's convention for a partial result.
+//
+// Each range is fetched via downloadFileWithRetry, so a failed range is
+// retried with its own fresh getDownloadStorageServer lookup, landing on
+// whatever storage replica the tracker hands back next rather than the one
+// that just failed.
+//
+// When opts.ChecksumPolicy is ChecksumModeCRC32, opts.Ranges is nil (the
+// whole file was fetched), and w also implements io.ReaderAt, the result is
+// read back and compared against FileInfo.CRC32, returning
+// ErrChecksumMismatch on mismatch. w not implementing io.ReaderAt (e.g. a
+// plain network sink) skips verification rather than failing: there's no
+// portable way to read back what was written through WriteAt alone.
+func (c *Client) DownloadRangesParallel(ctx context.Context, fileID string, w io.WriterAt, opts *ParallelOptions) (int64, error) {
+	if err := c.checkClosed(); err != nil {
+		return 0, err
+	}
+	resolved := ParallelOptions{}
+	if opts != nil {
+		resolved = *opts
+	}
+	resolved = resolved.withDefaults()
+
+	info, err := c.GetFileInfo(ctx, fileID)
+	if err != nil {
+		return 0, err
+	}
+	size := info.FileSize
+
+	ranges := resolved.Ranges
+	if ranges == nil {
+		chunkSize := int64(resolved.ChunkSize)
+		for offset := int64(0); offset < size; offset += chunkSize {
+			length := chunkSize
+			if remaining := size - offset; remaining < length {
+				length = remaining
+			}
+			ranges = append(ranges, HTTPRange{Start: offset, Length: length})
+		}
+	}
+	if len(ranges) == 0 {
+		return 0, nil
+	}
+
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rangeCh := make(chan HTTPRange, resolved.Concurrency)
+
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		workErr error
+		written int64
+	)
+	fail := func(e error) {
+		errOnce.Do(func() {
+			workErr = e
+			cancel()
+		})
+	}
+
+	for i := 0; i < resolved.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range rangeCh {
+				data, derr := c.downloadFileWithRetry(workerCtx, fileID, r.Start, r.Length)
+				if derr != nil {
+					fail(derr)
+					return
+				}
+				if _, werr := w.WriteAt(data, r.Start); werr != nil {
+					fail(fmt.Errorf("failed to write downloaded range at offset %d: %w", r.Start, werr))
+					return
+				}
+				atomic.AddInt64(&written, int64(len(data)))
+			}
+		}()
+	}
+
+sendLoop:
+	for _, r := range ranges {
+		select {
+		case rangeCh <- r:
+		case <-workerCtx.Done():
+			break sendLoop
+		}
+	}
+	close(rangeCh)
+	wg.Wait()
+
+	total := atomic.LoadInt64(&written)
+	if workErr != nil {
+		return total, workErr
+	}
+	if err := ctx.Err(); err != nil {
+		return total, err
+	}
+
+	if resolved.ChecksumPolicy == ChecksumModeCRC32 && resolved.Ranges == nil {
+		if ra, ok := w.(io.ReaderAt); ok {
+			if err := verifyReaderAtCRC32(ra, size, info.CRC32); err != nil {
+				return total, err
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// verifyReaderAtCRC32 reads the first size bytes visible through r and
+// compares their CRC32 against want, for DownloadRangesParallel's
+// post-download integrity check when its destination also supports reads.
+func verifyReaderAtCRC32(r io.ReaderAt, size int64, want uint32) error {
+	checksum := crc32.NewIEEE()
+	if _, err := io.Copy(checksum, io.NewSectionReader(r, 0, size)); err != nil {
+		return err
+	}
+	if checksum.Sum32() != want {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
@@ -0,0 +1,818 @@
+// Package fdfs concurrency pipeline.
+// This file adds a reusable worker-pool abstraction over Client, replacing
+// the goroutine/WaitGroup/mutex-guarded-slice scaffolding that otherwise
+// gets copy-pasted into every batch upload/download/delete workload. It
+// streams results back on a channel rather than returning a slice, so a
+// caller can drive millions of files without holding them all in memory at
+// once.
+package fdfs
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultPoolConcurrency is used when Pool.Concurrency is <= 0.
+const defaultPoolConcurrency = 8
+
+// Pool fans UploadAll/DownloadAll/DeleteAll work out across a bounded number
+// of goroutines (Concurrency), optionally pacing starts with RateLimit and
+// retrying failures with RetryPolicy, internally using the same
+// worker-pool-over-a-channel shape golang.org/x/sync/errgroup provides,
+// without taking on the external dependency.
+type Pool struct {
+	client *Client
+
+	// Concurrency is the number of worker goroutines processing jobs.
+	// Defaults to 8 when <= 0.
+	Concurrency int
+
+	// RateLimit, if set, caps how often a new operation is started,
+	// independent of Concurrency (which caps how many run at once).
+	RateLimit *RateLimiter
+
+	// RetryPolicy controls the backoff used between retry attempts for a
+	// failed job. Defaults to the client's configured/default RetryPolicy
+	// (see retry.go) when nil.
+	RetryPolicy RetryPolicy
+
+	// StopOnError cancels every other in-flight and queued job as soon as
+	// one job's retries are exhausted. Already-started jobs still report
+	// their result; jobs not yet started report ctx.Err().
+	StopOnError bool
+
+	// Reporters receive a Stats snapshot every ReportInterval while a batch
+	// call (UploadAll/DownloadAll/DeleteAll) has work in flight. Unset by
+	// default, so batch calls carry no progress-reporting overhead unless
+	// asked for.
+	Reporters []ProgressReporter
+
+	// ReportInterval is how often Reporters are pushed a Stats snapshot.
+	// Defaults to 1s when <= 0.
+	ReportInterval time.Duration
+
+	// Total is the number of jobs a batch call is expected to process, used
+	// only to estimate Stats.ETA. Leave unset (0) when the job count isn't
+	// known ahead of time; ETA is then always 0.
+	Total int64
+
+	// Adaptive, when true, ignores Concurrency and instead sizes the
+	// worker pool dynamically using client's ClientConfig.AdaptiveConcurrency,
+	// discovering the concurrency sweet spot for the current tracker/
+	// storage cluster instead of requiring one hard-coded up front. A no-op
+	// (falls back to Concurrency) when ClientConfig.AdaptiveConcurrency is
+	// unset.
+	Adaptive bool
+
+	progressMu sync.Mutex
+	progress   *progressTracker
+}
+
+// NewPool returns a Pool driving client.
+func NewPool(client *Client, concurrency int) *Pool {
+	return &Pool{client: client, Concurrency: concurrency}
+}
+
+// Stats returns a snapshot of the current (or most recent) batch call's
+// progress. The zero Stats is returned if no batch call has run yet.
+func (p *Pool) Stats() Stats {
+	p.progressMu.Lock()
+	defer p.progressMu.Unlock()
+	if p.progress == nil {
+		return Stats{}
+	}
+	return p.progress.snapshot()
+}
+
+// startProgress installs a fresh progressTracker for a new batch call and
+// starts its reporter goroutine, returning a stop func to call once every
+// job has been dispatched.
+func (p *Pool) startProgress() (track *progressTracker, stop func()) {
+	track = newProgressTracker(p.Total)
+
+	p.progressMu.Lock()
+	p.progress = track
+	p.progressMu.Unlock()
+
+	var concurrencyFn func() int
+	if p.Adaptive && p.client.adaptive != nil {
+		concurrencyFn = p.client.adaptive.concurrency
+	}
+
+	done := make(chan struct{})
+	go runReporters(track, p.Reporters, p.ReportInterval, done, concurrencyFn)
+	return track, func() { close(done) }
+}
+
+func (p *Pool) concurrency() int {
+	if p.Concurrency <= 0 {
+		return defaultPoolConcurrency
+	}
+	return p.Concurrency
+}
+
+// adaptiveGate returns the semaphore and controller an Adaptive Pool gates
+// work through, or (nil, nil) when Adaptive is unset or the Client has no
+// AdaptiveConcurrency configured.
+func (p *Pool) adaptiveGate() (*elasticSemaphore, *adaptiveConcurrencyController) {
+	if !p.Adaptive || p.client.adaptive == nil {
+		return nil, nil
+	}
+	return newElasticSemaphore(p.client.adaptive.concurrency()), p.client.adaptive
+}
+
+// workerCount is how many goroutines a batch call starts: under Adaptive,
+// that's the configured ceiling (workers beyond the controller's current
+// concurrency simply block on the adaptiveGate semaphore), otherwise it's
+// the static Concurrency.
+func (p *Pool) workerCount() int {
+	if p.Adaptive && p.client.adaptive != nil {
+		return p.client.config.AdaptiveConcurrency.withDefaults().MaxConcurrency
+	}
+	return p.concurrency()
+}
+
+func (p *Pool) retryPolicy() RetryPolicy {
+	if p.RetryPolicy != nil {
+		return p.RetryPolicy
+	}
+	return p.client.retryPolicy()
+}
+
+// withRetry runs op, retrying per Pool.RetryPolicy until it succeeds, the
+// error is non-retryable, the policy is exhausted (Next returns 0), or ctx
+// is done. attempts is how many times op was called (always >= 1). cmd
+// labels the retries metric emitted for each retry beyond the first (see
+// recordRetry); pass "" to skip metrics for call sites that don't have a
+// single representative command name.
+func (p *Pool) withRetry(ctx context.Context, cmd string, op func() error) (attempts int, err error) {
+	backoff := p.retryPolicy().NewState()
+	for {
+		attempts++
+		err = op()
+		if err == nil || isNonRetryable(err) {
+			p.recordRetry(cmd, attempts, err)
+			return attempts, err
+		}
+
+		delay := backoff.Next()
+		if delay <= 0 {
+			p.recordRetry(cmd, attempts, err)
+			return attempts, err
+		}
+
+		select {
+		case <-ctx.Done():
+			p.recordRetry(cmd, attempts, ctx.Err())
+			return attempts, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// recordRetry emits a retries counter for every attempt beyond the first,
+// tagged by the final outcome so a dashboard can separate "retried then
+// succeeded" from "retried until exhausted". A no-op when cmd is "" (the
+// call site has no single representative command name) or the job
+// succeeded on the first attempt.
+func (p *Pool) recordRetry(cmd string, attempts int, err error) {
+	if cmd == "" || attempts <= 1 {
+		return
+	}
+	reason := "exhausted"
+	if err == nil {
+		reason = "succeeded"
+	}
+	p.client.metrics().Counter("fdfs.retries", map[string]string{"cmd": cmd, "reason": reason}).Add(float64(attempts - 1))
+}
+
+// UploadJob is one unit of work for UploadAll: either LocalFilename (read
+// from disk) or Data+FileExtName (an in-memory buffer) must be set.
+type UploadJob struct {
+	LocalFilename string
+	Data          []byte
+	FileExtName   string
+	Metadata      map[string]string
+}
+
+// UploadResult is the outcome of one UploadJob.
+type UploadResult struct {
+	Job    UploadJob
+	FileID string
+	Err    error
+
+	// Index is this job's 0-based position in the order it was pulled off
+	// jobs, for correlating a result back to caller-side bookkeeping that
+	// doesn't round-trip through UploadJob itself.
+	Index int
+	// Attempts is how many times the upload was tried, including the
+	// first attempt (always >= 1 once a result is sent for a job that
+	// reached the upload itself, 0 for jobs rejected by rate limiting or
+	// the adaptive gate before ever attempting the upload).
+	Attempts int
+	// Duration is how long the final (successful or exhausted) attempt
+	// sequence took, from first attempt to last.
+	Duration time.Duration
+	// BytesTransferred is len(Job.Data) for in-memory uploads; 0 for
+	// UploadFile (local-disk) jobs, since the byte count isn't known
+	// without a stat the caller may not want to pay for.
+	BytesTransferred int64
+
+	// DedupHit is true when BatchUploadIfMissing resolved this job from the
+	// Client's DigestIndex without re-uploading it. Always false for
+	// UploadAll, which never consults the DigestIndex.
+	DedupHit bool
+}
+
+// UploadAll uploads every job received from jobs across Pool.Concurrency
+// workers, returning a channel of one UploadResult per job. The returned
+// channel is closed once jobs is closed (or drained, under StopOnError) and
+// every in-flight job has reported.
+func (p *Pool) UploadAll(ctx context.Context, jobs <-chan UploadJob) <-chan UploadResult {
+	results := make(chan UploadResult)
+	ctx, cancel := context.WithCancel(ctx)
+	track, stopProgress := p.startProgress()
+	sem, adaptive := p.adaptiveGate()
+	var nextIndex int64
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.workerCount(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				idx := int(atomic.AddInt64(&nextIndex, 1) - 1)
+
+				if err := p.waitRateLimit(ctx); err != nil {
+					sendUploadResult(ctx, results, UploadResult{Job: job, Err: err, Index: idx})
+					p.stopIfErr(err, cancel)
+					continue
+				}
+				if sem != nil {
+					if err := sem.acquire(ctx); err != nil {
+						sendUploadResult(ctx, results, UploadResult{Job: job, Err: err, Index: idx})
+						p.stopIfErr(err, cancel)
+						continue
+					}
+				}
+
+				track.startOp()
+				start := time.Now()
+				var fileID string
+				attempts, err := p.withRetry(ctx, "upload", func() error {
+					var innerErr error
+					if job.LocalFilename != "" {
+						fileID, innerErr = p.client.UploadFile(ctx, job.LocalFilename, job.Metadata)
+					} else {
+						fileID, innerErr = p.client.UploadBuffer(ctx, job.Data, job.FileExtName, job.Metadata)
+					}
+					return innerErr
+				})
+				latency := time.Since(start)
+				track.finish(int64(len(job.Data)), latency, err)
+				if sem != nil {
+					adaptive.recordOp(latency, err)
+					sem.setLimit(adaptive.concurrency())
+					sem.release()
+				}
+
+				sendUploadResult(ctx, results, UploadResult{
+					Job: job, FileID: fileID, Err: err,
+					Index: idx, Attempts: attempts, Duration: latency, BytesTransferred: int64(len(job.Data)),
+				})
+				p.stopIfErr(err, cancel)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		stopProgress()
+		cancel()
+		close(results)
+	}()
+
+	return results
+}
+
+// BatchUploadIfMissing is UploadAll's dedup-aware counterpart: each job's
+// content is checked against client.config.DigestIndex before uploading, and
+// a cache hit is resolved without going through the tracker/storage servers
+// at all (Attempts stays 0, DedupHit is true). jobs must use Data (not
+// LocalFilename), since the digest is computed from Job.Data. Behaves
+// exactly like UploadAll when client.config.DigestIndex is nil.
+func (p *Pool) BatchUploadIfMissing(ctx context.Context, jobs <-chan UploadJob) <-chan UploadResult {
+	results := make(chan UploadResult)
+	ctx, cancel := context.WithCancel(ctx)
+	track, stopProgress := p.startProgress()
+	sem, adaptive := p.adaptiveGate()
+	var nextIndex int64
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.workerCount(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				idx := int(atomic.AddInt64(&nextIndex, 1) - 1)
+
+				if fileID, hit := p.dedupLookup(job.Data); hit {
+					track.startOp()
+					track.finish(0, 0, nil)
+					sendUploadResult(ctx, results, UploadResult{
+						Job: job, FileID: fileID, Index: idx, DedupHit: true,
+					})
+					continue
+				}
+
+				if err := p.waitRateLimit(ctx); err != nil {
+					sendUploadResult(ctx, results, UploadResult{Job: job, Err: err, Index: idx})
+					p.stopIfErr(err, cancel)
+					continue
+				}
+				if sem != nil {
+					if err := sem.acquire(ctx); err != nil {
+						sendUploadResult(ctx, results, UploadResult{Job: job, Err: err, Index: idx})
+						p.stopIfErr(err, cancel)
+						continue
+					}
+				}
+
+				track.startOp()
+				start := time.Now()
+				var fileID string
+				attempts, err := p.withRetry(ctx, "upload", func() error {
+					var innerErr error
+					fileID, innerErr = p.client.UploadBuffer(ctx, job.Data, job.FileExtName, job.Metadata)
+					return innerErr
+				})
+				latency := time.Since(start)
+				track.finish(int64(len(job.Data)), latency, err)
+				if sem != nil {
+					adaptive.recordOp(latency, err)
+					sem.setLimit(adaptive.concurrency())
+					sem.release()
+				}
+				if err == nil {
+					p.dedupStore(job.Data, fileID)
+				}
+
+				sendUploadResult(ctx, results, UploadResult{
+					Job: job, FileID: fileID, Err: err,
+					Index: idx, Attempts: attempts, Duration: latency, BytesTransferred: int64(len(job.Data)),
+				})
+				p.stopIfErr(err, cancel)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		stopProgress()
+		cancel()
+		close(results)
+	}()
+
+	return results
+}
+
+// dedupLookup checks client.config.DigestIndex for data's digest, returning
+// (fileID, true) on a hit. Always (_, false) when no DigestIndex is
+// configured.
+func (p *Pool) dedupLookup(data []byte) (string, bool) {
+	if p.client.config.DigestIndex == nil {
+		return "", false
+	}
+	return p.client.config.DigestIndex.Lookup(ComputeDigest(data))
+}
+
+// dedupStore records data's digest → fileID in client.config.DigestIndex, a
+// no-op when none is configured. Errors are dropped: a failed index write
+// only costs a future dedup opportunity, not correctness.
+func (p *Pool) dedupStore(data []byte, fileID string) {
+	if p.client.config.DigestIndex == nil {
+		return
+	}
+	p.client.config.DigestIndex.Store(ComputeDigest(data), fileID)
+}
+
+// DownloadResult is the outcome of downloading one file ID.
+type DownloadResult struct {
+	FileID string
+	Data   []byte
+	Err    error
+
+	// Index is this file ID's 0-based position in the order it was pulled
+	// off ids.
+	Index int
+	// Attempts is how many times the download was tried, including the
+	// first attempt; 0 for jobs rejected by rate limiting or the adaptive
+	// gate before ever attempting the download.
+	Attempts int
+	// Duration is how long the final attempt sequence took.
+	Duration time.Duration
+	// BytesTransferred is len(Data).
+	BytesTransferred int64
+}
+
+// DownloadAll downloads every file ID received from ids across
+// Pool.Concurrency workers, returning a channel of one DownloadResult per
+// ID.
+func (p *Pool) DownloadAll(ctx context.Context, ids <-chan string) <-chan DownloadResult {
+	results := make(chan DownloadResult)
+	ctx, cancel := context.WithCancel(ctx)
+	track, stopProgress := p.startProgress()
+	sem, adaptive := p.adaptiveGate()
+	var nextIndex int64
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.workerCount(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for fileID := range ids {
+				idx := int(atomic.AddInt64(&nextIndex, 1) - 1)
+
+				if err := p.waitRateLimit(ctx); err != nil {
+					sendDownloadResult(ctx, results, DownloadResult{FileID: fileID, Err: err, Index: idx})
+					p.stopIfErr(err, cancel)
+					continue
+				}
+				if sem != nil {
+					if err := sem.acquire(ctx); err != nil {
+						sendDownloadResult(ctx, results, DownloadResult{FileID: fileID, Err: err, Index: idx})
+						p.stopIfErr(err, cancel)
+						continue
+					}
+				}
+
+				track.startOp()
+				start := time.Now()
+				var data []byte
+				attempts, err := p.withRetry(ctx, "download", func() error {
+					var innerErr error
+					data, innerErr = p.client.DownloadFile(ctx, fileID)
+					return innerErr
+				})
+				latency := time.Since(start)
+				track.finish(int64(len(data)), latency, err)
+				if sem != nil {
+					adaptive.recordOp(latency, err)
+					recordGroupLatency(adaptive, fileID, latency, err)
+					sem.setLimit(adaptive.concurrency())
+					sem.release()
+				}
+
+				sendDownloadResult(ctx, results, DownloadResult{
+					FileID: fileID, Data: data, Err: err,
+					Index: idx, Attempts: attempts, Duration: latency, BytesTransferred: int64(len(data)),
+				})
+				p.stopIfErr(err, cancel)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		stopProgress()
+		cancel()
+		close(results)
+	}()
+
+	return results
+}
+
+// DeleteResult is the outcome of deleting one file ID.
+type DeleteResult struct {
+	FileID string
+	Err    error
+
+	// Index is this file ID's 0-based position in the order it was pulled
+	// off ids.
+	Index int
+	// Attempts is how many times the delete was tried, including the
+	// first attempt; 0 for jobs rejected by rate limiting or the adaptive
+	// gate before ever attempting the delete.
+	Attempts int
+	// Duration is how long the final attempt sequence took.
+	Duration time.Duration
+}
+
+// DeleteAll deletes every file ID received from ids across Pool.Concurrency
+// workers, returning a channel of one DeleteResult per ID.
+func (p *Pool) DeleteAll(ctx context.Context, ids <-chan string) <-chan DeleteResult {
+	results := make(chan DeleteResult)
+	ctx, cancel := context.WithCancel(ctx)
+	track, stopProgress := p.startProgress()
+	sem, adaptive := p.adaptiveGate()
+	var nextIndex int64
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.workerCount(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for fileID := range ids {
+				idx := int(atomic.AddInt64(&nextIndex, 1) - 1)
+
+				if err := p.waitRateLimit(ctx); err != nil {
+					sendDeleteResult(ctx, results, DeleteResult{FileID: fileID, Err: err, Index: idx})
+					p.stopIfErr(err, cancel)
+					continue
+				}
+				if sem != nil {
+					if err := sem.acquire(ctx); err != nil {
+						sendDeleteResult(ctx, results, DeleteResult{FileID: fileID, Err: err, Index: idx})
+						p.stopIfErr(err, cancel)
+						continue
+					}
+				}
+
+				track.startOp()
+				start := time.Now()
+				attempts, err := p.withRetry(ctx, "delete", func() error {
+					return p.client.DeleteFile(ctx, fileID)
+				})
+				latency := time.Since(start)
+				track.finish(0, latency, err)
+				if sem != nil {
+					adaptive.recordOp(latency, err)
+					recordGroupLatency(adaptive, fileID, latency, err)
+					sem.setLimit(adaptive.concurrency())
+					sem.release()
+				}
+
+				sendDeleteResult(ctx, results, DeleteResult{
+					FileID: fileID, Err: err,
+					Index: idx, Attempts: attempts, Duration: latency,
+				})
+				p.stopIfErr(err, cancel)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		stopProgress()
+		cancel()
+		close(results)
+	}()
+
+	return results
+}
+
+// recordGroupLatency feeds one completed operation's latency into
+// adaptive's per-storage-group tracking (see GroupLatencies), a no-op when
+// fileID isn't a well-formed "group/remote_filename" ID (e.g. an upload
+// that failed before the tracker assigned one).
+func recordGroupLatency(adaptive *adaptiveConcurrencyController, fileID string, latency time.Duration, err error) {
+	group, _, splitErr := splitFileID(fileID)
+	if splitErr != nil {
+		return
+	}
+	adaptive.recordGroupOp(group, latency, err)
+}
+
+// waitRateLimit blocks for a token when Pool.RateLimit is set.
+func (p *Pool) waitRateLimit(ctx context.Context) error {
+	if p.RateLimit == nil {
+		return nil
+	}
+	return p.RateLimit.Wait(ctx)
+}
+
+// stopIfErr cancels the pool's worker context when err is non-nil and
+// StopOnError is set.
+func (p *Pool) stopIfErr(err error, cancel context.CancelFunc) {
+	if err != nil && p.StopOnError {
+		cancel()
+	}
+}
+
+// sendUploadResult sends result, giving up (without blocking forever) if
+// ctx is done before a receiver is ready.
+func sendUploadResult(ctx context.Context, results chan<- UploadResult, result UploadResult) {
+	select {
+	case results <- result:
+	case <-ctx.Done():
+	}
+}
+
+// sendDownloadResult is sendUploadResult's counterpart for DownloadAll.
+func sendDownloadResult(ctx context.Context, results chan<- DownloadResult, result DownloadResult) {
+	select {
+	case results <- result:
+	case <-ctx.Done():
+	}
+}
+
+// sendDeleteResult is sendUploadResult's counterpart for DeleteAll.
+func sendDeleteResult(ctx context.Context, results chan<- DeleteResult, result DeleteResult) {
+	select {
+	case results <- result:
+	case <-ctx.Done():
+	}
+}
+
+// RateLimiter is a simple token-bucket limiter used to cap how often a Pool
+// starts new operations, independent of Concurrency (which caps how many
+// run at once).
+type RateLimiter struct {
+	mu      sync.Mutex
+	tokens  float64
+	burst   float64
+	perSec  float64
+	lastRef time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing opsPerSec sustained
+// operations per second with bursts of up to burst tokens.
+func NewRateLimiter(opsPerSec float64, burst int) *RateLimiter {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &RateLimiter{
+		tokens:  float64(burst),
+		burst:   float64(burst),
+		perSec:  opsPerSec,
+		lastRef: time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	return r.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n tokens are available or ctx is done, for limiters
+// counting something other than one-token-per-operation (e.g. bytes rather
+// than requests). n larger than burst is granted the whole bucket once it's
+// full, rather than blocking forever.
+func (r *RateLimiter) WaitN(ctx context.Context, n int) error {
+	want := float64(n)
+	if want > r.burst {
+		want = r.burst
+	}
+	for {
+		r.mu.Lock()
+		now := time.Now()
+		r.tokens += now.Sub(r.lastRef).Seconds() * r.perSec
+		if r.tokens > r.burst {
+			r.tokens = r.burst
+		}
+		r.lastRef = now
+
+		if r.tokens >= want {
+			r.tokens -= want
+			r.mu.Unlock()
+			return nil
+		}
+
+		var wait time.Duration
+		if r.perSec > 0 {
+			wait = time.Duration((want - r.tokens) / r.perSec * float64(time.Second))
+		}
+		r.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// BatchStats summarizes an entire UploadAll/DownloadAll/DeleteAll call,
+// aggregated by WaitUploads/WaitDownloads/WaitDeletes from every result on
+// the channel those methods returned.
+type BatchStats struct {
+	Succeeded int64
+	Failed    int64
+
+	// DedupHits is how many of Succeeded were resolved by
+	// BatchUploadIfMissing from the DigestIndex without re-uploading.
+	// Always 0 for WaitDownloads/WaitDeletes and for plain UploadAll.
+	DedupHits int64
+	// DedupHitRate is DedupHits / (Succeeded + Failed), or 0 when that's 0.
+	DedupHitRate float64
+
+	MinLatency time.Duration
+	AvgLatency time.Duration
+	MaxLatency time.Duration
+	P50Latency time.Duration
+	P95Latency time.Duration
+	P99Latency time.Duration
+
+	BytesTransferred int64
+	Elapsed          time.Duration
+	OpsPerSec        float64
+	MBPerSec         float64
+}
+
+// WaitUploads drains results (as returned by UploadAll or
+// BatchUploadIfMissing) until it is closed and returns a BatchStats
+// aggregated across every job.
+func (p *Pool) WaitUploads(results <-chan UploadResult) BatchStats {
+	start := time.Now()
+	var succeeded, failed, bytes, dedupHits int64
+	var latencies []time.Duration
+	for r := range results {
+		if r.Err != nil {
+			failed++
+			continue
+		}
+		succeeded++
+		bytes += r.BytesTransferred
+		if r.DedupHit {
+			dedupHits++
+		} else {
+			latencies = append(latencies, r.Duration)
+		}
+	}
+	stats := batchStats(succeeded, failed, bytes, latencies, time.Since(start))
+	stats.DedupHits = dedupHits
+	if total := succeeded + failed; total > 0 {
+		stats.DedupHitRate = float64(dedupHits) / float64(total)
+	}
+	return stats
+}
+
+// WaitDownloads is WaitUploads' counterpart for a channel returned by
+// DownloadAll.
+func (p *Pool) WaitDownloads(results <-chan DownloadResult) BatchStats {
+	start := time.Now()
+	var succeeded, failed, bytes int64
+	var latencies []time.Duration
+	for r := range results {
+		if r.Err != nil {
+			failed++
+			continue
+		}
+		succeeded++
+		bytes += r.BytesTransferred
+		latencies = append(latencies, r.Duration)
+	}
+	return batchStats(succeeded, failed, bytes, latencies, time.Since(start))
+}
+
+// WaitDeletes is WaitUploads' counterpart for a channel returned by
+// DeleteAll. BatchStats.BytesTransferred and MBPerSec are always 0, since
+// deletes carry no payload.
+func (p *Pool) WaitDeletes(results <-chan DeleteResult) BatchStats {
+	start := time.Now()
+	var succeeded, failed int64
+	var latencies []time.Duration
+	for r := range results {
+		if r.Err != nil {
+			failed++
+			continue
+		}
+		succeeded++
+		latencies = append(latencies, r.Duration)
+	}
+	return batchStats(succeeded, failed, 0, latencies, time.Since(start))
+}
+
+// batchStats computes a BatchStats from the raw per-op samples collected by
+// WaitUploads/WaitDownloads/WaitDeletes, mirroring bench.Stat.report's
+// percentile math (see bench/bench.go).
+func batchStats(succeeded, failed, bytes int64, latencies []time.Duration, elapsed time.Duration) BatchStats {
+	stats := BatchStats{
+		Succeeded:        succeeded,
+		Failed:           failed,
+		BytesTransferred: bytes,
+		Elapsed:          elapsed,
+	}
+	if elapsed > 0 {
+		stats.OpsPerSec = float64(succeeded+failed) / elapsed.Seconds()
+		stats.MBPerSec = float64(bytes) / 1024 / 1024 / elapsed.Seconds()
+	}
+	if len(latencies) == 0 {
+		return stats
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	stats.MinLatency = latencies[0]
+	stats.MaxLatency = latencies[len(latencies)-1]
+	stats.P50Latency = latencyPercentile(latencies, 50)
+	stats.P95Latency = latencyPercentile(latencies, 95)
+	stats.P99Latency = latencyPercentile(latencies, 99)
+
+	var sum time.Duration
+	for _, d := range latencies {
+		sum += d
+	}
+	stats.AvgLatency = sum / time.Duration(len(latencies))
+
+	return stats
+}
+
+// latencyPercentile returns the latency at percentile p (0-100) across
+// sorted, which must already be sorted ascending.
+func latencyPercentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(float64(len(sorted)-1) * p / 100)
+	return sorted[idx]
+}
@@ -0,0 +1,59 @@
+// Package fdfs pool metrics and health inspection.
+// This file exposes ConnectionPool's per-address PoolStats (connection.go)
+// on Client for both tracker and storage pools together, plus two
+// operator-facing actions built on top of it: Ping, which exercises every
+// known tracker and storage address with the existing
+// FdfsProtoCmdActiveTest no-op (see trackerselector.go's activeTest,
+// shared with probeTracker), and EvictIdle, which force-closes idle
+// connections on demand rather than waiting for the periodic idle-timeout
+// sweep in maybeClean.
+package fdfs
+
+import "context"
+
+// ClientPoolStats reports ConnectionPool.Stats for both of a Client's
+// pools, keyed by server address.
+type ClientPoolStats struct {
+	Tracker map[string]PoolStats
+	Storage map[string]PoolStats
+}
+
+// PoolStats returns a snapshot of connection pool health for every tracker
+// and storage address this Client has seen, for wiring into dashboards or
+// alerting on saturation (Waiters > 0), dial failures, or idle churn.
+func (c *Client) PoolStats() ClientPoolStats {
+	return ClientPoolStats{
+		Tracker: c.trackerPool.Stats(),
+		Storage: c.storagePool.Stats(),
+	}
+}
+
+// Ping checks out one connection against every known tracker and storage
+// address and issues a FdfsProtoCmdActiveTest no-op on each, returning the
+// first error encountered (tagged with its address) or nil if every
+// address answered successfully. Storage addresses are only as complete
+// as what this Client has already discovered via QueryStorage/AddAddr;
+// it does not ask the tracker for a fresh storage server list.
+func (c *Client) Ping(ctx context.Context) error {
+	for _, addr := range c.trackerPool.Addrs() {
+		if err := c.activeTest(ctx, c.trackerPool, addr); err != nil {
+			return &TrackerError{Server: addr, Err: err}
+		}
+	}
+	for _, addr := range c.storagePool.Addrs() {
+		if err := c.activeTest(ctx, c.storagePool, addr); err != nil {
+			return &StorageError{Server: addr, Err: err}
+		}
+	}
+	return nil
+}
+
+// EvictIdle force-closes every idle tracker and storage connection,
+// regardless of how long it's been idle. Useful before a planned
+// maintenance window, or to shed connections after lowering MaxConns via
+// SetMaxConns rather than waiting for them to be discarded one at a time
+// as callers Put them back.
+func (c *Client) EvictIdle() {
+	c.trackerPool.EvictIdle()
+	c.storagePool.EvictIdle()
+}
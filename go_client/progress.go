@@ -0,0 +1,316 @@
+// Package fdfs progress reporting for long-running batch jobs.
+// This file adds live stats to the worker-pool abstraction in pool.go: a
+// Stats snapshot (completed/failed/in-flight counts, throughput, ETA) and a
+// pluggable ProgressReporter a caller registers on a Pool to receive it
+// periodically, instead of counting successes by hand off the result
+// channel. TerminalProgressReporter and JSONProgressReporter are ready-made
+// implementations for a terminal progress bar and structured logs
+// respectively; Prometheus gauges or anything else can implement
+// ProgressReporter directly.
+package fdfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ProgressReporter receives a Stats snapshot periodically while a Pool has
+// batch work in flight (see Pool.ReportInterval).
+type ProgressReporter interface {
+	OnProgress(stats Stats)
+}
+
+// Stats is a point-in-time snapshot of a Pool's batch progress.
+type Stats struct {
+	Completed             int64
+	Failed                int64
+	InFlight              int64
+	BytesTransferred      int64
+	CurrentThroughputMBps float64
+	EWMALatency           time.Duration
+	// ETA is the estimated time remaining, or 0 when Pool.Total is unset
+	// (there is no target count to estimate against) or not yet known.
+	ETA time.Duration
+
+	// Concurrency is the worker count an Adaptive Pool's
+	// AdaptiveConcurrencyConfig controller currently allows, so a
+	// ProgressReporter (e.g. IntervalReporter) can show it adjusting
+	// during a long batch run. Always 0 for a non-Adaptive Pool.
+	Concurrency int
+}
+
+// defaultReportInterval is how often a Pool pushes a Stats snapshot to its
+// Reporters when Pool.ReportInterval is <= 0.
+const defaultReportInterval = time.Second
+
+// progressEWMAAlpha is the smoothing factor for the throughput and latency
+// EWMAs, applied once per Pool.ReportInterval bucket for throughput and once
+// per finished operation for latency. 0.2 favors recent
+// samples enough to react to a slowdown within a few seconds without ETA
+// jittering wildly on the first couple of buckets.
+const progressEWMAAlpha = 0.2
+
+// progressTracker accumulates the counters behind a Pool's Stats snapshot.
+// All fields are guarded by mu; batch sizes are modest enough (thousands of
+// ops/sec at most) that a mutex is simpler than lock-free counters here.
+type progressTracker struct {
+	mu sync.Mutex
+
+	completed int64
+	failed    int64
+	inFlight  int64
+	bytes     int64
+
+	total int64
+	start time.Time
+
+	bucketBytes int64
+	throughput  float64 // EWMA, MB/sec
+	latency     time.Duration
+	lastTick    time.Time
+}
+
+func newProgressTracker(total int64) *progressTracker {
+	now := time.Now()
+	return &progressTracker{total: total, start: now, lastTick: now}
+}
+
+func (t *progressTracker) startOp() {
+	t.mu.Lock()
+	t.inFlight++
+	t.mu.Unlock()
+}
+
+func (t *progressTracker) finish(bytes int64, latency time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inFlight--
+	if err != nil {
+		t.failed++
+	} else {
+		t.completed++
+		t.bytes += bytes
+		t.bucketBytes += bytes
+	}
+
+	if t.latency == 0 {
+		t.latency = latency
+	} else {
+		t.latency = time.Duration(progressEWMAAlpha*float64(latency) + (1-progressEWMAAlpha)*float64(t.latency))
+	}
+}
+
+// tick folds the bytes transferred since the last tick into the throughput
+// EWMA and returns the current Stats snapshot. Called once per
+// Pool.ReportInterval by the reporter goroutine.
+func (t *progressTracker) tick() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(t.lastTick).Seconds()
+	if elapsed <= 0 {
+		elapsed = defaultReportInterval.Seconds()
+	}
+	t.lastTick = now
+
+	sample := float64(t.bucketBytes) / 1024 / 1024 / elapsed
+	t.bucketBytes = 0
+	if t.throughput == 0 {
+		t.throughput = sample
+	} else {
+		t.throughput = progressEWMAAlpha*sample + (1-progressEWMAAlpha)*t.throughput
+	}
+
+	stats := Stats{
+		Completed:             t.completed,
+		Failed:                t.failed,
+		InFlight:              t.inFlight,
+		BytesTransferred:      t.bytes,
+		CurrentThroughputMBps: t.throughput,
+		EWMALatency:           t.latency,
+	}
+
+	if t.total > 0 {
+		done := t.completed + t.failed
+		if elapsedSinceStart := now.Sub(t.start).Seconds(); elapsedSinceStart > 0 && done > 0 {
+			rate := float64(done) / elapsedSinceStart
+			if remaining := t.total - done; remaining > 0 {
+				stats.ETA = time.Duration(float64(remaining)/rate) * time.Second
+			}
+		}
+	}
+
+	return stats
+}
+
+// snapshot returns the current Stats without folding a new throughput
+// sample, for Pool.Stats() callers outside the reporter goroutine's cadence.
+func (t *progressTracker) snapshot() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Stats{
+		Completed:             t.completed,
+		Failed:                t.failed,
+		InFlight:              t.inFlight,
+		BytesTransferred:      t.bytes,
+		CurrentThroughputMBps: t.throughput,
+		EWMALatency:           t.latency,
+	}
+}
+
+// runReporters pushes a Stats snapshot to every reporter once per interval
+// until done is closed, then returns. concurrency, if non-nil, is called
+// once per tick to fill in Stats.Concurrency (see Pool.startProgress).
+func runReporters(t *progressTracker, reporters []ProgressReporter, interval time.Duration, done <-chan struct{}, concurrency func() int) {
+	if len(reporters) == 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = defaultReportInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			stats := t.tick()
+			if concurrency != nil {
+				stats.Concurrency = concurrency()
+			}
+			for _, r := range reporters {
+				r.OnProgress(stats)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// TerminalProgressReporter renders Stats as a single self-overwriting line,
+// pb-style, suitable for an interactive terminal.
+type TerminalProgressReporter struct {
+	Out io.Writer
+
+	// Prefix is printed before each line, letting a caller running several
+	// Pools in sequence (e.g. upload, then download, then delete) label
+	// which phase a given line belongs to. Unset by default.
+	Prefix string
+}
+
+// NewTerminalProgressReporter returns a TerminalProgressReporter writing to out.
+func NewTerminalProgressReporter(out io.Writer) *TerminalProgressReporter {
+	return &TerminalProgressReporter{Out: out}
+}
+
+// SetPrefix sets the label printed before each line (see Prefix).
+func (r *TerminalProgressReporter) SetPrefix(prefix string) {
+	r.Prefix = prefix
+}
+
+// OnProgress implements ProgressReporter.
+func (r *TerminalProgressReporter) OnProgress(stats Stats) {
+	fmt.Fprintf(r.Out, "\r%scompleted=%d failed=%d in-flight=%d %.2f MB/s latency=%v eta=%v   ",
+		r.Prefix, stats.Completed, stats.Failed, stats.InFlight, stats.CurrentThroughputMBps, stats.EWMALatency.Round(time.Millisecond), stats.ETA.Round(time.Second))
+}
+
+// IntervalReporter prints human-readable cumulative totals plus the
+// throughput delta since its last call: ops/sec and bytes/sec over roughly
+// the last Pool.ReportInterval, unsmoothed, rather than the EWMA-smoothed
+// Stats.CurrentThroughputMBps (see progressTracker.tick). Like
+// TerminalProgressReporter and JSONProgressReporter, it has no ticker of
+// its own: it only reacts when Pool's reporter goroutine calls OnProgress.
+type IntervalReporter struct {
+	Out io.Writer
+
+	fallback time.Duration
+
+	mu       sync.Mutex
+	last     Stats
+	lastTime time.Time
+}
+
+// NewIntervalReporter returns an IntervalReporter writing to w. interval
+// should match the Pool.ReportInterval it's registered against; it's used
+// only as a fallback divisor for the delta computed on the very first call,
+// before there's a real previous OnProgress call to measure elapsed time
+// against.
+func NewIntervalReporter(w io.Writer, interval time.Duration) *IntervalReporter {
+	if interval <= 0 {
+		interval = defaultReportInterval
+	}
+	return &IntervalReporter{Out: w, fallback: interval, lastTime: time.Now()}
+}
+
+// OnProgress implements ProgressReporter.
+func (r *IntervalReporter) OnProgress(stats Stats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(r.lastTime).Seconds()
+	if elapsed <= 0 {
+		elapsed = r.fallback.Seconds()
+	}
+
+	deltaOps := float64((stats.Completed + stats.Failed) - (r.last.Completed + r.last.Failed))
+	deltaBytes := stats.BytesTransferred - r.last.BytesTransferred
+
+	concurrency := ""
+	if stats.Concurrency > 0 {
+		concurrency = fmt.Sprintf(" concurrency=%d", stats.Concurrency)
+	}
+	fmt.Fprintf(r.Out, "completed=%d failed=%d total=%s%s | last window: %.1f ops/sec, %s/sec\n",
+		stats.Completed, stats.Failed, humanBytes(stats.BytesTransferred), concurrency,
+		deltaOps/elapsed, humanBytes(int64(float64(deltaBytes)/elapsed)))
+
+	r.last = stats
+	r.lastTime = now
+}
+
+// humanBytes formats n as a human-readable byte count (e.g. "12.3 MB"), a
+// minimal stand-in for code.cloudfoundry.org/bytefmt so this package
+// doesn't take on a dependency for one format call.
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// NoopProgressReporter discards every Stats snapshot. Registering one
+// explicitly (rather than leaving Pool.Reporters empty) is useful when a
+// caller conditionally assigns a single fdfs.ProgressReporter value, since
+// the zero value of that interface can't be called.
+type NoopProgressReporter struct{}
+
+// OnProgress implements ProgressReporter.
+func (NoopProgressReporter) OnProgress(Stats) {}
+
+// JSONProgressReporter writes one JSON object per Stats snapshot, newline
+// delimited, suitable for structured logs.
+type JSONProgressReporter struct {
+	Out io.Writer
+}
+
+// NewJSONProgressReporter returns a JSONProgressReporter writing to out.
+func NewJSONProgressReporter(out io.Writer) *JSONProgressReporter {
+	return &JSONProgressReporter{Out: out}
+}
+
+// OnProgress implements ProgressReporter.
+func (r *JSONProgressReporter) OnProgress(stats Stats) {
+	enc := json.NewEncoder(r.Out)
+	enc.Encode(stats)
+}
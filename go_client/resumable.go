@@ -0,0 +1,382 @@
+// Package fdfs resumable chunked upload.
+// This file adds UploadResumable, a chunked-upload path built on the
+// appender/modify commands (see appender.go): it commits one chunk at a
+// time via UploadAppenderBuffer/AppendFile and records how far it got in a
+// pluggable ProgressStore, so re-invoking it with the same key after a
+// crash or network failure resumes from the last committed chunk instead
+// of re-uploading the whole file, modeled on the chunked resumable upload
+// pattern used by services like Qiniu's upload API.
+package fdfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// defaultResumableChunkSize is used when UploadResumableOptions.ChunkSize
+// is unset. Bigger than streamChunkSize since each chunk here is a
+// separate appender-file RPC and progress-store write, not just a
+// read/write buffer.
+const defaultResumableChunkSize = 4 * 1024 * 1024 // 4 MiB
+
+// ResumableProgress records how much of a resumable upload has been
+// committed to the storage server: the appender file ID it's been written
+// to (empty until the first chunk succeeds) and the number of bytes
+// committed so far.
+type ResumableProgress struct {
+	FileID string `json:"file_id"`
+	Offset int64  `json:"offset"`
+
+	// ChunkSize is the chunk size this progress was committed with, so
+	// resuming purely from a Token (no shared ProgressStore) continues
+	// with the same chunking the upload was started with when
+	// UploadResumableOptions.ChunkSize is left unset on the resuming call.
+	ChunkSize int64 `json:"chunk_size"`
+
+	// SHA256 is the hex-encoded SHA-256 of the first Offset bytes
+	// committed so far. UploadResumable recomputes this over the prefix it
+	// skips on resume and fails with ErrChecksumMismatch if it doesn't
+	// match, instead of silently appending chunks from a reader whose
+	// prefix no longer matches what was already written to the remote
+	// appender file (the real risk in handing a token to a stateless
+	// service that re-opens its own copy of the source on retry).
+	SHA256 string `json:"sha256"`
+}
+
+// ProgressStore persists ResumableProgress between UploadResumable calls,
+// keyed by a caller-chosen identifier (e.g. a local file path or content
+// hash). See NewMemoryProgressStore and NewFileProgressStore for ready-made
+// implementations.
+type ProgressStore interface {
+	// Load returns the progress saved for key, and false if none exists.
+	Load(key string) (ResumableProgress, bool, error)
+
+	// Save records progress for key, overwriting any previous value.
+	Save(key string, progress ResumableProgress) error
+}
+
+// MemoryProgressStore is a ProgressStore backed by an in-process map. It
+// does not survive a process restart, so it's only useful for resuming
+// after a transient mid-run failure (e.g. one retryable RPC error),
+// not a crash; use NewFileProgressStore when progress must survive that.
+type MemoryProgressStore struct {
+	mu sync.Mutex
+	m  map[string]ResumableProgress
+}
+
+// NewMemoryProgressStore returns an empty MemoryProgressStore.
+func NewMemoryProgressStore() *MemoryProgressStore {
+	return &MemoryProgressStore{m: make(map[string]ResumableProgress)}
+}
+
+// Load implements ProgressStore.
+func (s *MemoryProgressStore) Load(key string) (ResumableProgress, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.m[key]
+	return p, ok, nil
+}
+
+// Save implements ProgressStore.
+func (s *MemoryProgressStore) Save(key string, progress ResumableProgress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[key] = progress
+	return nil
+}
+
+// FileProgressStore is a ProgressStore backed by a single JSON file holding
+// every key's progress, read and rewritten in full on every Save. That's
+// fine for the chunk counts a resumable upload produces (one write per
+// multi-MiB chunk) and keeps the on-disk format trivially inspectable.
+type FileProgressStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileProgressStore returns a FileProgressStore backed by path. The file
+// is created on the first Save; a missing file is treated as empty.
+func NewFileProgressStore(path string) *FileProgressStore {
+	return &FileProgressStore{path: path}
+}
+
+// Load implements ProgressStore.
+func (s *FileProgressStore) Load(key string) (ResumableProgress, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAll()
+	if err != nil {
+		return ResumableProgress{}, false, err
+	}
+	p, ok := all[key]
+	return p, ok, nil
+}
+
+// Save implements ProgressStore.
+func (s *FileProgressStore) Save(key string, progress ResumableProgress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAll()
+	if err != nil {
+		return err
+	}
+	all[key] = progress
+
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// readAll loads every key's progress from s.path. Caller must hold s.mu.
+func (s *FileProgressStore) readAll() (map[string]ResumableProgress, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string]ResumableProgress), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return make(map[string]ResumableProgress), nil
+	}
+
+	all := make(map[string]ResumableProgress)
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	return all, nil
+}
+
+// UploadResumableOptions configures UploadResumable.
+type UploadResumableOptions struct {
+	// Key identifies this upload across retries/restarts. Required unless
+	// Token is set: UploadResumable uses it to look up and save progress in
+	// ProgressStore.
+	Key string
+
+	// ProgressStore persists chunk progress. Defaults to a fresh
+	// MemoryProgressStore created for this call when unset, so progress
+	// saved there is not visible to any later call.
+	ProgressStore ProgressStore
+
+	// ChunkSize is the number of bytes committed per
+	// UploadAppenderBuffer/AppendFile RPC and per ProgressStore.Save call.
+	// When <= 0, the ChunkSize recorded in the resumed progress is used, or
+	// defaultResumableChunkSize if none was recorded.
+	ChunkSize int64
+
+	// Metadata is attached to the appender file on its initial upload
+	// (the first chunk), the same as UploadAppenderBuffer's metadata
+	// parameter.
+	Metadata map[string]string
+
+	// ProgressNotify, if set, is called after every chunk commits with
+	// the total bytes committed so far and the overall upload size, for
+	// UI reporting.
+	ProgressNotify func(offset, total int64)
+
+	// Token, if set, seeds the starting ResumableProgress directly (see
+	// ParseResumableToken), bypassing ProgressStore.Load. This lets a
+	// caller resume an upload using only a token it persisted itself
+	// (e.g. returned to a client and handed back on retry) without a
+	// ProgressStore shared between the process that started the upload
+	// and the one resuming it. Key is still used as the ProgressStore
+	// save key for each chunk as the upload progresses unless left empty,
+	// in which case ProgressStore is not consulted at all.
+	Token []byte
+
+	// Finalize, when true, truncates the appender file to exactly size
+	// once every chunk has committed, trimming any trailing bytes left
+	// over from a chunk that was retried after a partial commit (the
+	// server already has the bytes but the client couldn't confirm it, so
+	// retried the same offset, appending past size; TruncateFile recovers
+	// from that).
+	//
+	// This does not convert the result into an ordinary (non-appender)
+	// file: this client has no regenerate-appender-to-normal-file RPC
+	// implemented (FastDFS's storage protocol supports one; it's simply
+	// not wired up here), so the returned file ID remains an appender
+	// file ID. Callers that need a plain file should re-upload via
+	// UploadFile/UploadBuffer once the resumable upload completes.
+	Finalize bool
+}
+
+// Token encodes p for a caller to persist and pass back as
+// UploadResumableOptions.Token to resume later without a shared
+// ProgressStore.
+func (p ResumableProgress) Token() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// ParseResumableToken decodes a token produced by ResumableProgress.Token.
+func ParseResumableToken(token []byte) (ResumableProgress, error) {
+	var p ResumableProgress
+	if err := json.Unmarshal(token, &p); err != nil {
+		return ResumableProgress{}, fmt.Errorf("fdfs: invalid resumable token: %w", err)
+	}
+	return p, nil
+}
+
+// ResumableUploadError wraps an UploadResumable failure with the
+// ResumableProgress reached before the failure, whenever at least one
+// chunk had already committed. A caller can call Progress.Token() on it to
+// resume later (via UploadResumableOptions.Token) even without having
+// configured a ProgressStore, which is what makes an UploadResumable call
+// cancelable from the caller's ctx and resumable afterward: canceling ctx
+// mid-upload surfaces here with whatever prefix had already committed.
+type ResumableUploadError struct {
+	Progress ResumableProgress
+	Err      error
+}
+
+func (e *ResumableUploadError) Error() string {
+	return fmt.Sprintf("resumable upload failed at offset %d: %v", e.Progress.Offset, e.Err)
+}
+
+func (e *ResumableUploadError) Unwrap() error {
+	return e.Err
+}
+
+// UploadResumable uploads size bytes read from r as an appender file,
+// committing one opts.ChunkSize chunk at a time via UploadAppenderBuffer
+// (the first chunk) and AppendFile (subsequent chunks), saving
+// ResumableProgress after each commit. Re-invoking UploadResumable with the
+// same opts.Key resumes after the last committed chunk instead of
+// re-uploading from the start.
+//
+// r must yield the same bytes starting from offset 0 on every invocation
+// for the same key (e.g. re-opening the same local file): UploadResumable
+// does not require an io.Seeker, so on resume it instead hashes through the
+// already-committed prefix (discarding it) and fails with ErrChecksumMismatch
+// if that doesn't match the SHA256 recorded in progress, catching a resume
+// against a reader that no longer agrees with what's already on the remote
+// appender file instead of silently corrupting it.
+func (c *Client) UploadResumable(ctx context.Context, r io.Reader, size int64, extName string, opts UploadResumableOptions) (string, error) {
+	if err := c.checkClosed(); err != nil {
+		return "", err
+	}
+	return uploadResumable(ctx, c, r, size, extName, opts)
+}
+
+// resumableBackend is the subset of Client that uploadResumable drives,
+// factored out so tests can exercise the chunking/hashing/resume logic
+// against a fake that injects mid-upload failures, without a live
+// tracker/storage pair.
+type resumableBackend interface {
+	UploadAppenderBuffer(ctx context.Context, data []byte, fileExtName string, metadata map[string]string) (string, error)
+	AppendFile(ctx context.Context, fileID string, data []byte) error
+	TruncateFile(ctx context.Context, fileID string, size int64) error
+}
+
+func uploadResumable(ctx context.Context, backend resumableBackend, r io.Reader, size int64, extName string, opts UploadResumableOptions) (string, error) {
+	if opts.Key == "" && len(opts.Token) == 0 {
+		return "", errors.New("fdfs: UploadResumableOptions.Key or Token is required")
+	}
+
+	store := opts.ProgressStore
+	if store == nil {
+		store = NewMemoryProgressStore()
+	}
+
+	var progress ResumableProgress
+	if len(opts.Token) > 0 {
+		p, err := ParseResumableToken(opts.Token)
+		if err != nil {
+			return "", err
+		}
+		progress = p
+	} else {
+		p, _, err := store.Load(opts.Key)
+		if err != nil {
+			return "", fmt.Errorf("failed to load resumable progress for %q: %w", opts.Key, err)
+		}
+		progress = p
+	}
+
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = progress.ChunkSize
+	}
+	if chunkSize <= 0 {
+		chunkSize = defaultResumableChunkSize
+	}
+
+	hasher := sha256.New()
+	if progress.Offset > 0 {
+		if _, err := io.CopyN(hasher, r, progress.Offset); err != nil {
+			return "", fmt.Errorf("failed to skip %d already-committed bytes: %w", progress.Offset, err)
+		}
+		if progress.SHA256 != "" {
+			if got := hex.EncodeToString(hasher.Sum(nil)); got != progress.SHA256 {
+				return "", resumableErr(progress.FileID, progress.Offset, chunkSize, progress.SHA256,
+					fmt.Errorf("%w: resumed reader's first %d bytes don't match what was already committed to %s", ErrChecksumMismatch, progress.Offset, progress.FileID))
+			}
+		}
+	}
+
+	fileID := progress.FileID
+	offset := progress.Offset
+
+	for offset < size {
+		n := chunkSize
+		if remaining := size - offset; remaining < n {
+			n = remaining
+		}
+
+		chunk := make([]byte, n)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return "", resumableErr(fileID, offset, chunkSize, hex.EncodeToString(hasher.Sum(nil)), fmt.Errorf("failed to read upload data at offset %d: %w", offset, err))
+		}
+
+		if fileID == "" {
+			id, err := backend.UploadAppenderBuffer(ctx, chunk, extName, opts.Metadata)
+			if err != nil {
+				return "", resumableErr(fileID, offset, chunkSize, hex.EncodeToString(hasher.Sum(nil)), err)
+			}
+			fileID = id
+		} else if err := backend.AppendFile(ctx, fileID, chunk); err != nil {
+			return "", resumableErr(fileID, offset, chunkSize, hex.EncodeToString(hasher.Sum(nil)), err)
+		}
+
+		hasher.Write(chunk)
+		offset += n
+		sha := hex.EncodeToString(hasher.Sum(nil))
+		if opts.Key != "" {
+			if err := store.Save(opts.Key, ResumableProgress{FileID: fileID, Offset: offset, ChunkSize: chunkSize, SHA256: sha}); err != nil {
+				return "", resumableErr(fileID, offset, chunkSize, sha, fmt.Errorf("failed to save resumable progress for %q: %w", opts.Key, err))
+			}
+		}
+		if opts.ProgressNotify != nil {
+			opts.ProgressNotify(offset, size)
+		}
+	}
+
+	if opts.Finalize {
+		if err := backend.TruncateFile(ctx, fileID, size); err != nil {
+			return "", resumableErr(fileID, offset, chunkSize, hex.EncodeToString(hasher.Sum(nil)), fmt.Errorf("failed to finalize: %w", err))
+		}
+	}
+
+	return fileID, nil
+}
+
+// resumableErr wraps err as a *ResumableUploadError carrying the progress
+// reached so far, unless fileID is still empty (nothing committed yet, so
+// there's nothing for the caller to resume from and a plain error suffices).
+func resumableErr(fileID string, offset, chunkSize int64, sha256Hex string, err error) error {
+	if fileID == "" {
+		return err
+	}
+	return &ResumableUploadError{Progress: ResumableProgress{FileID: fileID, Offset: offset, ChunkSize: chunkSize, SHA256: sha256Hex}, Err: err}
+}
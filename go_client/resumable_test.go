@@ -0,0 +1,137 @@
+package fdfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResumableBackend implements resumableBackend in memory, so
+// uploadResumable's chunking/hashing/resume logic can be exercised without a
+// live tracker/storage pair. committed holds the bytes appended to each
+// fileID, in commit order, standing in for the remote appender file.
+type fakeResumableBackend struct {
+	committed map[string][]byte
+	nextID    int
+
+	// failAfter, if > 0, makes the failAfter'th call across
+	// UploadAppenderBuffer/AppendFile combined return failErr instead of
+	// committing, simulating a crash or network failure mid-upload.
+	failAfter int
+	failErr   error
+	calls     int
+}
+
+func newFakeResumableBackend() *fakeResumableBackend {
+	return &fakeResumableBackend{committed: make(map[string][]byte)}
+}
+
+func (f *fakeResumableBackend) shouldFail() bool {
+	f.calls++
+	return f.failAfter > 0 && f.calls == f.failAfter
+}
+
+func (f *fakeResumableBackend) UploadAppenderBuffer(ctx context.Context, data []byte, fileExtName string, metadata map[string]string) (string, error) {
+	if f.shouldFail() {
+		return "", f.failErr
+	}
+	f.nextID++
+	id := "group1/M00/00/00/fake" + string(rune('a'+f.nextID))
+	f.committed[id] = append([]byte(nil), data...)
+	return id, nil
+}
+
+func (f *fakeResumableBackend) AppendFile(ctx context.Context, fileID string, data []byte) error {
+	if f.shouldFail() {
+		return f.failErr
+	}
+	f.committed[fileID] = append(f.committed[fileID], data...)
+	return nil
+}
+
+func (f *fakeResumableBackend) TruncateFile(ctx context.Context, fileID string, size int64) error {
+	if int64(len(f.committed[fileID])) > size {
+		f.committed[fileID] = f.committed[fileID][:size]
+	}
+	return nil
+}
+
+func TestUploadResumableResumesAfterMidUploadFailure(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000) // 10000 bytes
+	backend := newFakeResumableBackend()
+	backend.failAfter = 2 // let chunk 1 commit, fail chunk 2
+	backend.failErr = errors.New("simulated network failure")
+
+	store := NewMemoryProgressStore()
+	opts := UploadResumableOptions{Key: "k", ProgressStore: store, ChunkSize: 3000}
+
+	_, err := uploadResumable(context.Background(), backend, bytes.NewReader(content), int64(len(content)), "bin", opts)
+	require.Error(t, err)
+
+	var resumeErr *ResumableUploadError
+	require.True(t, errors.As(err, &resumeErr), "expected a *ResumableUploadError, got %T: %v", err, err)
+	assert.Equal(t, int64(3000), resumeErr.Progress.Offset)
+
+	// Resume: a fresh reader over the same content, starting from scratch
+	// (as if the process restarted and re-opened the source).
+	backend.failAfter = 0
+	fileID, err := uploadResumable(context.Background(), backend, bytes.NewReader(content), int64(len(content)), "bin", opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, content, backend.committed[fileID], "resumed upload must produce byte-identical content")
+}
+
+func TestUploadResumableRejectsNonMatchingResume(t *testing.T) {
+	original := bytes.Repeat([]byte("a"), 5000)
+	backend := newFakeResumableBackend()
+	store := NewMemoryProgressStore()
+	opts := UploadResumableOptions{Key: "k", ProgressStore: store, ChunkSize: 2000}
+
+	fileID, err := uploadResumable(context.Background(), backend, bytes.NewReader(original[:2000]), 2000, "bin", opts)
+	require.NoError(t, err)
+	assert.NotEmpty(t, fileID)
+
+	// Simulate resuming with a reader whose prefix no longer matches what
+	// was already committed (e.g. the source file changed between runs).
+	changed := bytes.Repeat([]byte("b"), 5000)
+	_, err = uploadResumable(context.Background(), backend, bytes.NewReader(changed), int64(len(changed)), "bin", opts)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+}
+
+func TestUploadResumableFinalizeTrimsTrailingBytes(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 100)
+	backend := newFakeResumableBackend()
+	store := NewMemoryProgressStore()
+	opts := UploadResumableOptions{Key: "k", ProgressStore: store, ChunkSize: 100}
+
+	fileID, err := uploadResumable(context.Background(), backend, bytes.NewReader(content), int64(len(content)), "bin", opts)
+	require.NoError(t, err)
+
+	// Simulate a chunk commit that the server accepted but the client
+	// couldn't confirm (so it isn't reflected in the saved progress),
+	// leaving the remote appender file longer than the recorded size.
+	backend.committed[fileID] = append(backend.committed[fileID], []byte("stray")...)
+
+	// Re-invoking with the same (already-complete) progress and Finalize
+	// set should trim the stray trailing bytes back off.
+	opts.Finalize = true
+	resumedID, err := uploadResumable(context.Background(), backend, bytes.NewReader(content), int64(len(content)), "bin", opts)
+	require.NoError(t, err)
+	assert.Equal(t, fileID, resumedID)
+	assert.Equal(t, content, backend.committed[fileID])
+}
+
+func TestResumableProgressTokenRoundTrip(t *testing.T) {
+	p := ResumableProgress{FileID: "group1/M00/00/00/a.bin", Offset: 4096, ChunkSize: 4096, SHA256: "deadbeef"}
+	token, err := p.Token()
+	require.NoError(t, err)
+
+	got, err := ParseResumableToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, p, got)
+}
@@ -0,0 +1,178 @@
+// Package fdfs retention subsystem.
+// This file implements Grandfather-Father-Son bucket-based cleanup of
+// previously uploaded files, letting operators keep one representative
+// upload per time window instead of deleting purely on age.
+package fdfs
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Policy describes the Grandfather-Father-Son retention bucket windows,
+// each expressed relative to "now". A file is assigned to the first bucket
+// (in the order below) whose window contains its age:
+//
+//  1. Unlimited - age <= UnlimitedHours: kept unconditionally.
+//  2. Hourly    - age <= HourlyHours: one keeper per UTC hour window.
+//  3. Daily     - age <= DailyDays: one keeper per UTC calendar day.
+//  4. Weekly    - age <= WeeklyMonths (in months): one keeper per ISO week.
+//  5. Monthly   - age <= MonthlyMonths (in months): one keeper per calendar month.
+//  6. Expired   - older than all of the above: deleted unconditionally.
+//
+// Within a bucket, the oldest upload in each window is the keeper; every
+// other file in that window is deleted.
+type Policy struct {
+	UnlimitedHours int
+	HourlyHours    int
+	DailyDays      int
+	WeeklyMonths   int
+	MonthlyMonths  int
+}
+
+// FileRecord is a single file candidate for retention classification,
+// keyed by its FastDFS file ID and the timestamp used for bucketing
+// (typically the upload time recorded by the caller).
+type FileRecord struct {
+	FileID  string
+	ModTime time.Time
+}
+
+// RetentionLister enumerates the files in a group that are subject to a
+// retention policy. FastDFS's tracker protocol has no built-in "list all
+// files" command, so callers supply a Lister backed by whatever index they
+// already maintain for uploads in that group (an id-list file, an external
+// database, etc.); ApplyRetention/PlanRetention consult it rather than
+// talking to the tracker directly.
+type RetentionLister interface {
+	ListFiles(ctx context.Context, groupName string) ([]FileRecord, error)
+}
+
+// RetentionPlan is the outcome of classifying a set of files against a
+// Policy: which file IDs are kept (the chosen keeper for each window) and
+// which are marked for deletion.
+type RetentionPlan struct {
+	Keep   []string
+	Delete []string
+}
+
+// retentionBucket identifies which GFS bucket a file falls into.
+type retentionBucket int
+
+const (
+	bucketUnlimited retentionBucket = iota
+	bucketHourly
+	bucketDaily
+	bucketWeekly
+	bucketMonthly
+	bucketExpired
+)
+
+// PlanRetention classifies every file returned by the client's configured
+// RetentionLister into a bucket and computes which files would be kept or
+// deleted under p, without deleting anything. This is the dry-run mode used
+// by ApplyRetention internally and is also useful for operators to review a
+// deletion plan before execution.
+func (c *Client) PlanRetention(ctx context.Context, groupName string, p Policy) (*RetentionPlan, error) {
+	if c.config.RetentionLister == nil {
+		return nil, fmt.Errorf("fdfs: ClientConfig.RetentionLister is not configured")
+	}
+
+	files, err := c.config.RetentionLister.ListFiles(ctx, groupName)
+	if err != nil {
+		return nil, fmt.Errorf("fdfs: list files for retention: %w", err)
+	}
+
+	now := time.Now().UTC()
+
+	// windowKeepers maps a (bucket, window key) pair to the current keeper
+	// for that window; every other file sharing the key is deleted.
+	type windowID struct {
+		bucket retentionBucket
+		key    string
+	}
+	keepers := make(map[windowID]FileRecord)
+	plan := &RetentionPlan{}
+
+	for _, f := range files {
+		bucket, key := classifyFile(now, f.ModTime, p)
+
+		if bucket == bucketUnlimited {
+			plan.Keep = append(plan.Keep, f.FileID)
+			continue
+		}
+		if bucket == bucketExpired {
+			plan.Delete = append(plan.Delete, f.FileID)
+			continue
+		}
+
+		id := windowID{bucket: bucket, key: key}
+		existing, ok := keepers[id]
+		if !ok || f.ModTime.Before(existing.ModTime) {
+			if ok {
+				plan.Delete = append(plan.Delete, existing.FileID)
+			}
+			keepers[id] = f
+		} else {
+			plan.Delete = append(plan.Delete, f.FileID)
+		}
+	}
+
+	for _, f := range keepers {
+		plan.Keep = append(plan.Keep, f.FileID)
+	}
+
+	return plan, nil
+}
+
+// ApplyRetention enumerates the files in groupName via the client's
+// configured RetentionLister, classifies each by age into exactly one
+// Grandfather-Father-Son bucket per Policy, and deletes every file that is
+// not the keeper of its bucket window.
+func (c *Client) ApplyRetention(ctx context.Context, groupName string, p Policy) error {
+	plan, err := c.PlanRetention(ctx, groupName, p)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, fileID := range plan.Delete {
+		if err := c.DeleteFile(ctx, fileID); err != nil {
+			lastErr = fmt.Errorf("fdfs: delete %s: %w", fileID, err)
+		}
+	}
+	return lastErr
+}
+
+// classifyFile determines which bucket modTime falls into relative to now,
+// along with the window key used to group files that share a keeper.
+func classifyFile(now, modTime time.Time, p Policy) (retentionBucket, string) {
+	age := now.Sub(modTime)
+	modTime = modTime.UTC()
+
+	if p.UnlimitedHours > 0 && age <= time.Duration(p.UnlimitedHours)*time.Hour {
+		return bucketUnlimited, ""
+	}
+	if age <= time.Duration(p.HourlyHours)*time.Hour {
+		return bucketHourly, modTime.Format("2006-01-02T15")
+	}
+	if age <= time.Duration(p.DailyDays)*24*time.Hour {
+		return bucketDaily, modTime.Format("2006-01-02")
+	}
+	if age <= approxMonths(p.WeeklyMonths) {
+		year, week := modTime.ISOWeek()
+		return bucketWeekly, fmt.Sprintf("%04d-W%02d", year, week)
+	}
+	if age <= approxMonths(p.MonthlyMonths) {
+		return bucketMonthly, modTime.Format("2006-01")
+	}
+
+	return bucketExpired, ""
+}
+
+// approxMonths converts a count of calendar months into an approximate
+// duration (30 days/month) used purely for bucket-horizon comparisons.
+func approxMonths(months int) time.Duration {
+	return time.Duration(months) * 30 * 24 * time.Hour
+}
@@ -0,0 +1,237 @@
+// Package fdfs retry subsystem.
+// This file defines pluggable retry policies used by every *WithRetry
+// method, replacing the previous hard-coded linear backoff so callers can
+// tune (or override per call) how transient failures are retried.
+package fdfs
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy produces a fresh BackoffState for a single retry loop. A new
+// state is created at the start of every *WithRetry call so policies never
+// leak state across unrelated operations.
+type RetryPolicy interface {
+	NewState() BackoffState
+}
+
+// BackoffState tracks the progress of a single retry loop and decides how
+// long to wait before the next attempt.
+type BackoffState interface {
+	// Next returns how long to sleep before the next attempt.
+	Next() time.Duration
+}
+
+// ExponentialBackoff implements the classic decorrelated-jitter recurrence:
+//
+//	sleep = min(MaxInterval, random_between(InitialInterval, prev*Multiplier))
+//
+// resetting to InitialInterval at the start of every retry loop.
+type ExponentialBackoff struct {
+	InitialInterval     time.Duration
+	MaxInterval         time.Duration
+	Multiplier          float64
+	RandomizationFactor float64
+	MaxElapsedTime      time.Duration
+}
+
+// NewState implements RetryPolicy.
+func (e ExponentialBackoff) NewState() BackoffState {
+	return &exponentialState{policy: e, prev: e.InitialInterval, start: time.Now()}
+}
+
+type exponentialState struct {
+	policy ExponentialBackoff
+	prev   time.Duration
+	start  time.Time
+}
+
+// Next implements BackoffState.
+func (s *exponentialState) Next() time.Duration {
+	p := s.policy
+	if p.MaxElapsedTime > 0 && time.Since(s.start) > p.MaxElapsedTime {
+		return 0
+	}
+
+	initial := p.InitialInterval
+	if initial <= 0 {
+		initial = 500 * time.Millisecond
+	}
+	maxInterval := p.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = 30 * time.Second
+	}
+	multiplier := p.Multiplier
+	if multiplier <= 1 {
+		multiplier = 2
+	}
+	randFactor := p.RandomizationFactor
+
+	upper := time.Duration(float64(s.prev) * multiplier)
+	if upper < initial {
+		upper = initial
+	}
+	if upper > maxInterval {
+		upper = maxInterval
+	}
+
+	delay := randomBetween(initial, upper)
+	if randFactor > 0 {
+		delta := float64(delay) * randFactor
+		delay += time.Duration((rand.Float64()*2 - 1) * delta)
+		if delay < 0 {
+			delay = 0
+		}
+	}
+	if delay > maxInterval {
+		delay = maxInterval
+	}
+
+	s.prev = delay
+	return delay
+}
+
+// randomBetween returns a random duration in [lo, hi]; if hi <= lo it
+// returns lo.
+func randomBetween(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(rand.Int63n(int64(hi-lo)))
+}
+
+// FixedDelay retries at a constant interval up to MaxAttempts times.
+type FixedDelay struct {
+	Delay       time.Duration
+	MaxAttempts int
+}
+
+// NewState implements RetryPolicy.
+func (f FixedDelay) NewState() BackoffState {
+	return &fixedState{policy: f}
+}
+
+type fixedState struct {
+	policy   FixedDelay
+	attempts int
+}
+
+// Next implements BackoffState.
+func (s *fixedState) Next() time.Duration {
+	s.attempts++
+	if s.policy.MaxAttempts > 0 && s.attempts > s.policy.MaxAttempts {
+		return 0
+	}
+	return s.policy.Delay
+}
+
+// defaultRetryPolicy reproduces the client's historical linear backoff
+// (1s, 2s, 3s, ...) so behavior is unchanged when ClientConfig.RetryPolicy
+// (and no per-call override) is left unset.
+var defaultRetryPolicy RetryPolicy = linearPolicy{}
+
+type linearPolicy struct{}
+
+func (linearPolicy) NewState() BackoffState { return &linearState{} }
+
+type linearState struct{ attempt int }
+
+func (s *linearState) Next() time.Duration {
+	s.attempt++
+	return time.Second * time.Duration(s.attempt)
+}
+
+// retryContextKey is the private context key used by WithRetry.
+type retryContextKey struct{}
+
+// WithRetry returns a context carrying policy as a per-call override of
+// ClientConfig.RetryPolicy, letting a single caller retry a specific upload
+// or download more (or less) aggressively than the client-wide default.
+func WithRetry(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryContextKey{}, policy)
+}
+
+// newRetryBackoff builds a BackoffState for a single *WithRetry loop, using
+// an overriding policy from ctx if WithRetry was used, otherwise the
+// client-wide ClientConfig.RetryPolicy, otherwise the historical default.
+func (c *Client) newRetryBackoff(ctxs ...context.Context) BackoffState {
+	return c.retryPolicy(ctxs...).NewState()
+}
+
+func (c *Client) retryPolicy(ctxs ...context.Context) RetryPolicy {
+	for _, ctx := range ctxs {
+		if ctx == nil {
+			continue
+		}
+		if policy, ok := ctx.Value(retryContextKey{}).(RetryPolicy); ok {
+			return policy
+		}
+		if override, ok := GetConfig(ctx); ok && override.RetryPolicy != nil {
+			return override.RetryPolicy
+		}
+	}
+	if c.config.RetryPolicy != nil {
+		return c.config.RetryPolicy
+	}
+	return defaultRetryPolicy
+}
+
+// isNonRetryable reports whether err belongs to a class of failures that
+// should never be retried: invalid input, a definitively missing file, or
+// caller cancellation.
+func isNonRetryable(err error) bool {
+	return errors.Is(err, ErrInvalidArgument) ||
+		errors.Is(err, ErrFileNotFound) ||
+		errors.Is(err, ErrInvalidFileID) ||
+		errors.Is(err, context.Canceled)
+}
+
+// RetryableFunc classifies whether err should trigger another retry
+// attempt. The *WithRetry methods consult it after every failed attempt
+// instead of a hard-coded list of "don't retry on X" errors.
+type RetryableFunc func(err error) bool
+
+// defaultRetryableFunc retries everything isNonRetryable doesn't already
+// rule out, reproducing the client's historical per-operation checks
+// (ErrInvalidArgument, ErrFileNotFound, ErrInvalidFileID) in one place.
+// Notably ErrNoStorageServer and ErrCircuitOpen are retryable by default:
+// getStorageServer/getDownloadStorageServer re-query the tracker on every
+// attempt, so the next attempt may land on a different storage server.
+func defaultRetryableFunc(err error) bool {
+	return !isNonRetryable(err)
+}
+
+// retryableContextKey is the private context key used by WithRetryableFunc.
+type retryableContextKey struct{}
+
+// WithRetryableFunc returns a context carrying fn as a per-call override of
+// ClientConfig.RetryableFunc, letting a single caller change which errors
+// are worth retrying, e.g. giving up immediately on ErrNoStorageServer
+// instead of re-querying the tracker.
+func WithRetryableFunc(ctx context.Context, fn RetryableFunc) context.Context {
+	return context.WithValue(ctx, retryableContextKey{}, fn)
+}
+
+// retryableFunc resolves the RetryableFunc that applies to this call: a
+// per-call override from WithRetryableFunc, then ClientConfig.RetryableFunc,
+// then defaultRetryableFunc.
+func (c *Client) retryableFunc(ctxs ...context.Context) RetryableFunc {
+	for _, ctx := range ctxs {
+		if ctx == nil {
+			continue
+		}
+		if fn, ok := ctx.Value(retryableContextKey{}).(RetryableFunc); ok {
+			return fn
+		}
+		if override, ok := GetConfig(ctx); ok && override.RetryableFunc != nil {
+			return override.RetryableFunc
+		}
+	}
+	if c.config.RetryableFunc != nil {
+		return c.config.RetryableFunc
+	}
+	return defaultRetryableFunc
+}
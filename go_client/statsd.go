@@ -0,0 +1,110 @@
+// Package fdfs StatsD metrics sink.
+// This file implements Metrics over a UDP StatsD wire format, used
+// automatically when ClientConfig.StatsdAddr is set and ClientConfig.Metrics
+// is left nil.
+package fdfs
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StatsDMetrics sends counters/histograms/gauges to a StatsD-compatible UDP
+// endpoint, using the "|#tag:val,..." tag extension understood by DogStatsD
+// and Telegraf (plain StatsD has no tag support, so tags are dropped by
+// servers that don't understand the extension).
+type StatsDMetrics struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewStatsDMetrics dials addr (a "host:port" UDP endpoint) and returns a
+// Metrics sink backed by it. Dialing UDP never touches the network, so the
+// only failure mode is a malformed address.
+func NewStatsDMetrics(addr string) (*StatsDMetrics, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("statsd: dial %s: %w", addr, err)
+	}
+	return &StatsDMetrics{conn: conn}, nil
+}
+
+// Close closes the underlying UDP socket.
+func (s *StatsDMetrics) Close() error {
+	return s.conn.Close()
+}
+
+// send writes line to the UDP socket, best-effort: a dropped metrics packet
+// must never affect the RPC it describes.
+func (s *StatsDMetrics) send(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = s.conn.Write([]byte(line))
+}
+
+// formatTags renders tags in a stable (sorted) order so repeated calls for
+// the same logical metric produce identical wire output.
+func formatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + ":" + tags[k]
+	}
+	return "|#" + strings.Join(pairs, ",")
+}
+
+// Counter implements Metrics.
+func (s *StatsDMetrics) Counter(name string, tags map[string]string) Counter {
+	return &statsdCounter{s: s, name: name, tags: formatTags(tags)}
+}
+
+// Histogram implements Metrics.
+func (s *StatsDMetrics) Histogram(name string, tags map[string]string) Histogram {
+	return &statsdHistogram{s: s, name: name, tags: formatTags(tags)}
+}
+
+// Gauge implements Metrics.
+func (s *StatsDMetrics) Gauge(name string, tags map[string]string) Gauge {
+	return &statsdGauge{s: s, name: name, tags: formatTags(tags)}
+}
+
+type statsdCounter struct {
+	s    *StatsDMetrics
+	name string
+	tags string
+}
+
+func (c *statsdCounter) Add(delta float64) {
+	c.s.send(fmt.Sprintf("%s:%g|c%s", c.name, delta, c.tags))
+}
+
+type statsdHistogram struct {
+	s    *StatsDMetrics
+	name string
+	tags string
+}
+
+func (h *statsdHistogram) Observe(value float64) {
+	h.s.send(fmt.Sprintf("%s:%g|ms%s", h.name, value, h.tags))
+}
+
+type statsdGauge struct {
+	s    *StatsDMetrics
+	name string
+	tags string
+}
+
+func (g *statsdGauge) Set(value float64) {
+	g.s.send(fmt.Sprintf("%s:%g|g%s", g.name, value, g.tags))
+}
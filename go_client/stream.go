@@ -0,0 +1,241 @@
+// Package fdfs streaming I/O helpers.
+// This file adds upload/download paths that move data between the caller and
+// the storage connection in bounded chunks instead of buffering whole files
+// in memory, so multi-GB transfers run in constant memory. The chunk
+// buffers themselves come from ClientConfig.BufferPool (see buffer.go and
+// go_client/bufpool), so repeated calls don't each allocate their own.
+package fdfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// streamChunkSize is the size of each chunk copied between the caller's
+// io.Reader/io.Writer and the storage connection.
+const streamChunkSize = 64 * 1024
+
+// UploadStream uploads size bytes read from r to groupName (or any group if
+// groupName is empty), sending the FastDFS header up front and streaming the
+// body in bounded chunks rather than materializing it as a single []byte.
+// It returns the resulting file ID.
+func (c *Client) UploadStream(ctx context.Context, groupName string, r io.Reader, size int64, extName string) (string, error) {
+	if err := c.checkClosed(); err != nil {
+		return "", err
+	}
+
+	storageServer, err := c.getStorageServer(ctx, groupName)
+	if err != nil {
+		return "", err
+	}
+
+	conn, err := c.storagePool.Get(ctx, fmt.Sprintf("%s:%d", storageServer.IPAddr, storageServer.Port))
+	if err != nil {
+		return "", err
+	}
+	defer c.storagePool.Put(conn)
+
+	extNameBytes := padString(extName, FdfsFileExtNameMaxLen)
+	storePathIndex := byte(storageServer.StorePathIndex)
+
+	bodyLen := 1 + int64(FdfsFileExtNameMaxLen) + size
+	reqHeader := encodeHeader(bodyLen, StorageProtoCmdUploadFile, 0)
+
+	if err := conn.Send(reqHeader, c.networkTimeout(ctx)); err != nil {
+		return "", err
+	}
+	if err := conn.Send([]byte{storePathIndex}, c.networkTimeout(ctx)); err != nil {
+		return "", err
+	}
+	if err := conn.Send(extNameBytes, c.networkTimeout(ctx)); err != nil {
+		return "", err
+	}
+
+	if err := streamCopyToConn(ctx, conn, r, size, c.networkTimeout(ctx), c.chunkSize(ctx), c.bufferPool()); err != nil {
+		return "", err
+	}
+
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.networkTimeout(ctx))
+	if err != nil {
+		return "", err
+	}
+
+	respHeaderParsed, err := decodeHeader(respHeader)
+	if err != nil {
+		return "", err
+	}
+	if respHeaderParsed.Status != 0 {
+		return "", mapStatusToError(respHeaderParsed.Status)
+	}
+	if respHeaderParsed.Length <= 0 {
+		return "", ErrInvalidResponse
+	}
+
+	respBody, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.networkTimeout(ctx))
+	if err != nil {
+		return "", err
+	}
+	if len(respBody) < FdfsGroupNameMaxLen {
+		return "", ErrInvalidResponse
+	}
+
+	respGroupName := unpadString(respBody[:FdfsGroupNameMaxLen])
+	remoteFilename := string(respBody[FdfsGroupNameMaxLen:])
+
+	return joinFileID(respGroupName, remoteFilename), nil
+}
+
+// UploadFromPath opens localFilename and streams its contents via UploadStream.
+func (c *Client) UploadFromPath(ctx context.Context, groupName, localFilename string) (string, error) {
+	f, err := os.Open(localFilename)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	extName := getFileExtName(localFilename)
+	return c.UploadStream(ctx, groupName, f, stat.Size(), extName)
+}
+
+// DownloadStream downloads fileID and writes its content directly to w as
+// it is received, without allocating a buffer sized to the whole file. It
+// returns the number of bytes written. offset and length select a byte
+// range the same way DownloadFileRange does: length <= 0 means "to end of
+// file" from offset.
+func (c *Client) DownloadStream(ctx context.Context, fileID string, offset, length int64, w io.Writer) (int64, error) {
+	if err := c.checkClosed(); err != nil {
+		return 0, err
+	}
+
+	groupName, remoteFilename, err := splitFileID(fileID)
+	if err != nil {
+		return 0, err
+	}
+
+	storageServer, err := c.getDownloadStorageServer(ctx, groupName, remoteFilename)
+	if err != nil {
+		return 0, err
+	}
+
+	conn, err := c.storagePool.Get(ctx, fmt.Sprintf("%s:%d", storageServer.IPAddr, storageServer.Port))
+	if err != nil {
+		return 0, err
+	}
+	defer c.storagePool.Put(conn)
+
+	bodyLen := int64(16 + len(remoteFilename))
+	header := encodeHeader(bodyLen, StorageProtoCmdDownloadFile, 0)
+
+	reqBody := make([]byte, 0, bodyLen)
+	reqBody = append(reqBody, encodeInt64(offset)...)
+	reqBody = append(reqBody, encodeInt64(length)...)
+	reqBody = append(reqBody, []byte(remoteFilename)...)
+
+	if err := conn.Send(header, c.networkTimeout(ctx)); err != nil {
+		return 0, err
+	}
+	if err := conn.Send(reqBody, c.networkTimeout(ctx)); err != nil {
+		return 0, err
+	}
+
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.networkTimeout(ctx))
+	if err != nil {
+		return 0, err
+	}
+
+	respHeaderParsed, err := decodeHeader(respHeader)
+	if err != nil {
+		return 0, err
+	}
+	if respHeaderParsed.Status != 0 {
+		return 0, mapStatusToError(respHeaderParsed.Status)
+	}
+	if respHeaderParsed.Length <= 0 {
+		return 0, nil
+	}
+
+	return streamCopyFromConn(ctx, conn, w, respHeaderParsed.Length, c.networkTimeout(ctx), c.chunkSize(ctx), c.bufferPool())
+}
+
+// streamCopyToConn copies exactly size bytes from r to conn using chunks
+// from bufs, honoring ctx cancellation between chunks.
+func streamCopyToConn(ctx context.Context, conn *Connection, r io.Reader, size int64, timeout time.Duration, chunkSize int, bufs BufferPool) error {
+	buf := bufs.Get(chunkSize)
+	defer bufs.Put(buf)
+
+	var sent int64
+	for sent < size {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		toRead := int64(len(buf))
+		if remaining := size - sent; remaining < toRead {
+			toRead = remaining
+		}
+
+		n, err := io.ReadFull(r, buf[:toRead])
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read upload data: %w", err)
+		}
+		if n == 0 {
+			break
+		}
+
+		if err := conn.Send(buf[:n], timeout); err != nil {
+			return err
+		}
+		sent += int64(n)
+	}
+
+	if sent != size {
+		return fmt.Errorf("short upload: sent %d of %d bytes", sent, size)
+	}
+	return nil
+}
+
+// streamCopyFromConn reads exactly length bytes from conn and writes them to
+// w using chunks from bufs, honoring ctx cancellation between chunks.
+func streamCopyFromConn(ctx context.Context, conn *Connection, w io.Writer, length int64, timeout time.Duration, chunkSize int, bufs BufferPool) (int64, error) {
+	buf := bufs.Get(chunkSize)
+	defer bufs.Put(buf)
+
+	var received int64
+	for received < length {
+		select {
+		case <-ctx.Done():
+			return received, ctx.Err()
+		default:
+		}
+
+		readSize := chunkSize
+		if remaining := length - received; remaining < int64(readSize) {
+			readSize = int(remaining)
+		}
+
+		n, err := conn.ReceiveInto(buf[:readSize], timeout)
+		if err != nil {
+			return received, err
+		}
+		if n == 0 {
+			return received, fmt.Errorf("short download: received %d of %d bytes", received, length)
+		}
+
+		if _, err := w.Write(buf[:n]); err != nil {
+			return received, fmt.Errorf("failed to write downloaded data: %w", err)
+		}
+		received += int64(n)
+	}
+
+	return received, nil
+}
@@ -0,0 +1,97 @@
+// Package fdfs tracing integration.
+// This file defines a minimal, dependency-agnostic Span/Tracer abstraction
+// that every tracker query, storage upload/download/delete, and metadata
+// operation is wrapped in, plus adapters for OpenTelemetry and
+// Zipkin/OpenTracing-compatible tracers.
+package fdfs
+
+import (
+	"context"
+)
+
+// Span represents a single traced operation. Implementations are expected to
+// be safe to call from a single goroutine (the one that started the span).
+type Span interface {
+	// SetTag attaches a key/value pair to the span, e.g. "fdfs.cmd",
+	// "fdfs.group", "fdfs.server", "fdfs.file_id", "fdfs.bytes".
+	SetTag(key string, value interface{})
+
+	// SetError marks the span as failed and records err, including (when
+	// present) NetworkError.Op, StorageError/TrackerError.Server, and
+	// ProtocolError.Code so the failure detail survives in the trace.
+	SetError(err error)
+
+	// Finish completes the span.
+	Finish()
+}
+
+// Tracer starts a new Span for op ("upload_buffer", "download_file", ...)
+// and returns a context carrying it so nested calls can be parented to it.
+type Tracer interface {
+	StartSpan(ctx context.Context, op string) (context.Context, Span)
+}
+
+// noopTracer is used when ClientConfig.Tracer is nil, so instrumented call
+// sites don't need a nil check.
+type noopTracer struct{}
+
+func (noopTracer) StartSpan(ctx context.Context, op string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetTag(string, interface{}) {}
+func (noopSpan) SetError(error)             {}
+func (noopSpan) Finish()                    {}
+
+// spanContextKey is the context key startSpan stores the active Span
+// under, so code nested under it (e.g. a *WithRetry loop) can tag that
+// span without needing it threaded through every function signature.
+type spanContextKey struct{}
+
+// startSpan starts a span for op using the configured Tracer, or a no-op
+// span if none is configured. It also records the error-context fields
+// carried by this package's wrapped error types, so callers never have to
+// repeat that mapping at every call site. The returned context carries the
+// span, retrievable with spanFromContext.
+func (c *Client) startSpan(ctx context.Context, op string) (context.Context, Span) {
+	tracer := c.config.Tracer
+	if tracer == nil {
+		tracer = noopTracer{}
+	}
+	ctx, span := tracer.StartSpan(ctx, op)
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// spanFromContext returns the Span the nearest enclosing startSpan call
+// stored in ctx, or a no-op Span if none is present (e.g. ctx came from
+// somewhere that never called startSpan).
+func spanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanContextKey{}).(Span); ok {
+		return span
+	}
+	return noopSpan{}
+}
+
+// finishSpan records err on span (tagging any NetworkError/StorageError/
+// TrackerError/ProtocolError detail it carries) and finishes it. Call sites
+// should `defer` this immediately after starting a span.
+func finishSpan(span Span, err error) {
+	if err != nil {
+		span.SetError(err)
+
+		switch e := err.(type) {
+		case *NetworkError:
+			span.SetTag("fdfs.net.op", e.Op)
+			span.SetTag("fdfs.net.addr", e.Addr)
+		case *StorageError:
+			span.SetTag("fdfs.server", e.Server)
+		case *TrackerError:
+			span.SetTag("fdfs.server", e.Server)
+		case *ProtocolError:
+			span.SetTag("fdfs.protocol.code", e.Code)
+		}
+	}
+	span.Finish()
+}
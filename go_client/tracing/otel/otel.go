@@ -0,0 +1,70 @@
+// Package otel adapts the fdfs.Tracer/fdfs.Span interfaces to OpenTelemetry,
+// so a *Client can be configured with `ClientConfig.Tracer = otel.NewTracer(tp)`
+// and have every RPC emit a standard OTel span.
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	fdfs "github.com/happyfish100/fastdfs/go_client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer wraps an OpenTelemetry trace.Tracer as an fdfs.Tracer.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns an fdfs.Tracer backed by tracer.
+func NewTracer(tracer trace.Tracer) *Tracer {
+	return &Tracer{tracer: tracer}
+}
+
+// StartSpan implements fdfs.Tracer.
+func (t *Tracer) StartSpan(ctx context.Context, op string) (context.Context, fdfs.Span) {
+	ctx, span := t.tracer.Start(ctx, "fastdfs."+op)
+	return ctx, &Span{span: span}
+}
+
+// Span wraps an OpenTelemetry trace.Span as an fdfs.Span.
+type Span struct {
+	span trace.Span
+}
+
+// SetTag implements fdfs.Span.
+func (s *Span) SetTag(key string, value interface{}) {
+	s.span.SetAttributes(toAttribute(key, value))
+}
+
+// SetError implements fdfs.Span.
+func (s *Span) SetError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// Finish implements fdfs.Span.
+func (s *Span) Finish() {
+	s.span.End()
+}
+
+// toAttribute converts a loosely-typed tag value into an OTel attribute,
+// falling back to a string representation for types it doesn't special-case.
+func toAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case byte:
+		return attribute.Int(key, int(v))
+	default:
+		return attribute.String(key, fmt.Sprintf("%v", v))
+	}
+}
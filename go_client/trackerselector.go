@@ -0,0 +1,253 @@
+// Package fdfs tracker health tracking and selection.
+// This file adds passive health tracking for ClientConfig.TrackerAddrs, a
+// pluggable TrackerSelector used to pick the tracker for each new call, and
+// an optional background probe that proactively detects trackers coming
+// back online. It replaces the client's historical behavior of always
+// using the first configured tracker address.
+package fdfs
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// TrackerSelector chooses which tracker address to use for the next call
+// and observes the outcome of prior calls so it can steer traffic away
+// from unhealthy trackers. ClientConfig.TrackerSelector is optional; when
+// nil, trackerConn falls back to trying every configured address in order
+// (today's behavior).
+type TrackerSelector interface {
+	// Select returns the address to use for the next call out of addrs.
+	Select(addrs []string) string
+
+	// RecordResult reports the outcome and latency of a call against addr.
+	RecordResult(addr string, latency time.Duration, err error)
+}
+
+// RoundRobinTrackerSelector cycles through addrs in order, ignoring health.
+type RoundRobinTrackerSelector struct {
+	mu   sync.Mutex
+	next int
+}
+
+// Select implements TrackerSelector.
+func (s *RoundRobinTrackerSelector) Select(addrs []string) string {
+	if len(addrs) == 0 {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addr := addrs[s.next%len(addrs)]
+	s.next++
+	return addr
+}
+
+// RecordResult implements TrackerSelector; round-robin selection ignores outcomes.
+func (s *RoundRobinTrackerSelector) RecordResult(addr string, latency time.Duration, err error) {}
+
+// RandomTrackerSelector picks a uniformly random address, ignoring health.
+type RandomTrackerSelector struct{}
+
+// Select implements TrackerSelector.
+func (RandomTrackerSelector) Select(addrs []string) string {
+	if len(addrs) == 0 {
+		return ""
+	}
+	return addrs[rand.Intn(len(addrs))]
+}
+
+// RecordResult implements TrackerSelector; random selection ignores outcomes.
+func (RandomTrackerSelector) RecordResult(addr string, latency time.Duration, err error) {}
+
+// WeightedTrackerSelector selects via power-of-two-choices: it samples two
+// random addresses and picks the one with the lower penalty score, where
+// score = errRate*ErrorWeight + latencyEWMA*LatencyWeight. This gives
+// healthy trackers most of the traffic without starving a recovering one
+// the way a strict "always pick the best" policy would.
+type WeightedTrackerSelector struct {
+	// ErrorWeight scales the contribution of the rolling error rate (0..1)
+	// to the penalty score. Defaults to 1 if zero.
+	ErrorWeight float64
+
+	// LatencyWeight scales the contribution of the latency EWMA (in
+	// seconds) to the penalty score. Defaults to 1 if zero.
+	LatencyWeight float64
+
+	// EWMAAlpha is the smoothing factor for the latency/error-rate EWMAs,
+	// in (0, 1]; higher weighs recent calls more. Defaults to 0.2 if zero.
+	EWMAAlpha float64
+
+	mu    sync.Mutex
+	stats map[string]*trackerStat
+}
+
+type trackerStat struct {
+	errRate     float64
+	latencyEWMA time.Duration
+}
+
+// Select implements TrackerSelector using power-of-two-choices.
+func (s *WeightedTrackerSelector) Select(addrs []string) string {
+	if len(addrs) == 0 {
+		return ""
+	}
+	if len(addrs) == 1 {
+		return addrs[0]
+	}
+
+	i := rand.Intn(len(addrs))
+	j := rand.Intn(len(addrs) - 1)
+	if j >= i {
+		j++
+	}
+
+	a, b := addrs[i], addrs[j]
+	if s.penalty(a) <= s.penalty(b) {
+		return a
+	}
+	return b
+}
+
+// penalty returns the current score for addr; lower is healthier.
+func (s *WeightedTrackerSelector) penalty(addr string) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.stats[addr]
+	if !ok {
+		return 0
+	}
+	errWeight := s.ErrorWeight
+	if errWeight == 0 {
+		errWeight = 1
+	}
+	latWeight := s.LatencyWeight
+	if latWeight == 0 {
+		latWeight = 1
+	}
+	return st.errRate*errWeight + st.latencyEWMA.Seconds()*latWeight
+}
+
+// RecordResult implements TrackerSelector, updating addr's rolling error
+// rate and latency EWMA.
+func (s *WeightedTrackerSelector) RecordResult(addr string, latency time.Duration, err error) {
+	alpha := s.EWMAAlpha
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.2
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats == nil {
+		s.stats = make(map[string]*trackerStat)
+	}
+	st, ok := s.stats[addr]
+	if !ok {
+		st = &trackerStat{latencyEWMA: latency}
+		s.stats[addr] = st
+	}
+
+	outcome := 0.0
+	if err != nil {
+		outcome = 1.0
+	}
+	st.errRate = st.errRate + alpha*(outcome-st.errRate)
+	st.latencyEWMA = time.Duration(float64(st.latencyEWMA) + alpha*(float64(latency)-float64(st.latencyEWMA)))
+}
+
+// trackerHealthProbe runs Client.HealthCheckInterval's background ACTIVE_TEST
+// loop, started from NewClient when HealthCheckInterval > 0 and stopped
+// from Client.Close.
+type trackerHealthProbe struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startTrackerHealthProbe launches the background probe goroutine; callers
+// must arrange for it to be stopped via (*trackerHealthProbe).Close.
+func (c *Client) startTrackerHealthProbe() *trackerHealthProbe {
+	p := &trackerHealthProbe{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(c.config.HealthCheckInterval)
+		defer ticker.Stop()
+
+		up := make(map[string]bool, len(c.config.TrackerAddrs))
+		for _, addr := range c.config.TrackerAddrs {
+			up[addr] = true
+		}
+
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				for _, addr := range c.config.TrackerAddrs {
+					healthy := c.probeTracker(addr) == nil
+					wasUp := up[addr]
+					up[addr] = healthy
+					if healthy && !wasUp && c.config.OnTrackerUp != nil {
+						c.config.OnTrackerUp(addr)
+					}
+					if !healthy && wasUp && c.config.OnTrackerDown != nil {
+						c.config.OnTrackerDown(addr)
+					}
+				}
+			}
+		}
+	}()
+	return p
+}
+
+// Close stops the probe goroutine and waits for it to exit.
+func (p *trackerHealthProbe) Close() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+}
+
+// probeTracker issues a FdfsProtoCmdActiveTest against addr and reports
+// whether the tracker answered with a success status.
+func (c *Client) probeTracker(addr string) error {
+	return c.activeTest(context.Background(), c.trackerPool, addr)
+}
+
+// activeTest issues a FdfsProtoCmdActiveTest against addr through pool and
+// reports whether the server answered with a success status. It's the
+// shared no-op health check underlying both probeTracker (used by the
+// tracker health probe) and Client.Ping (used by
+// callers that want to check connectivity to every known tracker and
+// storage address on demand).
+func (c *Client) activeTest(ctx context.Context, pool *ConnectionPool, addr string) error {
+	conn, err := pool.Get(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer pool.Put(conn)
+
+	header := encodeHeader(0, FdfsProtoCmdActiveTest, 0)
+	if err := conn.Send(header, c.config.NetworkTimeout); err != nil {
+		return err
+	}
+
+	respHeader, err := conn.ReceiveFull(FdfsProtoHeaderLen, c.config.NetworkTimeout)
+	if err != nil {
+		return err
+	}
+	respHeaderParsed, err := decodeHeader(respHeader)
+	if err != nil {
+		return err
+	}
+	if respHeaderParsed.Status != 0 {
+		return mapStatusToError(respHeaderParsed.Status)
+	}
+	if respHeaderParsed.Length > 0 {
+		if _, err := conn.ReceiveFull(int(respHeaderParsed.Length), c.config.NetworkTimeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -33,6 +33,11 @@ const (
 	TrackerProtoCmdStorageSyncTimestamp             = 97
 	TrackerProtoCmdStorageSyncReport                = 98
 
+	// FdfsProtoCmdActiveTest is a no-op health check understood by both
+	// tracker and storage servers; a successful response only confirms the
+	// connection is alive.
+	FdfsProtoCmdActiveTest = 111
+
 	// Storage protocol commands - used when communicating with storage servers
 	StorageProtoCmdUploadFile         = 11 // Upload a regular file
 	StorageProtoCmdDeleteFile         = 12 // Delete a file
@@ -0,0 +1,239 @@
+// Package fdfs bulk verify/repair for an IDList.
+// This file audits a completed batch upload (recorded as an IDList, see
+// idlist.go): Verify streams every entry across N workers checking it's
+// still present and (optionally) that its content still matches the
+// recorded CRC32, and Repair re-uploads whatever Verify found missing or
+// corrupt from a caller-supplied source. Production users otherwise have no
+// way to tell which files from a batch job actually landed and are still
+// healthy.
+package fdfs
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"sync"
+)
+
+// VerifyStatus classifies one VerifyResult.
+type VerifyStatus int
+
+const (
+	// VerifyOK means the file exists and, when VerifyOptions.FullDownload
+	// is set, its content CRC32 matched the IDListEntry.
+	VerifyOK VerifyStatus = iota
+	// VerifyMissing means GetFileInfo (or the download) reported the file
+	// no longer exists.
+	VerifyMissing
+	// VerifyCorrupt means the file exists but its CRC32 (from GetFileInfo,
+	// or from a full download's content when FullDownload is set) or its
+	// GetFileInfo size doesn't match the IDListEntry, or the check failed
+	// with an error other than ErrFileNotFound (VerifyResult.Err is then set).
+	VerifyCorrupt
+)
+
+// String implements fmt.Stringer.
+func (s VerifyStatus) String() string {
+	switch s {
+	case VerifyOK:
+		return "ok"
+	case VerifyMissing:
+		return "missing"
+	case VerifyCorrupt:
+		return "corrupt"
+	default:
+		return "unknown"
+	}
+}
+
+// VerifyOptions configures Verify.
+type VerifyOptions struct {
+	// Client is the FastDFS client Verify runs checks through.
+	Client *Client
+
+	// Concurrency is the number of worker goroutines checking entries.
+	// Defaults to 8 when <= 0.
+	Concurrency int
+
+	// FullDownload, when true, downloads each file's full content and
+	// recomputes its CRC32 instead of trusting the tracker/storage
+	// server's recorded checksum from GetFileInfo. Far more expensive, but
+	// catches corruption GetFileInfo's metadata wouldn't.
+	FullDownload bool
+}
+
+// VerifyResult is the outcome of checking one IDListEntry.
+type VerifyResult struct {
+	Entry  IDListEntry
+	Status VerifyStatus
+	Err    error // set for a status-determining error other than "not found"
+}
+
+// Verify checks every entry in list against opts.Client, reporting which
+// are missing, corrupt, or still healthy.
+func Verify(ctx context.Context, list []IDListEntry, opts VerifyOptions) ([]VerifyResult, error) {
+	if opts.Client == nil {
+		return nil, fmt.Errorf("fdfs: Verify requires VerifyOptions.Client")
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 8
+	}
+
+	indices := make(chan int, concurrency)
+	results := make([]VerifyResult, len(list))
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indices {
+				results[idx] = verifyEntry(ctx, opts, list[idx])
+			}
+		}()
+	}
+
+	go func() {
+		for i := range list {
+			select {
+			case indices <- i:
+			case <-ctx.Done():
+			}
+		}
+		close(indices)
+	}()
+
+	wg.Wait()
+	return results, ctx.Err()
+}
+
+func verifyEntry(ctx context.Context, opts VerifyOptions, entry IDListEntry) VerifyResult {
+	if opts.FullDownload {
+		data, err := opts.Client.DownloadFile(ctx, entry.FileID)
+		if err == ErrFileNotFound {
+			return VerifyResult{Entry: entry, Status: VerifyMissing}
+		}
+		if err != nil {
+			return VerifyResult{Entry: entry, Status: VerifyCorrupt, Err: err}
+		}
+		if crc32.ChecksumIEEE(data) != entry.CRC32 {
+			return VerifyResult{Entry: entry, Status: VerifyCorrupt}
+		}
+		return VerifyResult{Entry: entry, Status: VerifyOK}
+	}
+
+	info, err := opts.Client.GetFileInfo(ctx, entry.FileID)
+	if err == ErrFileNotFound {
+		return VerifyResult{Entry: entry, Status: VerifyMissing}
+	}
+	if err != nil {
+		return VerifyResult{Entry: entry, Status: VerifyCorrupt, Err: err}
+	}
+	if info.CRC32 != entry.CRC32 || info.FileSize != entry.Size {
+		return VerifyResult{Entry: entry, Status: VerifyCorrupt}
+	}
+	return VerifyResult{Entry: entry, Status: VerifyOK}
+}
+
+// RepairSource supplies the original content for an entry Repair needs to
+// re-upload, keyed by the entry that failed verification.
+type RepairSource func(ctx context.Context, entry IDListEntry) (data []byte, fileExtName string, err error)
+
+// RepairOptions configures Repair.
+type RepairOptions struct {
+	// Client is the FastDFS client Repair re-uploads through.
+	Client *Client
+
+	// Concurrency is the number of worker goroutines re-uploading entries.
+	// Defaults to 8 when <= 0.
+	Concurrency int
+
+	// Source supplies the original content to re-upload for each entry
+	// Repair was asked to fix. Required.
+	Source RepairSource
+}
+
+// RepairResult is the outcome of re-uploading one failed VerifyResult.
+type RepairResult struct {
+	Original   IDListEntry
+	Reuploaded IDListEntry
+	Err        error
+}
+
+// Repair re-uploads, via opts.Source, every result in results whose Status
+// is not VerifyOK, returning one RepairResult per entry repaired. Results
+// with VerifyOK are skipped entirely.
+func Repair(ctx context.Context, results []VerifyResult, opts RepairOptions) ([]RepairResult, error) {
+	if opts.Client == nil {
+		return nil, fmt.Errorf("fdfs: Repair requires RepairOptions.Client")
+	}
+	if opts.Source == nil {
+		return nil, fmt.Errorf("fdfs: Repair requires RepairOptions.Source")
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 8
+	}
+
+	var toRepair []VerifyResult
+	for _, r := range results {
+		if r.Status != VerifyOK {
+			toRepair = append(toRepair, r)
+		}
+	}
+
+	indices := make(chan int, concurrency)
+	repairs := make([]RepairResult, len(toRepair))
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indices {
+				repairs[idx] = repairEntry(ctx, opts, toRepair[idx])
+			}
+		}()
+	}
+
+	go func() {
+		for i := range toRepair {
+			select {
+			case indices <- i:
+			case <-ctx.Done():
+			}
+		}
+		close(indices)
+	}()
+
+	wg.Wait()
+	return repairs, ctx.Err()
+}
+
+func repairEntry(ctx context.Context, opts RepairOptions, failed VerifyResult) RepairResult {
+	data, fileExtName, err := opts.Source(ctx, failed.Entry)
+	if err != nil {
+		return RepairResult{Original: failed.Entry, Err: fmt.Errorf("source: %w", err)}
+	}
+
+	if failed.Status == VerifyCorrupt {
+		if delErr := opts.Client.DeleteFile(ctx, failed.Entry.FileID); delErr != nil && delErr != ErrFileNotFound {
+			return RepairResult{Original: failed.Entry, Err: fmt.Errorf("delete corrupt file: %w", delErr)}
+		}
+	}
+
+	fileID, err := opts.Client.UploadBuffer(ctx, data, fileExtName, nil)
+	if err != nil {
+		return RepairResult{Original: failed.Entry, Err: fmt.Errorf("reupload: %w", err)}
+	}
+
+	return RepairResult{
+		Original: failed.Entry,
+		Reuploaded: IDListEntry{
+			FileID: fileID,
+			Size:   int64(len(data)),
+			CRC32:  crc32.ChecksumIEEE(data),
+		},
+	}
+}